@@ -3,7 +3,7 @@ package api
 // A PipelineName parameter model.
 //
 // This is used for operations that want the name of a pipeline in the path
-// swagger:parameters updatePipeline deletePipeline performPipeline
+// swagger:parameters updatePipeline deletePipeline performPipeline getPipelineDependency getBuildStatus getBuildLog getTestReport exportPipeline approveStage rejectStage
 type PipelineName struct {
 	// The name of the pipeline
 	//
@@ -12,6 +12,126 @@ type PipelineName struct {
 	Name string `json:"name"`
 }
 
+// An ExportFormatParam parameter model.
+//
+// This is used for operations that want the format to export a pipeline in the query
+// swagger:parameters exportPipeline
+type ExportFormatParam struct {
+	// The export format, gitlab or github
+	//
+	// in: query
+	// required: true
+	Format ExportFormat `json:"format"`
+}
+
+// A BuildNumber parameter model.
+//
+// This is used for operations that want the number of a build in the path
+// swagger:parameters getBuildStatus getBuildLog getTestReport approveStage rejectStage
+type BuildNumber struct {
+	// The number of the build
+	//
+	// in: path
+	// required: true
+	Number int64 `json:"buildnumber"`
+}
+
+// A TemplateName parameter model.
+//
+// This is used for operations that want the name of a template in the path
+// swagger:parameters getTemplate updateTemplate deleteTemplate previewTemplate previewTemplateChange rolloutTemplate
+type TemplateName struct {
+	// The name of the template
+	//
+	// in: path
+	// required: true
+	Name string `json:"templatename"`
+}
+
+// A StageName parameter model.
+//
+// This is used for operations that want the stage of a pipeline in the path
+// swagger:parameters approveStage rejectStage
+type StageName struct {
+	// The stage of the pipeline
+	//
+	// in: path
+	// required: true
+	Stage Stage `json:"stage"`
+}
+
+// A CredentialId parameter model.
+//
+// This is used for operations that want the id of a credential in the path
+// swagger:parameters updateCredential deleteCredential
+type CredentialId struct {
+	// The id of the credential
+	//
+	// in: path
+	// required: true
+	Id string `json:"credentialid"`
+}
+
+// A SubscriptionId parameter model.
+//
+// This is used for operations that want the id of a subscription in the path
+// swagger:parameters deleteSubscription listDeliveries
+type SubscriptionId struct {
+	// The id of the subscription
+	//
+	// in: path
+	// required: true
+	Id string `json:"subscriptionid"`
+}
+
+// A SubscriptionParams parameter model.
+//
+// This is used for operations that want the subscription in the body
+// swagger:parameters createSubscription
+type SubscriptionParams struct {
+	// The subscription
+	//
+	// in: body
+	// required: true
+	Subscription *Subscription `json:"subscription"`
+}
+
+// A TemplateParams parameter model.
+//
+// This is used for operations that want the template in the body
+// swagger:parameters createTemplate updateTemplate previewTemplateChange
+type TemplateParams struct {
+	// The pipeline template
+	//
+	// in: body
+	// required: true
+	Template *PipelineTemplate `json:"template"`
+}
+
+// A ApprovalDecisionParams parameter model.
+//
+// This is used for operations that want the approval decision in the body
+// swagger:parameters approveStage rejectStage
+type ApprovalDecisionParams struct {
+	// The approval decision
+	//
+	// in: body
+	// required: true
+	Decision *ApprovalDecision `json:"decision"`
+}
+
+// A CredentialParams parameter model.
+//
+// This is used for operations that want the credential in the body
+// swagger:parameters createCredential updateCredential
+type CredentialParams struct {
+	// The credential with secrets
+	//
+	// in: body
+	// required: true
+	Credential *Credential `json:"credential"`
+}
+
 // A PipelineParams parameter model.
 //
 // This is used for operations that want the pipeline config in the body
@@ -52,6 +172,216 @@ type PipelineResponse struct {
 	} `json:"body"`
 }
 
+// A PipelinesResponse response model
+//
+// This is used for returning a response with pipelines as body
+//
+// swagger:response pipelinesResponse
+type PipelinesResponse struct {
+	// in: body
+	Body struct {
+		Code       int32       `json:"code"`
+		Status     string      `json:"status"`
+		JsonObject []*Pipeline `json:"json_object"`
+	} `json:"body"`
+}
+
+// A DependencyGraphResponse response model
+//
+// This is used for returning a response with the dependency graph of a pipeline as body
+//
+// swagger:response dependencyGraphResponse
+type DependencyGraphResponse struct {
+	// in: body
+	Body struct {
+		Code       int32            `json:"code"`
+		Status     string           `json:"status"`
+		JsonObject *DependencyGraph `json:"json_object"`
+	} `json:"body"`
+}
+
+// A BuildLogResponse response model
+//
+// This is used for returning a response with the console log of a build as body
+//
+// swagger:response buildLogResponse
+type BuildLogResponse struct {
+	// in: body
+	Body struct {
+		Code       int32     `json:"code"`
+		Status     string    `json:"status"`
+		JsonObject *BuildLog `json:"json_object"`
+	} `json:"body"`
+}
+
+// A BuildStatusResponse response model
+//
+// This is used for returning a response with the status of a build as body
+//
+// swagger:response buildStatusResponse
+type BuildStatusResponse struct {
+	// in: body
+	Body struct {
+		Code       int32        `json:"code"`
+		Status     string       `json:"status"`
+		JsonObject *BuildStatus `json:"json_object"`
+	} `json:"body"`
+}
+
+// A DiskUsagesResponse response model
+//
+// This is used for returning a response with the disk usages of pipelines as body
+//
+// swagger:response diskUsagesResponse
+type DiskUsagesResponse struct {
+	// in: body
+	Body struct {
+		Code       int32        `json:"code"`
+		Status     string       `json:"status"`
+		JsonObject []*DiskUsage `json:"json_object"`
+	} `json:"body"`
+}
+
+// A TestReportResponse response model
+//
+// This is used for returning a response with the test report of a build as body
+//
+// swagger:response testReportResponse
+type TestReportResponse struct {
+	// in: body
+	Body struct {
+		Code       int32       `json:"code"`
+		Status     string      `json:"status"`
+		JsonObject *TestReport `json:"json_object"`
+	} `json:"body"`
+}
+
+// A PipelineExportResponse response model
+//
+// This is used for returning a response with the exported config of a pipeline as body
+//
+// swagger:response pipelineExportResponse
+type PipelineExportResponse struct {
+	// in: body
+	Body struct {
+		Code       int32           `json:"code"`
+		Status     string          `json:"status"`
+		JsonObject *PipelineExport `json:"json_object"`
+	} `json:"body"`
+}
+
+// A CredentialResponse response model
+//
+// This is used for returning a response with a credential without secrets as body
+//
+// swagger:response credentialResponse
+type CredentialResponse struct {
+	// in: body
+	Body struct {
+		Code       int32       `json:"code"`
+		Status     string      `json:"status"`
+		JsonObject *Credential `json:"json_object"`
+	} `json:"body"`
+}
+
+// A SubscriptionResponse response model
+//
+// This is used for returning a response with a subscription without secret as body
+//
+// swagger:response subscriptionResponse
+type SubscriptionResponse struct {
+	// in: body
+	Body struct {
+		Code       int32         `json:"code"`
+		Status     string        `json:"status"`
+		JsonObject *Subscription `json:"json_object"`
+	} `json:"body"`
+}
+
+// A SubscriptionsResponse response model
+//
+// This is used for returning a response with subscriptions without secrets as body
+//
+// swagger:response subscriptionsResponse
+type SubscriptionsResponse struct {
+	// in: body
+	Body struct {
+		Code       int32           `json:"code"`
+		Status     string          `json:"status"`
+		JsonObject []*Subscription `json:"json_object"`
+	} `json:"body"`
+}
+
+// A DeliveriesResponse response model
+//
+// This is used for returning a response with deliveries as body
+//
+// swagger:response deliveriesResponse
+type DeliveriesResponse struct {
+	// in: body
+	Body struct {
+		Code       int32       `json:"code"`
+		Status     string      `json:"status"`
+		JsonObject []*Delivery `json:"json_object"`
+	} `json:"body"`
+}
+
+// A TemplateResponse response model
+//
+// This is used for returning a response with a pipeline template as body
+//
+// swagger:response templateResponse
+type TemplateResponse struct {
+	// in: body
+	Body struct {
+		Code       int32             `json:"code"`
+		Status     string            `json:"status"`
+		JsonObject *PipelineTemplate `json:"json_object"`
+	} `json:"body"`
+}
+
+// A TemplatesResponse response model
+//
+// This is used for returning a response with pipeline templates as body
+//
+// swagger:response templatesResponse
+type TemplatesResponse struct {
+	// in: body
+	Body struct {
+		Code       int32               `json:"code"`
+		Status     string              `json:"status"`
+		JsonObject []*PipelineTemplate `json:"json_object"`
+	} `json:"body"`
+}
+
+// A TemplateRenderingsResponse response model
+//
+// This is used for returning a response with the re-rendered pipelines of a template as body
+//
+// swagger:response templateRenderingsResponse
+type TemplateRenderingsResponse struct {
+	// in: body
+	Body struct {
+		Code       int32                `json:"code"`
+		Status     string               `json:"status"`
+		JsonObject []*TemplateRendering `json:"json_object"`
+	} `json:"body"`
+}
+
+// A PendingApprovalsResponse response model
+//
+// This is used for returning a response with the pending approvals as body
+//
+// swagger:response pendingApprovalsResponse
+type PendingApprovalsResponse struct {
+	// in: body
+	Body struct {
+		Code       int32              `json:"code"`
+		Status     string             `json:"status"`
+		JsonObject []*PendingApproval `json:"json_object"`
+	} `json:"body"`
+}
+
 // A NoObjectResponse response model
 //
 // This is used for returning a response without json object as body
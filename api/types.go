@@ -1,7 +1,22 @@
 package api
 
+import "time"
+
 type ProjectType string
 type Stage string
+type BuildResult string
+type TimeUnit string
+type ParameterType string
+type CredentialType string
+type RepoType string
+type NotifyEvent string
+type BuildEventType string
+type AgentOS string
+type CacheScope string
+type ReporterType string
+type CommitState string
+type BackendType string
+type ExportFormat string
 
 const (
 	// Project types
@@ -15,6 +30,75 @@ const (
 	UT            = "unit_test"
 	BUILD         = "build"
 	DEPLOY        = "deploy"
+
+	// Build results
+	SUCCESS  BuildResult = "SUCCESS"
+	UNSTABLE             = "UNSTABLE"
+	FAILURE              = "FAILURE"
+
+	// Time units
+	SECONDS TimeUnit = "SECONDS"
+	MINUTES          = "MINUTES"
+	HOURS            = "HOURS"
+
+	// Parameter types
+	STRING_PARAM   ParameterType = "string"
+	CHOICE_PARAM                 = "choice"
+	BOOLEAN_PARAM                = "boolean"
+	PASSWORD_PARAM               = "password"
+	TEXT_PARAM                   = "text"
+
+	// Credential types
+	USERNAME_PASSWORD CredentialType = "username_password"
+	SECRET_TEXT                      = "secret_text"
+	SSH_KEY                          = "ssh_key"
+	SECRET_FILE                      = "secret_file"
+
+	// Repo types
+	GIT       RepoType = "git"
+	SVN                = "svn"
+	MERCURIAL          = "hg"
+
+	// Notify events
+	NOTIFY_START    NotifyEvent = "start"
+	NOTIFY_FAILURE              = "failure"
+	NOTIFY_FIXED                = "fixed"
+	NOTIFY_UNSTABLE             = "unstable"
+	NOTIFY_ALWAYS               = "always"
+
+	// Build event types
+	BUILD_QUEUED    BuildEventType = "build_queued"
+	BUILD_STARTED                  = "build_started"
+	STAGE_FINISHED                 = "stage_finished"
+	BUILD_COMPLETED                = "build_completed"
+
+	// Agent OSes
+	LINUX   AgentOS = "linux"
+	WINDOWS         = "windows"
+
+	// Cache scopes
+	SHARED_CACHE   CacheScope = "shared"
+	PIPELINE_CACHE            = "pipeline"
+
+	// Status reporter types
+	GITHUB ReporterType = "github"
+	GITLAB              = "gitlab"
+	GITEA               = "gitea"
+
+	// Commit states
+	COMMIT_PENDING CommitState = "pending"
+	COMMIT_RUNNING             = "running"
+	COMMIT_SUCCESS             = "success"
+	COMMIT_FAILURE             = "failure"
+	COMMIT_ERROR               = "error"
+
+	// Backend types
+	JENKINS_BACKEND BackendType = "jenkins"
+	LOCAL_BACKEND   BackendType = "local"
+
+	// Export formats
+	GITLAB_CI      ExportFormat = "gitlab"
+	GITHUB_ACTIONS ExportFormat = "github"
 )
 
 var (
@@ -23,23 +107,127 @@ var (
 		"jdk1.7": "/usr/lib/jvm/java-1.7.0",
 		"jdk1.8": "/usr/lib/jvm/java-1.8.0",
 	}
+
+	WINDOWS_JDK_PATH = map[string]string{
+		"jdk1.6": `C:\Program Files\Java\jdk1.6.0`,
+		"jdk1.7": `C:\Program Files\Java\jdk1.7.0`,
+		"jdk1.8": `C:\Program Files\Java\jdk1.8.0`,
+	}
 )
 
+// Pipeline is the pipeline definition. The pipeline referencing a Template is rendered
+// by merging the Overrides of the request into the template.
+// The pipeline with Branches fans out to a branch pipeline per matching branch, and
+// BranchOf is the full name of the fan-out pipeline generating the branch pipeline.
 type Pipeline struct {
-	Name             string         `json:"name,omitemtpy"`
-	NodeLabel        string         `json:"node_label,omitempty"`
-	Jdk              string         `json:"jdk,omitempty"`
-	Repo             *Repo          `json:"repo,omitempty"`
-	PeriodTrigger    *PeriodTrigger `json:"period_trigger,omitempty"`
-	ProjectType      ProjectType    `json:"type,omitemtpy"`
-	Project          interface{}    `json:"project,omitempty"`
-	Stages           []Stage        `json:"stages,omitemtpy"`
-	ArchiveWorkspace bool           `json:"archiveWorkspace"`
+	Name          string                 `json:"name,omitemtpy"`
+	Namespace     string                 `json:"namespace,omitempty"`
+	Tags          []string               `json:"tags,omitempty"`
+	NodeLabel     string                 `json:"node_label,omitempty"`
+	Jdk           string                 `json:"jdk,omitempty"`
+	OS            AgentOS                `json:"os,omitempty"`
+	Repo          *Repo                  `json:"repo,omitempty"`
+	PeriodTrigger *PeriodTrigger         `json:"period_trigger,omitempty"`
+	ProjectType   ProjectType            `json:"type,omitemtpy"`
+	Project       interface{}            `json:"project,omitempty"`
+	Stages        []Stage                `json:"stages,omitemtpy"`
+	Artifacts     *Artifacts             `json:"artifacts,omitempty"`
+	Stash         *Stash                 `json:"stash,omitempty"`
+	Retention     *Retention             `json:"retention,omitempty"`
+	Concurrency   *Concurrency           `json:"concurrency,omitempty"`
+	Upstream      *Upstream              `json:"upstream,omitempty"`
+	Downstream    []*Downstream          `json:"downstream,omitempty"`
+	Timeout       *Timeout               `json:"timeout,omitempty"`
+	StageOptions  map[Stage]*StageOption `json:"stage_options,omitempty"`
+	Parameters    []*Parameter           `json:"parameters,omitempty"`
+	Credentials   []*CredentialBinding   `json:"credentials,omitempty"`
+	Notifications *Notifications         `json:"notifications,omitempty"`
+	Matrix        *Matrix                `json:"matrix,omitempty"`
+	Template      string                 `json:"template,omitempty"`
+	Overrides     map[string]interface{} `json:"overrides,omitempty"`
+	Branches      *Branches              `json:"branches,omitempty"`
+	PullRequests  *PullRequests          `json:"pull_requests,omitempty"`
+	BranchOf      string                 `json:"branch_of,omitempty"`
+}
+
+// Branches is the branch fan-out of the pipeline. The Git branches matching the Pattern such as release/*
+// are discovered, and the branch pipelines are created for them with the Overrides of the matching branches.
+// The branch pipelines of the deleted branches are deleted after the GracePeriod, 24 hours by default.
+type Branches struct {
+	Pattern     string            `json:"pattern"`
+	Overrides   []*BranchOverride `json:"overrides,omitempty"`
+	GracePeriod *Timeout          `json:"grace_period,omitempty"`
+}
+
+// PullRequests is the pull request mode of the pipeline. The pull requests into the branch of the pipeline
+// are built by merging them into the branch, and the results are reported as the commit statuses by the
+// Reporter configured in goline. Context is the name of the commit status, goline/<pipeline> by default.
+type PullRequests struct {
+	Reporter string `json:"reporter"`
+	Context  string `json:"context,omitempty"`
+}
+
+// ReporterConfig is the config of the status reporter to report the commit statuses to GitHub, GitLab or Gitea.
+// Url is the API url of GitHub such as https://api.github.com, or the url of GitLab and Gitea.
+type ReporterConfig struct {
+	Type  ReporterType `json:"type"`
+	Url   string       `json:"url"`
+	Token string       `json:"token"`
+}
+
+// CommitStatus is the build status of the commit. Repo is the full name of the repo, such as owner/name.
+type CommitStatus struct {
+	Repo        string      `json:"repo"`
+	Sha         string      `json:"sha"`
+	State       CommitState `json:"state"`
+	Context     string      `json:"context"`
+	Description string      `json:"description,omitempty"`
+	TargetUrl   string      `json:"target_url,omitempty"`
+}
+
+// BranchOverride is the partial pipeline definition merged into the branch pipelines of
+// the branches matching the Pattern, in the order of the overrides.
+type BranchOverride struct {
+	Pattern  string                 `json:"pattern"`
+	Pipeline map[string]interface{} `json:"pipeline"`
 }
 
+// PipelineTemplate is the reusable pipeline definition shared by the pipelines referencing it by name.
+// Pipeline is the partial pipeline definition, whose fields are overridden by the pipelines.
+type PipelineTemplate struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Pipeline    map[string]interface{} `json:"pipeline"`
+}
+
+// TemplateRendering is the result to re-render the pipeline with its template.
+// Changed is true if the re-rendered Config differs from the current config of the pipeline.
+type TemplateRendering struct {
+	Pipeline string    `json:"pipeline"`
+	Changed  bool      `json:"changed"`
+	Config   *Pipeline `json:"config,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Repo is the source code repo, Git is the default type.
+// Revision is the revision to check out instead of the branch head: the commit SHA for Git,
+// the revision number for SVN and the changeset for Mercurial.
+// For Git, Tag is the tag to check out, and the other Git options control how to clone.
+// For SVN, Branch is the path under RepoPath such as trunk or branches/1.x,
+// and Externals determines whether to check out the externals.
 type Repo struct {
-	RepoPath string `json:"repo_path,omitempty"`
-	Branch   string `json:"branch,omitempty"`
+	Type         RepoType `json:"type,omitempty"`
+	RepoPath     string   `json:"repo_path,omitempty"`
+	Branch       string   `json:"branch,omitempty"`
+	CredentialId string   `json:"credential_id,omitempty"`
+	Revision     string   `json:"revision,omitempty"`
+	Tag          string   `json:"tag,omitempty"`
+	Externals    bool     `json:"externals,omitempty"`
+	Depth        int      `json:"depth,omitempty"`
+	Submodules   bool     `json:"submodules,omitempty"`
+	SparsePaths  []string `json:"sparse_paths,omitempty"`
+	Lfs          bool     `json:"lfs,omitempty"`
+	Clean        bool     `json:"clean,omitempty"`
 }
 
 type PeriodTrigger struct {
@@ -47,6 +235,224 @@ type PeriodTrigger struct {
 	Strategy string `json:"strategy,omitempty"`
 }
 
+// Parameter is the build parameter of the pipeline, which is also exposed
+// as the environment variable with the same name in stages.
+// The default of choice parameter is its first choice if not specified.
+type Parameter struct {
+	Name        string        `json:"name"`
+	Type        ParameterType `json:"type"`
+	Default     string        `json:"default,omitempty"`
+	Choices     []string      `json:"choices,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+// CredentialBinding binds the Jenkins credential to environment variables in the stages.
+// Variable is for the secret text, the secret file and the key file of SSH key,
+// UsernameVariable is for the username of username/password and SSH key,
+// PasswordVariable is for the password of username/password and the passphrase of SSH key.
+type CredentialBinding struct {
+	CredentialId     string         `json:"credential_id"`
+	Type             CredentialType `json:"type"`
+	Variable         string         `json:"variable,omitempty"`
+	UsernameVariable string         `json:"username_variable,omitempty"`
+	PasswordVariable string         `json:"password_variable,omitempty"`
+	Stages           []Stage        `json:"stages"`
+}
+
+// Artifacts archives the files matching the Includes but not the Excludes after the stages,
+// the Includes default to the outputs of the project type. The archived files are fingerprinted
+// if Fingerprint is true, and only archived for the successful builds if OnlyOnSuccess is true.
+type Artifacts struct {
+	Includes      []string `json:"includes,omitempty"`
+	Excludes      []string `json:"excludes,omitempty"`
+	AllowEmpty    bool     `json:"allow_empty,omitempty"`
+	Fingerprint   bool     `json:"fingerprint,omitempty"`
+	OnlyOnSuccess bool     `json:"only_on_success,omitempty"`
+}
+
+// Stash hands off the workspace files matching the Includes but not the Excludes between
+// the stages running on different agents, the Includes default to all the files.
+type Stash struct {
+	Includes []string `json:"includes,omitempty"`
+	Excludes []string `json:"excludes,omitempty"`
+}
+
+// Retention discards the old builds and their artifacts, 0 means no limit.
+// The artifacts are discarded earlier than the builds by the artifact limits.
+type Retention struct {
+	DaysToKeep         int `json:"days_to_keep,omitempty"`
+	NumToKeep          int `json:"num_to_keep,omitempty"`
+	ArtifactDaysToKeep int `json:"artifact_days_to_keep,omitempty"`
+	ArtifactNumToKeep  int `json:"artifact_num_to_keep,omitempty"`
+}
+
+// DiskUsage is the disk usage of the pipeline in Jenkins, the sizes are in bytes.
+type DiskUsage struct {
+	Pipeline      string `json:"pipeline"`
+	Builds        int    `json:"builds"`
+	Size          int64  `json:"size"`
+	ArtifactsSize int64  `json:"artifacts_size"`
+}
+
+// Matrix runs the stages in parallel cells, one cell for each combination of the JDKs,
+// the node labels and the env values. The cells matching any of the Excludes are skipped,
+// the keys of the excludes are "jdk", "node_label" or the env names.
+type Matrix struct {
+	Jdks       []string            `json:"jdks,omitempty"`
+	NodeLabels []string            `json:"node_labels,omitempty"`
+	Env        map[string][]string `json:"env,omitempty"`
+	Excludes   []map[string]string `json:"excludes,omitempty"`
+}
+
+// MatrixCell is a combination of the matrix axes.
+type MatrixCell struct {
+	Name      string            `json:"name"`
+	Jdk       string            `json:"jdk,omitempty"`
+	NodeLabel string            `json:"node_label,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Notifications sends the notifications to the channels when the events happen.
+// The test summary and the culprits are included in the notifications if required.
+type Notifications struct {
+	Slack              *SlackNotification   `json:"slack,omitempty"`
+	Email              *EmailNotification   `json:"email,omitempty"`
+	Webhook            *WebhookNotification `json:"webhook,omitempty"`
+	Events             []NotifyEvent        `json:"events,omitempty"`
+	IncludeTestSummary bool                 `json:"include_test_summary"`
+	IncludeCulprits    bool                 `json:"include_culprits"`
+}
+
+// SlackNotification is the Slack room to send notifications with the token in the Jenkins credential.
+type SlackNotification struct {
+	TeamDomain        string `json:"team_domain,omitempty"`
+	Room              string `json:"room,omitempty"`
+	TokenCredentialId string `json:"token_credential_id,omitempty"`
+}
+
+// EmailNotification is the email recipients, the culprits are also mailed if NotifyCulprits is true.
+type EmailNotification struct {
+	Recipients     []string `json:"recipients,omitempty"`
+	NotifyCulprits bool     `json:"notify_culprits"`
+}
+
+// WebhookNotification is the generic webhook to post the notifications in JSON.
+type WebhookNotification struct {
+	Url string `json:"url,omitempty"`
+}
+
+// Timeout is the time limit, MINUTES is the default unit.
+type Timeout struct {
+	Time int      `json:"time"`
+	Unit TimeUnit `json:"unit,omitempty"`
+}
+
+// StageOption is the option to run a stage.
+// The stage is retried at most Retry times when fails, and the seconds to wait
+// before each retry starts from RetryBackoff and doubles after every retry.
+// The Lockable Resources in Locks are locked while the stage runs, after it is approved.
+// The stage runs on the agents with NodeLabel instead of the node label of the pipeline.
+type StageOption struct {
+	Timeout      *Timeout  `json:"timeout,omitempty"`
+	Retry        int       `json:"retry,omitempty"`
+	RetryBackoff int       `json:"retry_backoff,omitempty"`
+	Locks        []string  `json:"locks,omitempty"`
+	Approval     *Approval `json:"approval,omitempty"`
+	NodeLabel    string    `json:"node_label,omitempty"`
+}
+
+// Approval gates the stage until one of the Approvers approves it, which are the ids of the Jenkins users.
+// Anyone can approve if Approvers is empty. The stage is aborted if it is rejected,
+// or not approved within the Timeout.
+type Approval struct {
+	Approvers []string `json:"approvers,omitempty"`
+	Message   string   `json:"message,omitempty"`
+	Timeout   *Timeout `json:"timeout,omitempty"`
+}
+
+// PendingApproval is the approval gate waiting for the approvers in the running build.
+type PendingApproval struct {
+	Pipeline  string   `json:"pipeline"`
+	Build     int64    `json:"build"`
+	Stage     Stage    `json:"stage"`
+	Message   string   `json:"message"`
+	Approvers []string `json:"approvers,omitempty"`
+}
+
+// ApprovalDecision is the decision of the approver on the approval gate.
+// Approver is the Jenkins user authenticated by the request, the one in the request body is ignored.
+type ApprovalDecision struct {
+	Approver string `json:"approver"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Concurrency controls the concurrent builds of the pipeline.
+// A newer build aborts the older ones when it passes their stages if Supersede is true.
+type Concurrency struct {
+	DisableConcurrent bool      `json:"disable_concurrent,omitempty"`
+	Throttle          *Throttle `json:"throttle,omitempty"`
+	Supersede         bool      `json:"supersede,omitempty"`
+}
+
+// Throttle limits the concurrent builds of the pipeline, 0 means no limit.
+// The builds are also limited by the throttle categories in Jenkins, which can limit the builds per label.
+type Throttle struct {
+	MaxPerNode int      `json:"max_per_node,omitempty"`
+	MaxTotal   int      `json:"max_total,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// Upstream triggers the pipeline when one of the upstream pipelines
+// completes with a result not worse than the threshold.
+type Upstream struct {
+	Pipelines []string    `json:"pipelines,omitempty"`
+	Threshold BuildResult `json:"threshold,omitempty"`
+}
+
+// Downstream is the pipeline to be built after the pipeline succeeds.
+type Downstream struct {
+	Pipeline string            `json:"pipeline,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+	Wait     bool              `json:"wait"`
+}
+
+// DependencyGraph is the graph of the pipelines chained with the pipeline.
+type DependencyGraph struct {
+	Pipeline   string            `json:"pipeline"`
+	Upstream   []string          `json:"upstream"`
+	Downstream []string          `json:"downstream"`
+	Edges      []*DependencyEdge `json:"edges"`
+}
+
+type DependencyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PipelineExport is the pipeline exported as the config file of another CI system, which is put at Path
+// in the repo. The features of the pipeline which can not be mapped are listed in Warnings.
+type PipelineExport struct {
+	Pipeline string       `json:"pipeline"`
+	Format   ExportFormat `json:"format"`
+	Path     string       `json:"path"`
+	Content  string       `json:"content"`
+	Warnings []string     `json:"warnings"`
+}
+
+// Credential is the Jenkins credential managed by goline.
+// Username and Password are for username/password, Secret is for secret text,
+// Username, PrivateKey and Passphrase are for SSH key.
+type Credential struct {
+	Id          string         `json:"id"`
+	Type        CredentialType `json:"type"`
+	Description string         `json:"description,omitempty"`
+	Username    string         `json:"username,omitempty"`
+	Password    string         `json:"password,omitempty"`
+	Secret      string         `json:"secret,omitempty"`
+	PrivateKey  string         `json:"private_key,omitempty"`
+	Passphrase  string         `json:"passphrase,omitempty"`
+}
+
 type ScriptProject struct {
 	Compile  *ScriptCompile  `json:"compile,omitemtpy"`
 	UnitTest *ScriptUnitTest `json:"unit_test,omitempty"`
@@ -70,6 +476,7 @@ type MavenProject struct {
 	RootPom  string         `json:"root_pom,omitempty"`
 	Options  string         `json:"options,omitempty"`
 	UnitTest *MavenUnitTest `json:"unit_test,omitempty"`
+	Cache    *Cache         `json:"cache,omitempty"`
 }
 
 type MavenUnitTest struct {
@@ -79,13 +486,117 @@ type MavenUnitTest struct {
 type GradleProject struct {
 	Options  string          `json:"options,omitempty"`
 	UnitTest *GradleUnitTest `json:"unit_test,omitempty"`
+	Cache    *Cache          `json:"cache,omitempty"`
 }
 
 type GradleUnitTest struct {
 	TestReportPath string `json:"test_report_path,omitempty"`
 }
 
+// Cache is the dependency cache of Maven and Gradle, the local repo of Maven or the user home of Gradle.
+// The shared cache is at Path, or the default location of the agent if Path is empty.
+// The pipeline cache is isolated for each pipeline on each agent.
+// The dependencies are resolved from the cache only if Offline is true,
+// and the snapshots are updated from the remote repos if ForceUpdate is true.
+type Cache struct {
+	Scope       CacheScope `json:"scope,omitempty"`
+	Path        string     `json:"path,omitempty"`
+	Offline     bool       `json:"offline,omitempty"`
+	ForceUpdate bool       `json:"force_update,omitempty"`
+}
+
+// PerformParams is the params to perform the pipeline.
+// Revision is the revision to check out instead of the branch head, which is a tag
+// or commit SHA for Git, a revision number for SVN and a changeset for Mercurial.
+// The dependency cache is purged before the stages if PurgeCache is true.
 type PerformParams struct {
-	Branch        string `json:"branch,omitempty"`
-	PerformPhases string `json:"perform_phases,omitempty"`
+	Branch        string            `json:"branch,omitempty"`
+	Revision      string            `json:"revision,omitempty"`
+	PerformPhases string            `json:"perform_phases,omitempty"`
+	PurgeCache    bool              `json:"purge_cache,omitempty"`
+	Params        map[string]string `json:"params,omitempty"`
+}
+
+// BuildStatus is the status of the pipeline build. For the matrix builds,
+// the stages are reported per cell, and the result of each cell is reported in Cells.
+type BuildStatus struct {
+	Pipeline string         `json:"pipeline"`
+	Build    int64          `json:"build"`
+	Running  bool           `json:"running"`
+	Result   string         `json:"result,omitempty"`
+	Stages   []*StageStatus `json:"stages"`
+	Cells    []*CellStatus  `json:"cells,omitempty"`
+}
+
+// BuildLog is the console log of the pipeline build
+type BuildLog struct {
+	Pipeline string `json:"pipeline"`
+	Build    int64  `json:"build"`
+	Log      string `json:"log"`
+}
+
+type StageStatus struct {
+	Name   string `json:"name"`
+	Cell   string `json:"cell,omitempty"`
+	Status string `json:"status"`
+}
+
+type CellStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// TestReport is the test report of the pipeline build. For the matrix builds,
+// the tests are also reported per cell in Cells.
+type TestReport struct {
+	Pipeline string `json:"pipeline"`
+	Build    int64  `json:"build"`
+	TestSummary
+	Cells []*CellTestReport `json:"cells,omitempty"`
+}
+
+type TestSummary struct {
+	Total   int `json:"total"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+type CellTestReport struct {
+	Name string `json:"name"`
+	TestSummary
+}
+
+// BuildEvent is the event of the pipeline build emitted by goline.
+// Stage is only for the stage finished event, and Result is for the stage
+// finished event and the build completed event.
+type BuildEvent struct {
+	Id        string         `json:"id"`
+	Type      BuildEventType `json:"type"`
+	Pipeline  string         `json:"pipeline"`
+	Build     int64          `json:"build,omitempty"`
+	Stage     string         `json:"stage,omitempty"`
+	Result    string         `json:"result,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Subscription subscribes the build events of the pipelines, all events of all pipelines
+// are subscribed if Events or Pipelines is empty. The deliveries are signed with the Secret.
+type Subscription struct {
+	Id        string           `json:"id"`
+	Url       string           `json:"url"`
+	Secret    string           `json:"secret,omitempty"`
+	Events    []BuildEventType `json:"events,omitempty"`
+	Pipelines []string         `json:"pipelines,omitempty"`
+}
+
+// Delivery is the record of delivering the event to the subscriber.
+type Delivery struct {
+	Id             string      `json:"id"`
+	SubscriptionId string      `json:"subscription_id"`
+	Event          *BuildEvent `json:"event"`
+	Attempts       int         `json:"attempts"`
+	StatusCode     int         `json:"status_code,omitempty"`
+	Error          string      `json:"error,omitempty"`
+	Delivered      bool        `json:"delivered"`
+	Timestamp      time.Time   `json:"timestamp"`
 }
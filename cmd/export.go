@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/pipeline"
+)
+
+// exportCommand exports the pipeline definition file as the config file of GitLab CI or GitHub Actions,
+// which needs no goline server
+var exportCommand = cli.Command{
+	Name:      "export",
+	Usage:     "export the pipeline definition as the config file of GitLab CI or GitHub Actions",
+	ArgsUsage: "<pipeline definition file>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "format, f",
+			Usage: "export format, gitlab or github",
+			Value: string(api.GITLAB_CI),
+		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "output file path, the standard output by default",
+		},
+	},
+	Action: func(c *cli.Context) {
+		err := exportPipeline(c.Args().First(), api.ExportFormat(c.String("format")), c.String("output"))
+		if err != nil {
+			log.Errorln(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+func exportPipeline(path string, format api.ExportFormat, output string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("The pipeline definition file is not specified")
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Fail to read the pipeline definition file %s", path)
+	}
+	definition := map[string]interface{}{}
+	if err = json.Unmarshal(contents, &definition); err != nil {
+		return fmt.Errorf("Fail to unmarshal a JSON object from the pipeline definition file %s", path)
+	}
+	if template, ok := definition["template"]; ok {
+		return fmt.Errorf("The pipeline referencing the template %v can not be rendered without goline server, export it by the API", template)
+	}
+
+	pl, err := pipeline.DecodePipeline(definition)
+	if err != nil {
+		return fmt.Errorf("Fail to decode the pipeline definition as %s", err.Error())
+	}
+	export, err := pipeline.ExportPipeline(pl, format)
+	if err != nil {
+		return fmt.Errorf("Fail to export the pipeline as %s", err.Error())
+	}
+
+	for _, warning := range export.Warnings {
+		log.Warnln(warning)
+	}
+	if len(output) == 0 {
+		fmt.Print(export.Content)
+		return nil
+	}
+	if err = ioutil.WriteFile(output, []byte(export.Content), 0644); err != nil {
+		return fmt.Errorf("Fail to write the exported config into %s as %s", output, err.Error())
+	}
+	log.Infof("The pipeline is exported into %s, which is %s in the repo", output, export.Path)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
+	"github.com/supereagle/goline/api"
 	"github.com/supereagle/goline/config"
 	"github.com/supereagle/goline/server"
 )
@@ -13,7 +14,7 @@ func main() {
 	// Create the command line app
 	app := cli.NewApp()
 	app.Name = "pipeline"
-	app.Usage = "Tools to manage CI/CD pipelines for Jenkins 2.0"
+	app.Usage = "Tools to manage CI/CD pipelines for Jenkins 2.0 or local processes"
 	app.Version = "0.1.0"
 	app.Flags = []cli.Flag{
 		cli.HelpFlag,
@@ -23,14 +24,30 @@ func main() {
 			Usage: "config file path",
 			Value: "config.json",
 		},
+		cli.StringFlag{
+			Name:  "backend, b",
+			Usage: "backend to run the pipelines, jenkins or local, overrides the one in config file",
+		},
+	}
+	app.Commands = []cli.Command{
+		exportCommand,
 	}
 	app.Action = func(c *cli.Context) {
-		// Read the config
+		// Read the config, the default config is used when there is no default config file
 		path := c.String("config")
-		cfg, err := config.Read(path)
-		if err != nil {
-			log.Errorln(err.Error())
-			return
+		var cfg *config.Config
+		var err error
+		if _, statErr := os.Stat(path); os.IsNotExist(statErr) && !c.IsSet("config") {
+			cfg = config.Default()
+		} else {
+			cfg, err = config.Read(path)
+			if err != nil {
+				log.Errorln(err.Error())
+				return
+			}
+		}
+		if c.IsSet("backend") {
+			cfg.Backend = api.BackendType(c.String("backend"))
 		}
 
 		// Run the server
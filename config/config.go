@@ -4,10 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+
+	"github.com/supereagle/goline/api"
 )
 
 const (
-	defaultPort = 8080
+	defaultPort          = 8080
+	defaultDataDir       = "./data"
+	defaultWatchInterval = 10
+	// Discover the branches of the fan-out pipelines every 5 minutes
+	defaultBranchDiscoveryInterval = 300
 )
 
 type Config struct {
@@ -16,6 +22,20 @@ type Config struct {
 	JenkinsPassword     string `json:"jenkins_password,omitempty"`
 	JenkinsCredentialId string `json:"jenkins_credential,omitempty"`
 	Port                int    `json:"port,omitempty"`
+	DataDir             string `json:"data_dir,omitempty"`
+	WatchInterval       int    `json:"watch_interval,omitempty"`
+	// The backend to run the pipelines, jenkins by default or local
+	Backend api.BackendType `json:"backend,omitempty"`
+	// The seconds between the branch discoveries of the fan-out pipelines
+	BranchDiscoveryInterval int `json:"branch_discovery_interval,omitempty"`
+	// The default retention of the pipelines without their own
+	Retention *api.Retention `json:"retention,omitempty"`
+	// The url of goline to link the builds in the commit statuses, http://localhost:<port> by default
+	ExternalUrl string `json:"external_url,omitempty"`
+	// The status reporters of the pull request mode by names
+	StatusReporters map[string]*api.ReporterConfig `json:"status_reporters,omitempty"`
+	// The secret of the webhooks sending the scm events, the events are rejected if it is not set
+	ScmEventSecret string `json:"scm_event_secret,omitempty"`
 }
 
 func Read(path string) (*Config, error) {
@@ -30,10 +50,35 @@ func Read(path string) (*Config, error) {
 		return nil, fmt.Errorf("Fail to unmarshal a JSON object from the config file %s", path)
 	}
 
-	// Set the default config for configures not specified
+	setDefaults(cfg)
+	return cfg, nil
+}
+
+// Default Gets the default config, which is used when there is no config file
+func Default() *Config {
+	cfg := &Config{}
+	setDefaults(cfg)
+	return cfg
+}
+
+// setDefaults Sets the default config for configures not specified
+func setDefaults(cfg *Config) {
+	if len(cfg.Backend) == 0 {
+		cfg.Backend = api.JENKINS_BACKEND
+	}
 	if cfg.Port == 0 {
 		cfg.Port = defaultPort
 	}
-
-	return cfg, nil
+	if len(cfg.DataDir) == 0 {
+		cfg.DataDir = defaultDataDir
+	}
+	if cfg.WatchInterval == 0 {
+		cfg.WatchInterval = defaultWatchInterval
+	}
+	if len(cfg.ExternalUrl) == 0 {
+		cfg.ExternalUrl = fmt.Sprintf("http://localhost:%d", cfg.Port)
+	}
+	if cfg.BranchDiscoveryInterval == 0 {
+		cfg.BranchDiscoveryInterval = defaultBranchDiscoveryInterval
+	}
 }
@@ -0,0 +1,150 @@
+package pipeline
+
+import (
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+const (
+	stashName          = "workspace"
+	artifactsStashName = "artifacts"
+)
+
+// The stages in the order they run, with the placeholders in the script
+var agentStages = []struct {
+	stage       api.Stage
+	placeholder string
+}{
+	{api.COMPILE, "${pipeline.script.stage.compile}"},
+	{api.UT, "${pipeline.script.stage.unittest}"},
+	{api.BUILD, "${pipeline.script.stage.build}"},
+	{api.DEPLOY, "${pipeline.script.stage.deploy}"},
+}
+
+// agentSegment is the consecutive stages running on the same agent
+type agentSegment struct {
+	label        string
+	placeholders []string
+}
+
+// stageSegments Groups the consecutive stages of the pipeline by the node labels they run on
+func stageSegments(pipeline *api.Pipeline) []*agentSegment {
+	segments := []*agentSegment{}
+	for _, s := range agentStages {
+		if !containStage(pipeline.Stages, s.stage) {
+			continue
+		}
+
+		label := pipeline.NodeLabel
+		if option := pipeline.StageOptions[s.stage]; option != nil && len(option.NodeLabel) > 0 {
+			label = option.NodeLabel
+		}
+
+		if len(segments) == 0 || segments[len(segments)-1].label != label {
+			segments = append(segments, &agentSegment{label: label})
+		}
+		last := segments[len(segments)-1]
+		last.placeholders = append(last.placeholders, s.placeholder)
+	}
+
+	return segments
+}
+
+// hasStageAgents Whether the stages of the pipeline run on more than one agent
+func hasStageAgents(pipeline *api.Pipeline) bool {
+	return len(stageSegments(pipeline)) > 1
+}
+
+// generateAgentsTmpl Generates the nodes of the stage segments in order, the first node checks out
+// the source code, and each node hands off the workspace to the next one through stash and unstash.
+// Each node keeps the artifacts even if its stages fail, and the ones kept last are archived on a new node.
+func generateAgentsTmpl(pipeline *api.Pipeline, platform *Platform, envTmpl string) string {
+	segments := stageSegments(pipeline)
+	stashTmpl := generateStashTmpl(pipeline.Stash)
+
+	artifactsTmpl, archiveTmpl := "// Not need to archive artifacts", ""
+	if artifacts := pipeline.Artifacts; artifacts != nil {
+		includes := artifacts.Includes
+		if len(includes) == 0 {
+			includes = defaultArtifacts[pipeline.ProjectType]
+		}
+		artifactsTmpl = strings.NewReplacer("${stash.name}", artifactsStashName,
+			"${artifacts.includes}", escapeGroovyString(strings.Join(includes, ", ")),
+			"${artifacts.excludes}", escapeGroovyString(strings.Join(artifacts.Excludes, ", "))).Replace(AGENT_ARTIFACTS_STASH_TEMPLATE)
+		archiveTmpl = strings.NewReplacer("${pipeline.label.node}", escapeGroovyGString(pipeline.NodeLabel),
+			"${stash.name}", artifactsStashName).Replace(AGENT_ARCHIVE_TEMPLATE)
+	}
+
+	nodesTmpl := ""
+	for i, segment := range segments {
+		workspaceTmpl := "// Checkout the source code\n\t${pipeline.script.checkout}"
+		if i > 0 {
+			workspaceTmpl = strings.Replace(UNSTASH_TEMPLATE, "${stash.name}", stashName, 1)
+		}
+
+		handoffTmpl := ""
+		if i < len(segments)-1 {
+			handoffTmpl = stashTmpl
+		}
+
+		stagesTmpl := ""
+		for _, placeholder := range segment.placeholders {
+			stagesTmpl += strings.Replace(AGENT_STAGE_TEMPLATE, "${pipeline.script.stage}", placeholder, 1)
+		}
+
+		nodesTmpl += strings.NewReplacer("${agent.workspace}", workspaceTmpl,
+			"${agent.stages}", stagesTmpl,
+			"${agent.artifacts}", artifactsTmpl,
+			"${agent.handoff}", handoffTmpl).Replace(nodeReplacer(segment.label, pipeline.Jdk, platform, envTmpl).Replace(AGENT_NODE_TEMPLATE))
+	}
+
+	return strings.NewReplacer("${agent.nodes}", nodesTmpl,
+		"${agent.archive}", archiveTmpl).Replace(AGENTS_TEMPLATE)
+}
+
+func generateStashTmpl(stash *api.Stash) string {
+	includes := []string{"**"}
+	excludes := []string{}
+	if stash != nil {
+		if len(stash.Includes) > 0 {
+			includes = stash.Includes
+		}
+		excludes = stash.Excludes
+	}
+
+	return strings.NewReplacer("${stash.name}", stashName,
+		"${stash.includes}", escapeGroovyString(strings.Join(includes, ", ")),
+		"${stash.excludes}", escapeGroovyString(strings.Join(excludes, ", "))).Replace(STASH_TEMPLATE)
+}
+
+// validateStageAgents Validates the node labels of the stages and the stash patterns
+func validateStageAgents(pipeline *api.Pipeline) bool {
+	for stage, option := range pipeline.StageOptions {
+		if option == nil || len(option.NodeLabel) == 0 {
+			continue
+		}
+		if len(strings.TrimSpace(option.NodeLabel)) == 0 {
+			log.Errorf("The node label of stage %s is empty", stage)
+			return false
+		}
+		if pipeline.Matrix != nil {
+			log.Errorf("The node label of stage %s is not supported by the matrix builds", stage)
+			return false
+		}
+	}
+
+	if pipeline.Stash != nil {
+		patterns := append(append([]string{}, pipeline.Stash.Includes...), pipeline.Stash.Excludes...)
+		for _, pattern := range patterns {
+			// The patterns are joined with commas
+			if len(strings.TrimSpace(pattern)) == 0 || strings.Contains(pattern, ",") {
+				log.Errorf("The stash pattern '%s' is not correct", pattern)
+				return false
+			}
+		}
+	}
+
+	return true
+}
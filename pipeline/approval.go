@@ -0,0 +1,320 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bndr/gojenkins"
+	"github.com/supereagle/goline/api"
+)
+
+// The ids of the input steps to approve the stages
+var approvalIds = map[api.Stage]string{
+	api.COMPILE: "ApproveCompile",
+	api.UT:      "ApproveUnitTest",
+	api.BUILD:   "ApproveBuild",
+	api.DEPLOY:  "ApproveDeploy",
+}
+
+// pendingInput is the pending input step described by the Pipeline Stage View API
+type pendingInput struct {
+	Id      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// generateApprovalTmpl Wraps the stage function with the input step to approve it
+func generateApprovalTmpl(stageTmpl string, stage api.Stage, pipeline *api.Pipeline, approval *api.Approval, jenkinsUser string) string {
+	message := approval.Message
+	if len(message) == 0 {
+		message = fmt.Sprintf("Approve the %s stage of %s?", stage, pipeline.Name)
+	}
+
+	// Only the approvers can submit the input in Jenkins. The decisions through goline are submitted by
+	// the Jenkins user of goline after the approvers are authenticated, so it is also a submitter.
+	submitterTmpl := ""
+	if len(approval.Approvers) > 0 {
+		submitters := approval.Approvers
+		if len(jenkinsUser) > 0 && !containString(submitters, jenkinsUser) {
+			submitters = append(append([]string{}, submitters...), jenkinsUser)
+		}
+		submitterTmpl = strings.Replace(APPROVAL_SUBMITTER_TEMPLATE, "${approval.submitter.names}",
+			escapeGroovyString(strings.Join(submitters, ",")), 1)
+	}
+	inputTmpl := strings.NewReplacer("${approval.id}", approvalIds[stage],
+		"${approval.message}", escapeGroovyString(message),
+		"${approval.submitter}", submitterTmpl).Replace(APPROVAL_INPUT_TEMPLATE)
+	if approval.Timeout != nil {
+		inputTmpl = strings.NewReplacer("${approval.timeout.time}", strconv.Itoa(approval.Timeout.Time),
+			"${approval.timeout.unit}", string(timeUnitOf(approval.Timeout)),
+			"${stage.approval.input}", inputTmpl).Replace(APPROVAL_TIMEOUT_TEMPLATE)
+	}
+
+	return strings.Replace(stageTmpl, "${pipeline.script.stage.function}",
+		strings.Replace(STAGE_APPROVAL_TEMPLATE, "${stage.approval.input}", inputTmpl, 1), 1)
+}
+
+// validateApproval Validates the approvers and the timeout of the approval
+func validateApproval(stage api.Stage, approval *api.Approval) bool {
+	for _, approver := range approval.Approvers {
+		if len(strings.TrimSpace(approver)) == 0 || strings.Contains(approver, ",") {
+			log.Errorf("The approver '%s' of stage %s is not correct", approver, stage)
+			return false
+		}
+	}
+
+	if approval.Timeout != nil {
+		if ok := validateTimeout(approval.Timeout); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ListPendingApprovals Lists the approval gates waiting for the approvers in the running builds of all pipelines
+func (backend *JenkinsBackend) ListPendingApprovals() ([]*api.PendingApproval, error) {
+	names, err := backend.store.List(pipelineKind)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	approvals := []*api.PendingApproval{}
+	for _, name := range names {
+		pl, err := getPipeline(backend.store, name)
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, err
+		}
+
+		job, err := backend.getJob(name)
+		if _, ok := err.(*NotExistError); ok {
+			log.Warnf("Skip the pending approvals of pipeline %s as %s", name, err.Error())
+			continue
+		}
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, err
+		}
+
+		numbers, err := backend.runningBuilds(job)
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, err
+		}
+
+		for _, number := range numbers {
+			inputs, err := backend.pendingInputs(job, number)
+			if err != nil {
+				log.Errorln(err.Error())
+				return nil, err
+			}
+
+			for _, input := range inputs {
+				stage, ok := approvalStage(input.Id)
+				if !ok {
+					continue
+				}
+
+				approval := &api.PendingApproval{
+					Pipeline: name,
+					Build:    number,
+					Stage:    stage,
+					Message:  input.Message,
+				}
+				if option := pl.StageOptions[stage]; option != nil && option.Approval != nil {
+					approval.Approvers = option.Approval.Approvers
+				}
+				approvals = append(approvals, approval)
+			}
+		}
+	}
+
+	return approvals, nil
+}
+
+// Approve Approves the approval gate of the stage on behalf of the approver authenticated by the token,
+// the comment is passed to the build
+func (backend *JenkinsBackend) Approve(plName string, number int64, stage api.Stage, decision *api.ApprovalDecision, token string) error {
+	job, err := backend.checkApproval(plName, number, stage, decision, token)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	params, err := json.Marshal(map[string]interface{}{
+		"parameter": []map[string]string{
+			map[string]string{"name": "approver", "value": decision.Approver},
+			map[string]string{"name": "comment", "value": decision.Comment},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	form := "proceed=Approve&json=" + url.QueryEscape(string(params))
+
+	resp, err := backend.Jenkins.Requester.Post(inputPath(job, number, stage)+"/submit", strings.NewReader(form), nil, nil)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Fail to approve the %s stage of build %d as %s", stage, number, resp.Status)
+		log.Errorln(err.Error())
+		return err
+	}
+
+	log.Infof("The %s stage of build %d of pipeline %s is approved by %s: %s", stage, number, plName, decision.Approver, decision.Comment)
+	return nil
+}
+
+// Reject Rejects the approval gate of the stage on behalf of the approver authenticated by the token, which aborts the build
+func (backend *JenkinsBackend) Reject(plName string, number int64, stage api.Stage, decision *api.ApprovalDecision, token string) error {
+	job, err := backend.checkApproval(plName, number, stage, decision, token)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	resp, err := backend.Jenkins.Requester.Post(inputPath(job, number, stage)+"/abort", nil, nil, nil)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Fail to reject the %s stage of build %d as %s", stage, number, resp.Status)
+		log.Errorln(err.Error())
+		return err
+	}
+
+	log.Infof("The %s stage of build %d of pipeline %s is rejected by %s: %s", stage, number, plName, decision.Approver, decision.Comment)
+	return nil
+}
+
+// checkApproval Checks whether the approver can decide the approval gate, and whether the gate is pending.
+// The approver is authenticated as a Jenkins user by its API token or password, and replaced by the id of the user.
+func (backend *JenkinsBackend) checkApproval(plName string, number int64, stage api.Stage, decision *api.ApprovalDecision, token string) (*gojenkins.Job, error) {
+	if len(strings.TrimSpace(decision.Approver)) == 0 || len(token) == 0 {
+		return nil, fmt.Errorf("The approver is not authenticated")
+	}
+	approver, err := backend.authenticate(decision.Approver, token)
+	if err != nil {
+		return nil, err
+	}
+	decision.Approver = approver
+
+	pl, err := getPipeline(backend.store, plName)
+	if err != nil {
+		return nil, err
+	}
+	option := pl.StageOptions[stage]
+	if option == nil || option.Approval == nil {
+		return nil, fmt.Errorf("The %s stage of pipeline %s has no approval", stage, plName)
+	}
+	if len(option.Approval.Approvers) > 0 && !containString(option.Approval.Approvers, decision.Approver) {
+		return nil, fmt.Errorf("%s is not an approver of the %s stage of pipeline %s", decision.Approver, stage, plName)
+	}
+
+	job, err := backend.getJob(plName)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := backend.pendingInputs(job, number)
+	if err != nil {
+		return nil, err
+	}
+	for _, input := range inputs {
+		if input.Id == approvalIds[stage] {
+			return job, nil
+		}
+	}
+
+	return nil, fmt.Errorf("The %s stage of build %d of pipeline %s is not waiting for approval", stage, number, plName)
+}
+
+// authenticate Authenticates the Jenkins user by its API token or password, and gets the id of the user
+func (backend *JenkinsBackend) authenticate(user, token string) (string, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(backend.Jenkins.Server, "/")+"/me/api/json", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(user, token)
+
+	resp, err := backend.Jenkins.Requester.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Fail to authenticate the user %s as %s", user, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Fail to authenticate the user %s as %s", user, resp.Status)
+	}
+
+	me := struct {
+		Id string `json:"id"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&me); err != nil {
+		return "", fmt.Errorf("Fail to authenticate the user %s as %s", user, err.Error())
+	}
+	if len(me.Id) == 0 || me.Id == "anonymous" {
+		return "", fmt.Errorf("Fail to authenticate the user %s", user)
+	}
+
+	return me.Id, nil
+}
+
+// runningBuilds Gets the numbers of the running builds
+func (backend *JenkinsBackend) runningBuilds(job *gojenkins.Job) ([]int64, error) {
+	result := struct {
+		Builds []struct {
+			Number   int64 `json:"number"`
+			Building bool  `json:"building"`
+		} `json:"builds"`
+	}{}
+
+	querystring := map[string]string{
+		"tree": "builds[number,building]",
+	}
+	_, err := backend.Jenkins.Requester.GetJSON(job.Base, &result, querystring)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to get the builds of pipeline %s as %s", job.GetName(), err.Error())
+	}
+
+	numbers := []int64{}
+	for _, build := range result.Builds {
+		if build.Building {
+			numbers = append(numbers, build.Number)
+		}
+	}
+
+	return numbers, nil
+}
+
+// pendingInputs Gets the pending input steps of the build through the Pipeline Stage View API
+func (backend *JenkinsBackend) pendingInputs(job *gojenkins.Job, number int64) ([]pendingInput, error) {
+	inputs := []pendingInput{}
+	_, err := backend.Jenkins.Requester.Get(job.Base+"/"+strconv.FormatInt(number, 10)+"/wfapi/pendingInputActions", &inputs, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to get the pending inputs of build %d as %s", number, err.Error())
+	}
+
+	return inputs, nil
+}
+
+func inputPath(job *gojenkins.Job, number int64, stage api.Stage) string {
+	return job.Base + "/" + strconv.FormatInt(number, 10) + "/input/" + approvalIds[stage]
+}
+
+func approvalStage(inputId string) (api.Stage, bool) {
+	for stage, id := range approvalIds {
+		if id == inputId {
+			return stage, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,59 @@
+package pipeline
+
+import (
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+// Default artifacts of the project types, the script projects have no default
+var defaultArtifacts = map[api.ProjectType][]string{
+	api.MAVEN:  []string{"**/target/*.jar", "**/target/*.war"},
+	api.GRADLE: []string{"**/build/libs/*"},
+}
+
+// generateArchiveTmpl Generates the step to archive the artifacts
+func generateArchiveTmpl(pipeline *api.Pipeline) string {
+	artifacts := pipeline.Artifacts
+	if artifacts == nil {
+		return "// Not need to archive artifacts"
+	}
+
+	includes := artifacts.Includes
+	if len(includes) == 0 {
+		includes = defaultArtifacts[pipeline.ProjectType]
+	}
+
+	archiveTmpl := strings.NewReplacer("${artifacts.includes}", escapeGroovyString(strings.Join(includes, ", ")),
+		"${artifacts.excludes}", escapeGroovyString(strings.Join(artifacts.Excludes, ", ")),
+		"${artifacts.allow.empty}", strconv.FormatBool(artifacts.AllowEmpty),
+		"${artifacts.fingerprint}", strconv.FormatBool(artifacts.Fingerprint)).Replace(ARCHIVE_ARTIFACTS_TEMPLATE)
+
+	if artifacts.OnlyOnSuccess {
+		archiveTmpl = strings.Replace(ARCHIVE_ON_SUCCESS_TEMPLATE, "${pipeline.script.archive}", archiveTmpl, 1)
+	}
+
+	return archiveTmpl
+}
+
+// validateArtifacts Validates the patterns of the artifacts
+func validateArtifacts(pipeline *api.Pipeline) bool {
+	artifacts := pipeline.Artifacts
+	if len(artifacts.Includes) == 0 && len(defaultArtifacts[pipeline.ProjectType]) == 0 {
+		log.Errorf("The artifacts to archive must be specified for the %s project", pipeline.ProjectType)
+		return false
+	}
+
+	patterns := append(append([]string{}, artifacts.Includes...), artifacts.Excludes...)
+	for _, pattern := range patterns {
+		// The patterns are joined with commas
+		if len(strings.TrimSpace(pattern)) == 0 || strings.Contains(pattern, ",") {
+			log.Errorf("The artifact pattern '%s' is not correct", pattern)
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,143 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/supereagle/goline/api"
+)
+
+// Backend is the CI system which runs the pipelines, the pipelines are identified by their full names.
+// The pipeline configs are saved by the manager, the backends only keep what they need to run them.
+type Backend interface {
+	// Create Creates the pipeline in the backend, return error if exists
+	Create(pl *api.Pipeline) error
+	// Update Updates the pipeline in the backend, return NotExistError if not exists
+	Update(pl *api.Pipeline) error
+	// Delete Deletes the pipeline in the backend, return NotExistError if not exists
+	Delete(plName string) error
+	// Perform Triggers a build of the pipeline with the perform parameters
+	Perform(plName string, params *api.PerformParams) error
+	// GetBuildStatus Gets the status of the build and its stages
+	GetBuildStatus(plName string, number int64) (*api.BuildStatus, error)
+	// GetBuildLog Gets the console log of the build
+	GetBuildLog(plName string, number int64) (string, error)
+}
+
+// TestReporter is the backend which collects the test reports of the builds
+type TestReporter interface {
+	GetTestReport(plName string, number int64) (*api.TestReport, error)
+}
+
+// BuildWatcher is the backend which emits the build events of the pipelines
+type BuildWatcher interface {
+	WatchBuilds(interval time.Duration, handler func(*api.BuildEvent))
+}
+
+// BuildParameterGetter is the backend which keeps the parameters of the builds
+type BuildParameterGetter interface {
+	GetBuildParameters(plName string, number int64) (map[string]string, error)
+}
+
+// PasswordDefaultGetter is the backend which keeps the defaults of the password parameters,
+// as they are not saved with the pipeline configs
+type PasswordDefaultGetter interface {
+	GetPasswordDefaults(plName string) (map[string]string, error)
+}
+
+// NotExistError is returned by the backends when the pipeline does not exist in them
+type NotExistError struct {
+	Pipeline string
+}
+
+func (err *NotExistError) Error() string {
+	return fmt.Sprintf("The pipeline %s does not exist", err.Pipeline)
+}
+
+// jenkinsOnly Gets the Jenkins backend for the features only supported by Jenkins
+func (mgr *Manager) jenkinsOnly(feature string) (*JenkinsBackend, error) {
+	if mgr.jenkins == nil {
+		return nil, fmt.Errorf("The %s are only supported by the Jenkins backend", feature)
+	}
+	return mgr.jenkins, nil
+}
+
+// GetTestReport Gets the test report of the build, reported per cell for the matrix builds
+func (mgr *Manager) GetTestReport(plName string, number int64) (*api.TestReport, error) {
+	reporter, ok := mgr.backend.(TestReporter)
+	if !ok {
+		return nil, fmt.Errorf("The test reports are not supported by the backend")
+	}
+	return reporter.GetTestReport(plName, number)
+}
+
+// WatchBuilds Watches the builds of the managed pipelines, and emits the build events to the handler.
+// The builds are not watched if the backend does not emit the build events.
+func (mgr *Manager) WatchBuilds(interval time.Duration, handler func(*api.BuildEvent)) {
+	if watcher, ok := mgr.backend.(BuildWatcher); ok {
+		watcher.WatchBuilds(interval, handler)
+	}
+}
+
+// ListPendingApprovals Lists the approval stages waiting for the decisions in the running builds
+func (mgr *Manager) ListPendingApprovals() ([]*api.PendingApproval, error) {
+	jenkins, err := mgr.jenkinsOnly("approvals")
+	if err != nil {
+		return nil, err
+	}
+	return jenkins.ListPendingApprovals()
+}
+
+// Approve Approves the approval stage of the build, the approver is authenticated by the Jenkins API token or password
+func (mgr *Manager) Approve(plName string, number int64, stage api.Stage, decision *api.ApprovalDecision, token string) error {
+	jenkins, err := mgr.jenkinsOnly("approvals")
+	if err != nil {
+		return err
+	}
+	return jenkins.Approve(plName, number, stage, decision, token)
+}
+
+// Reject Rejects the approval stage of the build, the approver is authenticated by the Jenkins API token or password
+func (mgr *Manager) Reject(plName string, number int64, stage api.Stage, decision *api.ApprovalDecision, token string) error {
+	jenkins, err := mgr.jenkinsOnly("approvals")
+	if err != nil {
+		return err
+	}
+	return jenkins.Reject(plName, number, stage, decision, token)
+}
+
+// GetDiskUsages Gets the disk usages of the managed pipelines
+func (mgr *Manager) GetDiskUsages() ([]*api.DiskUsage, error) {
+	jenkins, err := mgr.jenkinsOnly("disk usages")
+	if err != nil {
+		return nil, err
+	}
+	return jenkins.GetDiskUsages()
+}
+
+// CreateCredential Creates the credential in the backend
+func (mgr *Manager) CreateCredential(credential *api.Credential) error {
+	jenkins, err := mgr.jenkinsOnly("credentials")
+	if err != nil {
+		return err
+	}
+	return jenkins.CreateCredential(credential)
+}
+
+// UpdateCredential Updates the credential in the backend
+func (mgr *Manager) UpdateCredential(credential *api.Credential) error {
+	jenkins, err := mgr.jenkinsOnly("credentials")
+	if err != nil {
+		return err
+	}
+	return jenkins.UpdateCredential(credential)
+}
+
+// DeleteCredential Deletes the credential in the backend
+func (mgr *Manager) DeleteCredential(id string) error {
+	jenkins, err := mgr.jenkinsOnly("credentials")
+	if err != nil {
+		return err
+	}
+	return jenkins.DeleteCredential(id)
+}
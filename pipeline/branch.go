@@ -0,0 +1,444 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/store"
+)
+
+const (
+	fanoutKind       = "fanouts"
+	fanoutBranchKind = "fanoutbranches"
+
+	defaultGracePeriod = 24 * time.Hour
+	lsRemoteTimeout    = time.Minute
+)
+
+var (
+	branchNameReplacer = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+	// The fields owned by the fan-out pipeline, which can not be overridden for the branches
+	branchOwnedFields = []string{"name", "namespace", "template", "overrides", "branches", "branch_of"}
+)
+
+// fanoutBranch is the branch pipeline generated for a branch of the fan-out pipeline.
+// MissingSince is the time when the branch is found deleted, the branch pipeline is
+// deleted after the grace period.
+type fanoutBranch struct {
+	Pipeline     string     `json:"pipeline"`
+	MissingSince *time.Time `json:"missing_since,omitempty"`
+}
+
+// branchPipelineName Gets the name of the branch pipeline, such as order-service-release-1.0 for release/1.0
+func branchPipelineName(name, branch string) string {
+	return name + "-" + branchNameReplacer.ReplaceAllString(branch, "-")
+}
+
+// branchPipeline Generates the branch pipeline from the fan-out pipeline, with the overrides of the matching branches
+func branchPipeline(fanout *api.Pipeline, branch string) (*api.Pipeline, error) {
+	data, err := json.Marshal(fanout)
+	if err != nil {
+		return nil, err
+	}
+	definition := map[string]interface{}{}
+	if err = json.Unmarshal(data, &definition); err != nil {
+		return nil, err
+	}
+	for _, field := range branchOwnedFields {
+		delete(definition, field)
+	}
+
+	for _, override := range fanout.Branches.Overrides {
+		if matched, _ := path.Match(override.Pattern, branch); matched {
+			definition = MergePipeline(definition, override.Pipeline)
+		}
+	}
+
+	pl, err := DecodePipeline(definition)
+	if err != nil {
+		return nil, err
+	}
+	pl.Name = branchPipelineName(fanout.Name, branch)
+	pl.Namespace = fanout.Namespace
+	pl.BranchOf = fullNameOf(fanout)
+	if pl.Repo == nil {
+		pl.Repo = &api.Repo{}
+	}
+	pl.Repo.Branch = branch
+
+	return pl, nil
+}
+
+// gracePeriodOf Gets the time to keep the branch pipelines of the deleted branches
+func gracePeriodOf(branches *api.Branches) time.Duration {
+	if branches.GracePeriod == nil {
+		return defaultGracePeriod
+	}
+	return durationOf(branches.GracePeriod)
+}
+
+// lsRemoteBranches Lists the branches of the Git repo by git ls-remote, with the Git credentials of the goline host
+func lsRemoteBranches(repoPath string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lsRemoteTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", "--", repoPath)
+	// Fail instead of waiting for the username and password
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Fail to list the branches of %s as %s", repoPath, err.Error())
+	}
+
+	branches := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.HasPrefix(fields[1], "refs/heads/") {
+			branches = append(branches, strings.TrimPrefix(fields[1], "refs/heads/"))
+		}
+	}
+
+	return branches, nil
+}
+
+// getFanout Gets the fan-out pipeline, returns store.ErrNotFound if not exists
+func (mgr *Manager) getFanout(plName string) (*api.Pipeline, error) {
+	pl := &api.Pipeline{}
+	if err := mgr.store.Get(fanoutKind, plName, pl); err != nil {
+		return nil, err
+	}
+
+	return pl, nil
+}
+
+// listFanouts Lists all the fan-out pipelines
+func (mgr *Manager) listFanouts() ([]*api.Pipeline, error) {
+	names, err := mgr.store.List(fanoutKind)
+	if err != nil {
+		return nil, err
+	}
+
+	pls := []*api.Pipeline{}
+	for _, name := range names {
+		pl, err := mgr.getFanout(name)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to get the fan-out pipeline %s as %s", name, err.Error())
+		}
+		pls = append(pls, pl)
+	}
+
+	return pls, nil
+}
+
+// fanoutBranches Gets the branch pipelines of the fan-out pipeline by branches
+func (mgr *Manager) fanoutBranches(plName string) (map[string]*fanoutBranch, error) {
+	branches := map[string]*fanoutBranch{}
+	err := mgr.store.Get(fanoutBranchKind, plName, &branches)
+	if err != nil && err != store.ErrNotFound {
+		return nil, fmt.Errorf("Fail to get the branches of pipeline %s as %s", plName, err.Error())
+	}
+
+	return branches, nil
+}
+
+// createFanout Creates the fan-out pipeline, and the branch pipelines of the matching branches
+func (mgr *Manager) createFanout(pl *api.Pipeline) error {
+	if ok := ValidatePipeline(pl); !ok {
+		return fmt.Errorf("The pipeline config is not correct")
+	}
+
+	mgr.branchMutex.Lock()
+	defer mgr.branchMutex.Unlock()
+
+	name := fullNameOf(pl)
+	if _, err := mgr.getFanout(name); err == nil {
+		return fmt.Errorf("The pipeline %s already exists", name)
+	}
+	if _, err := getPipeline(mgr.store, name); err == nil {
+		return fmt.Errorf("The pipeline %s already exists", name)
+	}
+
+	if err := mgr.syncBranches(pl); err != nil {
+		return err
+	}
+
+	return mgr.store.Put(fanoutKind, name, withoutPasswordDefaults(pl))
+}
+
+// updateFanout Updates the fan-out pipeline and its branch pipelines
+func (mgr *Manager) updateFanout(pl *api.Pipeline) error {
+	if ok := ValidatePipeline(pl); !ok {
+		return fmt.Errorf("The pipeline config is not correct")
+	}
+
+	mgr.branchMutex.Lock()
+	defer mgr.branchMutex.Unlock()
+
+	name := fullNameOf(pl)
+	if _, err := mgr.getFanout(name); err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Errorf("The branch fan-out pipeline %s does not exist", name)
+		}
+		return err
+	}
+
+	// Check the branch pipelines before any of them is updated, as the dependencies may make cycles
+	branches, err := mgr.fanoutBranches(name)
+	if err != nil {
+		return err
+	}
+	branchPls := []*api.Pipeline{}
+	for branch := range branches {
+		branchPl, err := branchPipeline(pl, branch)
+		if err != nil {
+			return err
+		}
+		if err = checkDependencyCycle(mgr.store, branchPl); err != nil {
+			return err
+		}
+		branchPls = append(branchPls, branchPl)
+	}
+
+	if err := mgr.store.Put(fanoutKind, name, withoutPasswordDefaults(pl)); err != nil {
+		return err
+	}
+
+	for _, branchPl := range branchPls {
+		if err = mgr.Update(branchPl); err != nil {
+			return fmt.Errorf("Fail to update the pipeline of branch %s as %s", branchPl.Repo.Branch, err.Error())
+		}
+	}
+
+	return mgr.syncBranches(pl)
+}
+
+// deleteFanout Deletes the fan-out pipeline and its branch pipelines
+func (mgr *Manager) deleteFanout(plName string) error {
+	mgr.branchMutex.Lock()
+	defer mgr.branchMutex.Unlock()
+
+	branches, err := mgr.fanoutBranches(plName)
+	if err != nil {
+		return err
+	}
+	for branch, branchPl := range branches {
+		if err = mgr.deleteBranchPipeline(branchPl.Pipeline); err != nil {
+			return fmt.Errorf("Fail to delete the pipeline of branch %s as %s", branch, err.Error())
+		}
+		// Save the progress, so that the deleted branch pipelines are not deleted again when retried
+		delete(branches, branch)
+		if err = mgr.store.Put(fanoutBranchKind, plName, branches); err != nil {
+			return err
+		}
+	}
+
+	if err = mgr.store.Delete(fanoutBranchKind, plName); err != nil && err != store.ErrNotFound {
+		return err
+	}
+	return mgr.store.Delete(fanoutKind, plName)
+}
+
+// deleteBranchPipeline Deletes the branch pipeline, which may have been deleted in the backend
+func (mgr *Manager) deleteBranchPipeline(plName string) error {
+	err := mgr.backend.Delete(plName)
+	if _, ok := err.(*NotExistError); err != nil && !ok {
+		return err
+	}
+
+	err = mgr.store.Delete(pipelineKind, plName)
+	if err != nil && err != store.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// syncBranches Creates the branch pipelines for the new matching branches, and deletes the ones of
+// the branches deleted longer than the grace period. The caller should hold the branch mutex.
+func (mgr *Manager) syncBranches(pl *api.Pipeline) error {
+	name := fullNameOf(pl)
+	remoteBranches, err := lsRemoteBranches(pl.Repo.RepoPath)
+	if err != nil {
+		return err
+	}
+
+	branches, err := mgr.fanoutBranches(name)
+	if err != nil {
+		return err
+	}
+
+	// The names of the branch pipelines, to skip the branches mapped to the same pipeline name
+	plNames := map[string]string{}
+	for branch, branchPl := range branches {
+		plNames[branchPl.Pipeline] = branch
+	}
+
+	now := time.Now()
+	found := map[string]bool{}
+	for _, branch := range remoteBranches {
+		if matched, _ := path.Match(pl.Branches.Pattern, branch); !matched {
+			continue
+		}
+		found[branch] = true
+
+		if branchPl, ok := branches[branch]; ok {
+			branchPl.MissingSince = nil
+			continue
+		}
+
+		branchPl, err := branchPipeline(pl, branch)
+		if err != nil {
+			return err
+		}
+		plName := fullNameOf(branchPl)
+		if other, ok := plNames[plName]; ok {
+			log.Warnf("Skip the branch %s of pipeline %s, as its pipeline %s is the one of branch %s", branch, name, plName, other)
+			continue
+		}
+		if err = mgr.Create(branchPl); err != nil {
+			log.Warnf("Fail to create the pipeline of branch %s as %s", branch, err.Error())
+			continue
+		}
+		log.Infof("Create the pipeline %s for the branch %s of pipeline %s", plName, branch, name)
+		branches[branch] = &fanoutBranch{Pipeline: plName}
+		plNames[plName] = branch
+	}
+
+	gracePeriod := gracePeriodOf(pl.Branches)
+	for branch, branchPl := range branches {
+		if found[branch] {
+			continue
+		}
+		if branchPl.MissingSince == nil {
+			branchPl.MissingSince = &now
+			continue
+		}
+		if now.Sub(*branchPl.MissingSince) < gracePeriod {
+			continue
+		}
+
+		if err = mgr.deleteBranchPipeline(branchPl.Pipeline); err != nil {
+			log.Warnf("Fail to delete the pipeline of the deleted branch %s as %s", branch, err.Error())
+			continue
+		}
+		log.Infof("Delete the pipeline %s of the deleted branch %s of pipeline %s", branchPl.Pipeline, branch, name)
+		delete(branches, branch)
+	}
+
+	return mgr.store.Put(fanoutBranchKind, name, branches)
+}
+
+// syncSavedBranches Syncs the branch pipelines of the fan-out pipeline loaded from the store, whose defaults of
+// the password parameters are got from its branch pipelines. The caller should hold the branch mutex.
+func (mgr *Manager) syncSavedBranches(pl *api.Pipeline) error {
+	pl, err := mgr.withFanoutPasswordDefaults(pl)
+	if err != nil {
+		return err
+	}
+
+	return mgr.syncBranches(pl)
+}
+
+// DiscoverBranches Discovers the branches of the fan-out pipelines periodically, to create and clean up
+// their branch pipelines.
+func (mgr *Manager) DiscoverBranches(interval time.Duration) {
+	for {
+		pls, err := mgr.listFanouts()
+		if err != nil {
+			log.Errorf("Fail to list the fan-out pipelines as %s", err.Error())
+		}
+
+		for _, pl := range pls {
+			mgr.branchMutex.Lock()
+			err = mgr.syncSavedBranches(pl)
+			mgr.branchMutex.Unlock()
+			if err != nil {
+				log.Warnf("Fail to discover the branches of pipeline %s as %s", fullNameOf(pl), err.Error())
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// discoverRepoBranches Discovers the branches of the fan-out pipelines of the repo immediately,
+// when the branches of the repo are changed.
+func (mgr *Manager) discoverRepoBranches(repoUrls []string) error {
+	pls, err := mgr.listFanouts()
+	if err != nil {
+		return err
+	}
+
+	for _, pl := range pls {
+		if !matchRepoUrl(pl.Repo.RepoPath, repoUrls) {
+			continue
+		}
+
+		mgr.branchMutex.Lock()
+		err = mgr.syncSavedBranches(pl)
+		mgr.branchMutex.Unlock()
+		if err != nil {
+			return fmt.Errorf("Fail to discover the branches of pipeline %s as %s", fullNameOf(pl), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// validateBranches Validates the branch fan-out, and the branch pipeline generated for the pattern as a sample
+func validateBranches(pipeline *api.Pipeline) bool {
+	branches := pipeline.Branches
+	if pipeline.Repo == nil || (pipeline.Repo.Type != "" && pipeline.Repo.Type != api.GIT) {
+		log.Errorln("The branch fan-out only supports the Git repos")
+		return false
+	}
+
+	if len(pipeline.BranchOf) > 0 {
+		log.Errorf("The branch pipeline of %s can not fan out again", pipeline.BranchOf)
+		return false
+	}
+
+	if _, err := path.Match(branches.Pattern, ""); err != nil || len(branches.Pattern) == 0 {
+		log.Errorf("The branch pattern %s is not a valid pattern", branches.Pattern)
+		return false
+	}
+
+	for _, override := range branches.Overrides {
+		if override == nil {
+			log.Errorln("The branch override is empty")
+			return false
+		}
+		if _, err := path.Match(override.Pattern, ""); err != nil || len(override.Pattern) == 0 {
+			log.Errorf("The branch pattern %s of the override is not a valid pattern", override.Pattern)
+			return false
+		}
+		for _, field := range branchOwnedFields {
+			if _, ok := override.Pipeline[field]; ok {
+				log.Errorf("The %s of pipeline can not be overridden for the branches %s", field, override.Pattern)
+				return false
+			}
+		}
+	}
+
+	if branches.GracePeriod != nil {
+		if ok := validateTimeout(branches.GracePeriod); !ok {
+			return false
+		}
+	}
+
+	sample, err := branchPipeline(pipeline, branches.Pattern)
+	if err != nil {
+		log.Errorf("Fail to generate the branch pipeline as %s", err.Error())
+		return false
+	}
+	return ValidatePipeline(sample)
+}
@@ -0,0 +1,143 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+// The stages of the matrix cells are named as "<stage> [<cell>]"
+var cellStagePattern = regexp.MustCompile(`^(.*?) \[(.*)\]$`)
+
+// Orders of the stage statuses to get the cell status, the greater one takes precedence
+var stageStatusOrders = map[string]int{
+	"SUCCESS":      0,
+	"NOT_EXECUTED": 0,
+	"UNSTABLE":     1,
+	"ABORTED":      2,
+	"FAILED":       3,
+	"IN_PROGRESS":  4,
+}
+
+// testSuite is the test suite in the Jenkins test report, the enclosing blocks are
+// the names of the stages and parallel branches which publish the suite.
+type testSuite struct {
+	EnclosingBlockNames []string `json:"enclosingBlockNames"`
+	Cases               []struct {
+		Status string `json:"status"`
+	} `json:"cases"`
+}
+
+// GetBuildStatus Gets the status of the build and its stages, reported per cell for the matrix builds
+func (backend *JenkinsBackend) GetBuildStatus(plName string, number int64) (*api.BuildStatus, error) {
+	job, err := backend.getJob(plName)
+	if err != nil {
+		return nil, err
+	}
+
+	build, err := backend.getBuildInfo(job, number)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to get the build %d of pipeline %s as %s", number, plName, err.Error())
+	}
+
+	stages, err := backend.describeStages(job, number)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &api.BuildStatus{
+		Pipeline: plName,
+		Build:    number,
+		Running:  build.Building,
+		Result:   build.Result,
+		Stages:   []*api.StageStatus{},
+	}
+
+	cells := map[string]*api.CellStatus{}
+	for _, stage := range stages {
+		stageStatus := &api.StageStatus{Name: stage.Name, Status: stage.Status}
+		if matches := cellStagePattern.FindStringSubmatch(stage.Name); matches != nil {
+			stageStatus.Name, stageStatus.Cell = matches[1], matches[2]
+
+			cell, ok := cells[stageStatus.Cell]
+			if !ok {
+				cell = &api.CellStatus{Name: stageStatus.Cell, Status: stage.Status}
+				cells[cell.Name] = cell
+				status.Cells = append(status.Cells, cell)
+			}
+			if stageStatusOrders[stage.Status] > stageStatusOrders[cell.Status] {
+				cell.Status = stage.Status
+			}
+		}
+		status.Stages = append(status.Stages, stageStatus)
+	}
+
+	return status, nil
+}
+
+// GetTestReport Gets the test report of the build, reported per cell for the matrix builds
+func (backend *JenkinsBackend) GetTestReport(plName string, number int64) (*api.TestReport, error) {
+	job, err := backend.getJob(plName)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	result := struct {
+		Suites []testSuite `json:"suites"`
+	}{}
+	resp, err := backend.Jenkins.Requester.GetJSON(job.Base+"/"+strconv.FormatInt(number, 10)+"/testReport", &result, nil)
+	if err != nil {
+		err = fmt.Errorf("Fail to get the test report of build %d of pipeline %s as %s", number, plName, err.Error())
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	report := &api.TestReport{
+		Pipeline: plName,
+		Build:    number,
+	}
+	// The build has no test report
+	if resp.StatusCode == http.StatusNotFound {
+		return report, nil
+	}
+
+	cells := map[string]*api.CellTestReport{}
+	for _, suite := range result.Suites {
+		var cell *api.CellTestReport
+		for _, name := range suite.EnclosingBlockNames {
+			if matches := cellStagePattern.FindStringSubmatch(name); matches != nil {
+				var ok bool
+				if cell, ok = cells[matches[2]]; !ok {
+					cell = &api.CellTestReport{Name: matches[2]}
+					cells[cell.Name] = cell
+					report.Cells = append(report.Cells, cell)
+				}
+				break
+			}
+		}
+
+		for _, c := range suite.Cases {
+			countTestCase(&report.TestSummary, c.Status)
+			if cell != nil {
+				countTestCase(&cell.TestSummary, c.Status)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func countTestCase(summary *api.TestSummary, status string) {
+	summary.Total++
+	switch status {
+	case "FAILED", "REGRESSION":
+		summary.Failed++
+	case "SKIPPED":
+		summary.Skipped++
+	}
+}
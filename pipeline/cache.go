@@ -0,0 +1,123 @@
+package pipeline
+
+import (
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+const (
+	purgeCacheParameter = "purgeCache"
+
+	// The pipeline cache is beside the workspace, so that it survives the workspace cleanup
+	pipelineCacheDir = "${env.WORKSPACE}@cache"
+)
+
+// cacheOf Gets the dependency cache of the Maven and Gradle projects
+func cacheOf(pipeline *api.Pipeline) *api.Cache {
+	switch project := pipeline.Project.(type) {
+	case api.MavenProject:
+		return project.Cache
+	case api.GradleProject:
+		return project.Cache
+	}
+
+	return nil
+}
+
+// cacheDir Gets the dir of the cache to be put in a Groovy GString,
+// empty if the cache is at the default location of the agent.
+func cacheDir(cache *api.Cache) string {
+	if cache == nil {
+		return ""
+	}
+
+	if cache.Scope == api.PIPELINE_CACHE {
+		return pipelineCacheDir
+	}
+	return escapeGroovyGString(cache.Path)
+}
+
+// generateCacheOptions Generates the options of the Maven or Gradle command to use the cache
+func generateCacheOptions(cache *api.Cache, projectType api.ProjectType) string {
+	if cache == nil {
+		return ""
+	}
+
+	options := ""
+	dir := cacheDir(cache)
+	switch projectType {
+	case api.MAVEN:
+		if len(dir) > 0 {
+			options += ` -Dmaven.repo.local=\"` + dir + `\"`
+		}
+		if cache.Offline {
+			options += " -o"
+		}
+		if cache.ForceUpdate {
+			options += " -U"
+		}
+	case api.GRADLE:
+		if len(dir) > 0 {
+			options += ` --gradle-user-home \"` + dir + `\"`
+		}
+		if cache.Offline {
+			options += " --offline"
+		}
+		if cache.ForceUpdate {
+			options += " --refresh-dependencies"
+		}
+	}
+
+	return options
+}
+
+// generateCachePurgeTmpl Generates the step to purge the cache when the build is performed to purge it
+func generateCachePurgeTmpl(cache *api.Cache) string {
+	dir := cacheDir(cache)
+	if len(dir) == 0 {
+		return "// No dependency cache to purge"
+	}
+
+	return strings.Replace(CACHE_PURGE_TEMPLATE, "${cache.dir}", dir, -1)
+}
+
+// generateCacheParameterTmpl Generates the parameter to purge the cache, only the caches
+// at the known dirs can be purged.
+func generateCacheParameterTmpl(cache *api.Cache) string {
+	if len(cacheDir(cache)) == 0 {
+		return ""
+	}
+
+	return generateParametersTmpl([]*api.Parameter{
+		&api.Parameter{
+			Name:        purgeCacheParameter,
+			Type:        api.BOOLEAN_PARAM,
+			Description: "Whether to purge the dependency cache before the stages.",
+			Default:     "false",
+		},
+	})
+}
+
+// validateCache Validates the scope and the options of the cache
+func validateCache(cache *api.Cache) bool {
+	switch cache.Scope {
+	case "", api.SHARED_CACHE:
+	case api.PIPELINE_CACHE:
+		if len(cache.Path) > 0 {
+			log.Errorf("The path of the cache is only for the %s cache", api.SHARED_CACHE)
+			return false
+		}
+	default:
+		log.Errorf("The cache scope %s is not supported, only supports %s and %s", cache.Scope, api.SHARED_CACHE, api.PIPELINE_CACHE)
+		return false
+	}
+
+	if cache.Offline && cache.ForceUpdate {
+		log.Errorln("The cache can not be offline and force updated at the same time")
+		return false
+	}
+
+	return true
+}
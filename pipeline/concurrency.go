@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+// The milestone ordinals of the stages, the ordinal 1 is passed when the build starts
+var stageMilestones = map[api.Stage]int{
+	api.COMPILE: 2,
+	api.UT:      3,
+	api.BUILD:   4,
+	api.DEPLOY:  5,
+}
+
+// generateConcurrencyPropertyTmpl Generates the job properties to disable and throttle the concurrent builds
+func generateConcurrencyPropertyTmpl(concurrency *api.Concurrency) string {
+	if concurrency == nil {
+		return ""
+	}
+
+	propertyTmpl := ""
+	if concurrency.DisableConcurrent {
+		propertyTmpl += DISABLE_CONCURRENT_TEMPLATE
+	}
+
+	throttle := concurrency.Throttle
+	if throttle != nil && (throttle.MaxPerNode > 0 || throttle.MaxTotal > 0) {
+		propertyTmpl += strings.NewReplacer("${throttle.max.per.node}", strconv.Itoa(throttle.MaxPerNode),
+			"${throttle.max.total}", strconv.Itoa(throttle.MaxTotal)).Replace(THROTTLE_TEMPLATE)
+	}
+
+	return propertyTmpl
+}
+
+// generateThrottleTmpl Wraps the node with the throttle categories, the categories must wrap the node to limit it
+func generateThrottleTmpl(nodeTmpl string, concurrency *api.Concurrency) string {
+	if concurrency == nil || concurrency.Throttle == nil || len(concurrency.Throttle.Categories) == 0 {
+		return nodeTmpl
+	}
+
+	categories := []string{}
+	for _, category := range concurrency.Throttle.Categories {
+		categories = append(categories, "'"+escapeGroovyString(category)+"'")
+	}
+
+	return strings.NewReplacer("${throttle.categories}", strings.Join(categories, ", "),
+		"${pipeline.script.node}", nodeTmpl).Replace(THROTTLE_CATEGORIES_TEMPLATE)
+}
+
+// generateMilestoneTmpl Generates the milestone of the stage, or the start of the build if the stage is empty
+func generateMilestoneTmpl(concurrency *api.Concurrency, stage api.Stage) string {
+	if concurrency == nil || !concurrency.Supersede {
+		return ""
+	}
+
+	ordinal := 1
+	if len(stage) > 0 {
+		ordinal = stageMilestones[stage]
+	}
+
+	return strings.Replace(MILESTONE_TEMPLATE, "${milestone.ordinal}", strconv.Itoa(ordinal), 1)
+}
+
+// generateLocksTmpl Wraps the stage function with the locks, which are sorted to avoid deadlocks between stages
+func generateLocksTmpl(stageTmpl string, locks []string) string {
+	sorted := append([]string{}, locks...)
+	sort.Strings(sorted)
+
+	for _, lock := range sorted {
+		stageTmpl = strings.Replace(stageTmpl, "${pipeline.script.stage.function}",
+			strings.Replace(STAGE_LOCK_TEMPLATE, "${stage.lock}", escapeGroovyString(lock), 1), 1)
+	}
+
+	return stageTmpl
+}
+
+// validateConcurrency Validates the throttle and the supersede of the pipeline
+func validateConcurrency(pipeline *api.Pipeline) bool {
+	concurrency := pipeline.Concurrency
+
+	if throttle := concurrency.Throttle; throttle != nil {
+		if throttle.MaxPerNode < 0 || throttle.MaxTotal < 0 {
+			log.Errorf("The throttle limits %d and %d should not be negative", throttle.MaxPerNode, throttle.MaxTotal)
+			return false
+		}
+		for _, category := range throttle.Categories {
+			if len(strings.TrimSpace(category)) == 0 {
+				log.Errorln("The throttle category is empty")
+				return false
+			}
+		}
+	}
+
+	// The milestones can not be passed inside the parallel cells
+	if concurrency.Supersede && pipeline.Matrix != nil {
+		log.Errorln("The supersede is not supported by the matrix builds")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,286 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+const credentialsStore = "/credentials/store/system/domain/_"
+
+var (
+	credentialIdPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+	// The binding templates of withCredentials for credential types
+	credentialBindings = map[api.CredentialType]string{
+		api.USERNAME_PASSWORD: USERNAME_PASSWORD_BINDING,
+		api.SECRET_TEXT:       SECRET_TEXT_BINDING,
+		api.SSH_KEY:           SSH_KEY_BINDING,
+		api.SECRET_FILE:       SECRET_FILE_BINDING,
+	}
+)
+
+// generateCredentialsTmpl Generates the bindings of the credentials used in the stage
+func generateCredentialsTmpl(stage api.Stage, bindings []*api.CredentialBinding) string {
+	credentials := []string{}
+	for _, binding := range bindings {
+		if !containStage(binding.Stages, stage) {
+			continue
+		}
+
+		credentials = append(credentials, strings.NewReplacer("${credential.id}", escapeGroovyString(binding.CredentialId),
+			"${credential.variable}", escapeGroovyString(binding.Variable),
+			"${credential.username.variable}", escapeGroovyString(binding.UsernameVariable),
+			"${credential.password.variable}", escapeGroovyString(binding.PasswordVariable)).Replace(credentialBindings[binding.Type]))
+	}
+
+	return strings.Join(credentials, ", ")
+}
+
+// validateCredentialBindings Validates the credential references and their variables.
+func validateCredentialBindings(pipeline *api.Pipeline) bool {
+	// The variables bound in each stage
+	variables := map[api.Stage]map[string]bool{}
+
+	for _, binding := range pipeline.Credentials {
+		if binding == nil || len(strings.TrimSpace(binding.CredentialId)) == 0 {
+			log.Errorln("The credential id is empty")
+			return false
+		}
+
+		// Check the variables required by the credential type
+		bound := []string{}
+		switch binding.Type {
+		case api.USERNAME_PASSWORD:
+			bound = append(bound, binding.UsernameVariable, binding.PasswordVariable)
+		case api.SECRET_TEXT, api.SECRET_FILE:
+			bound = append(bound, binding.Variable)
+		case api.SSH_KEY:
+			bound = append(bound, binding.Variable, binding.UsernameVariable)
+			// The passphrase is optional for SSH key
+			if len(binding.PasswordVariable) > 0 {
+				bound = append(bound, binding.PasswordVariable)
+			}
+		default:
+			log.Errorf("The type %s of credential %s is not supported", binding.Type, binding.CredentialId)
+			return false
+		}
+		for _, variable := range bound {
+			if !parameterNamePattern.MatchString(variable) {
+				log.Errorf("The variable %q of credential %s should only contain letters, digits and underscores", variable, binding.CredentialId)
+				return false
+			}
+		}
+
+		if len(binding.Stages) == 0 {
+			log.Errorf("The stages using credential %s are not specified", binding.CredentialId)
+			return false
+		}
+		for _, stage := range binding.Stages {
+			if !containStage(pipeline.Stages, stage) {
+				log.Errorf("The stage %s using credential %s is not in the pipeline stages", stage, binding.CredentialId)
+				return false
+			}
+			if !containStage(generatedStages, stage) {
+				log.Errorf("The credential %s can not be bound in stage %s, as the stage is not generated", binding.CredentialId, stage)
+				return false
+			}
+
+			if variables[stage] == nil {
+				variables[stage] = map[string]bool{}
+			}
+			for _, variable := range bound {
+				if variables[stage][variable] {
+					log.Errorf("The variable %s is bound more than once in stage %s", variable, stage)
+					return false
+				}
+				variables[stage][variable] = true
+			}
+		}
+	}
+
+	return true
+}
+
+// checkCredentials Checks the existence of the credentials referenced by the pipeline in Jenkins
+func (backend *JenkinsBackend) checkCredentials(pl *api.Pipeline) error {
+	ids := []string{}
+	if len(pl.Repo.CredentialId) > 0 {
+		ids = append(ids, pl.Repo.CredentialId)
+	}
+	for _, binding := range pl.Credentials {
+		ids = append(ids, binding.CredentialId)
+	}
+
+	for _, id := range ids {
+		exist, err := backend.existCredential(id)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return fmt.Errorf("The credential %s does not exist", id)
+		}
+	}
+
+	return nil
+}
+
+// CreateCredential Creates the credential in Jenkins
+func (backend *JenkinsBackend) CreateCredential(credential *api.Credential) error {
+	credentialCfg, err := generateCredentialConfig(credential)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	resp, err := backend.Jenkins.Requester.PostXML(credentialsStore+"/createCredentials", credentialCfg, nil, nil)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Fail to create the credential %s as %s", credential.Id, resp.Status)
+		log.Errorln(err.Error())
+		return err
+	}
+	return nil
+}
+
+// UpdateCredential Updates the credential in Jenkins, which is used to rotate the secrets
+func (backend *JenkinsBackend) UpdateCredential(credential *api.Credential) error {
+	// Check the existence of the credential
+	exist, err := backend.existCredential(credential.Id)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+	if !exist {
+		err = fmt.Errorf("The credential %s does not exist", credential.Id)
+		log.Errorln(err.Error())
+		return err
+	}
+
+	credentialCfg, err := generateCredentialConfig(credential)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	resp, err := backend.Jenkins.Requester.PostXML(credentialsStore+"/credential/"+credential.Id+"/config.xml", credentialCfg, nil, nil)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Fail to update the credential %s as %s", credential.Id, resp.Status)
+		log.Errorln(err.Error())
+		return err
+	}
+	return nil
+}
+
+// DeleteCredential Deletes the credential in Jenkins
+func (backend *JenkinsBackend) DeleteCredential(id string) error {
+	// Check the existence of the credential
+	exist, err := backend.existCredential(id)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+	if !exist {
+		err = fmt.Errorf("The credential %s does not exist", id)
+		log.Errorln(err.Error())
+		return err
+	}
+
+	resp, err := backend.Jenkins.Requester.Post(credentialsStore+"/credential/"+id+"/doDelete", nil, nil, nil)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Fail to delete the credential %s as %s", id, resp.Status)
+		log.Errorln(err.Error())
+		return err
+	}
+	return nil
+}
+
+func generateCredentialConfig(credential *api.Credential) (credentialCfg string, err error) {
+	// Validate the credential
+	if ok := ValidateCredential(credential); !ok {
+		return "", fmt.Errorf("Credential config is not correct")
+	}
+
+	var credentialTmpl string
+	switch credential.Type {
+	case api.USERNAME_PASSWORD:
+		credentialTmpl = USERNAME_PASSWORD_CREDENTIAL_TEMPLATE
+	case api.SECRET_TEXT:
+		credentialTmpl = SECRET_TEXT_CREDENTIAL_TEMPLATE
+	case api.SSH_KEY:
+		credentialTmpl = SSH_KEY_CREDENTIAL_TEMPLATE
+	}
+
+	credentialCfg = strings.NewReplacer("${credential.id}", escapeXml(credential.Id),
+		"${credential.description}", escapeXml(credential.Description),
+		"${credential.username}", escapeXml(credential.Username),
+		"${credential.password}", escapeXml(credential.Password),
+		"${credential.secret}", escapeXml(credential.Secret),
+		"${credential.private.key}", escapeXml(credential.PrivateKey),
+		"${credential.passphrase}", escapeXml(credential.Passphrase)).Replace(credentialTmpl)
+	return
+}
+
+// ValidateCredential Validates the credential config.
+// Returns true if correct, or false if wrong.
+func ValidateCredential(credential *api.Credential) bool {
+	if !credentialIdPattern.MatchString(credential.Id) {
+		log.Errorf("The credential id %q should only contain letters, digits, dots, underscores and hyphens", credential.Id)
+		return false
+	}
+
+	switch credential.Type {
+	case api.USERNAME_PASSWORD:
+		if len(credential.Username) == 0 || len(credential.Password) == 0 {
+			log.Errorf("The username or password of credential %s is empty", credential.Id)
+			return false
+		}
+	case api.SECRET_TEXT:
+		if len(credential.Secret) == 0 {
+			log.Errorf("The secret of credential %s is empty", credential.Id)
+			return false
+		}
+	case api.SSH_KEY:
+		if len(credential.Username) == 0 || len(credential.PrivateKey) == 0 {
+			log.Errorf("The username or private key of credential %s is empty", credential.Id)
+			return false
+		}
+	default:
+		log.Errorf("The type %s of credential %s is not supported to be managed", credential.Type, credential.Id)
+		return false
+	}
+
+	return true
+}
+
+func (backend *JenkinsBackend) existCredential(id string) (bool, error) {
+	result := map[string]interface{}{}
+	resp, err := backend.Jenkins.Requester.GetJSON(credentialsStore+"/credential/"+id, &result, nil)
+	if err != nil {
+		return false, fmt.Errorf("Fail to get the credential %s as %s", id, err.Error())
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("Fail to get the credential %s as %s", id, resp.Status)
+	}
+}
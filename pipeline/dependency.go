@@ -0,0 +1,230 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bndr/gojenkins"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/store"
+)
+
+// GetDependencyGraph Gets the graph of all the pipelines chained with the pipeline, both the upstream ones
+// and the downstream ones. The graph of the Jenkins backend is got from the Jenkins jobs, which also has the
+// triggers configured in Jenkins, and the graph of the other backends is got from the saved configs.
+func (mgr *Manager) GetDependencyGraph(plName string) (*api.DependencyGraph, error) {
+	var graph *api.DependencyGraph
+	var err error
+	if mgr.jenkins != nil {
+		graph, err = mgr.jenkins.GetDependencyGraph(plName)
+	} else {
+		graph, err = savedDependencyGraph(mgr.store, plName)
+	}
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	return graph, nil
+}
+
+// GetDependencyGraph Gets the dependency graph from the upstream and downstream projects of the Jenkins jobs.
+// They are got by their urls instead of Job.GetUpstreamJobs and Job.GetDownstreamJobs, which get the jobs by
+// their short names, so the jobs in the namespace folders are not found.
+func (backend *JenkinsBackend) GetDependencyGraph(plName string) (*api.DependencyGraph, error) {
+	jobs := map[string]*gojenkins.Job{}
+	getJob := func(name string) (*gojenkins.Job, error) {
+		if job, ok := jobs[name]; ok {
+			return job, nil
+		}
+		job, err := backend.getJob(name)
+		if err != nil {
+			return nil, err
+		}
+		jobs[name] = job
+		return job, nil
+	}
+	if _, err := getJob(plName); err != nil {
+		return nil, err
+	}
+
+	return walkDependencies(plName, func(name string) ([]string, error) {
+		job, err := getJob(name)
+		if err != nil {
+			return nil, err
+		}
+		return upstreamsOf(job)
+	}, func(name string) ([]string, error) {
+		job, err := getJob(name)
+		if err != nil {
+			return nil, err
+		}
+		return downstreamsOf(job)
+	})
+}
+
+// savedDependencyGraph Gets the dependency graph from the upstream and downstream configs of the saved pipelines
+func savedDependencyGraph(st *store.Store, plName string) (*api.DependencyGraph, error) {
+	if _, err := getPipeline(st, plName); err != nil {
+		return nil, err
+	}
+
+	downstreams, err := dependencyEdges(st, nil)
+	if err != nil {
+		return nil, err
+	}
+	upstreams := map[string]map[string]bool{}
+	for from, tos := range downstreams {
+		for to := range tos {
+			if upstreams[to] == nil {
+				upstreams[to] = map[string]bool{}
+			}
+			upstreams[to][from] = true
+		}
+	}
+
+	return walkDependencies(plName, func(name string) ([]string, error) {
+		return sortedNames(upstreams[name]), nil
+	}, func(name string) ([]string, error) {
+		return sortedNames(downstreams[name]), nil
+	})
+}
+
+// walkDependencies Walks through the upstream and downstream pipelines of the pipeline to build its graph
+func walkDependencies(plName string, upstreamsOf, downstreamsOf func(string) ([]string, error)) (*api.DependencyGraph, error) {
+	graph := &api.DependencyGraph{
+		Pipeline:   plName,
+		Upstream:   []string{},
+		Downstream: []string{},
+		Edges:      []*api.DependencyEdge{},
+	}
+
+	// Walk through the upstream pipelines
+	visited := map[string]bool{plName: true}
+	next := []string{plName}
+	for len(next) > 0 {
+		name := next[0]
+		next = next[1:]
+
+		upstreams, err := upstreamsOf(name)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to get the upstream pipelines of %s as %s", name, err.Error())
+		}
+		for _, upstream := range upstreams {
+			graph.Edges = append(graph.Edges, &api.DependencyEdge{From: upstream, To: name})
+			if !visited[upstream] {
+				visited[upstream] = true
+				graph.Upstream = append(graph.Upstream, upstream)
+				next = append(next, upstream)
+			}
+		}
+	}
+
+	// Walk through the downstream pipelines
+	visited = map[string]bool{plName: true}
+	next = []string{plName}
+	for len(next) > 0 {
+		name := next[0]
+		next = next[1:]
+
+		downstreams, err := downstreamsOf(name)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to get the downstream pipelines of %s as %s", name, err.Error())
+		}
+		for _, downstream := range downstreams {
+			graph.Edges = append(graph.Edges, &api.DependencyEdge{From: name, To: downstream})
+			if !visited[downstream] {
+				visited[downstream] = true
+				graph.Downstream = append(graph.Downstream, downstream)
+				next = append(next, downstream)
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// checkDependencyCycle Checks whether the pipeline makes a cycle with the saved pipelines
+// after its upstream and downstream pipelines are changed to the ones in the config.
+func checkDependencyCycle(st *store.Store, pl *api.Pipeline) error {
+	downstreams, err := dependencyEdges(st, pl)
+	if err != nil {
+		return err
+	}
+
+	// Start from the pipeline, and find whether it can reach itself again
+	plName := fullNameOf(pl)
+	next := sortedNames(downstreams[plName])
+	visited := map[string]bool{}
+	for len(next) > 0 {
+		name := next[0]
+		next = next[1:]
+
+		if name == plName {
+			return fmt.Errorf("The pipeline %s makes a dependency cycle with the existing pipelines", plName)
+		}
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		next = append(next, sortedNames(downstreams[name])...)
+	}
+
+	return nil
+}
+
+// dependencyEdges Gets the trigger relations among the saved pipelines from both their upstream
+// and downstream configs, keyed by the triggering pipelines. The saved config of the pipeline
+// is replaced by the given one if it is not nil.
+func dependencyEdges(st *store.Store, pl *api.Pipeline) (map[string]map[string]bool, error) {
+	names, err := st.List(pipelineKind)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to list the pipelines as %s", err.Error())
+	}
+
+	configs := []*api.Pipeline{}
+	for _, name := range names {
+		if pl != nil && name == fullNameOf(pl) {
+			continue
+		}
+		saved, err := getPipeline(st, name)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, saved)
+	}
+	if pl != nil {
+		configs = append(configs, pl)
+	}
+
+	edges := map[string]map[string]bool{}
+	addEdge := func(from, to string) {
+		if edges[from] == nil {
+			edges[from] = map[string]bool{}
+		}
+		edges[from][to] = true
+	}
+	for _, config := range configs {
+		if config.Upstream != nil {
+			for _, upstream := range config.Upstream.Pipelines {
+				addEdge(upstream, fullNameOf(config))
+			}
+		}
+		for _, downstream := range config.Downstream {
+			addEdge(fullNameOf(config), downstream.Pipeline)
+		}
+	}
+
+	return edges, nil
+}
+
+// sortedNames Gets the names in the set in order
+func sortedNames(names map[string]bool) []string {
+	sorted := []string{}
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
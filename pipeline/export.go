@@ -0,0 +1,759 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/store"
+)
+
+// The images of the GitLab CI jobs by the project types, ${jdk.version} is the major version of the JDK such as 8
+var gitlabImages = map[api.ProjectType]string{
+	api.MAVEN:  "maven:3-jdk-${jdk.version}",
+	api.GRADLE: "gradle:jdk${jdk.version}",
+	api.SHELL:  "openjdk:${jdk.version}-jdk",
+	api.BATCH:  "openjdk:${jdk.version}-jdk",
+}
+
+// The Jenkins cron aliases, which are not supported by GitHub Actions
+var cronAliases = map[string]string{
+	"@yearly":   "H H H H *",
+	"@annually": "H H H H *",
+	"@monthly":  "H H H * *",
+	"@weekly":   "H H * * H",
+	"@daily":    "H H * * *",
+	"@midnight": "H H(0-2) * * *",
+	"@hourly":   "H * * * *",
+}
+
+var (
+	// The minimums of the minute, hour, day of month, month and day of week fields of cron
+	cronFieldMinimums = []string{"0", "0", "1", "1", "0"}
+
+	hashRangePattern = regexp.MustCompile(`H\((\d+)-\d+\)`)
+)
+
+// exporter exports the pipeline, and collects the warnings of the features which can not be mapped
+type exporter struct {
+	pipeline *api.Pipeline
+	// The name of the CI system in the warnings
+	system   string
+	warnings []string
+}
+
+// ExportPipeline Exports the pipeline as the config file of GitLab CI or GitHub Actions
+func ExportPipeline(pl *api.Pipeline, format api.ExportFormat) (*api.PipelineExport, error) {
+	var system, path string
+	switch format {
+	case api.GITLAB_CI:
+		system, path = "GitLab CI", ".gitlab-ci.yml"
+	case api.GITHUB_ACTIONS:
+		system, path = "GitHub Actions", ".github/workflows/"+pl.Name+".yml"
+	default:
+		return nil, fmt.Errorf("The export format %s is not supported", format)
+	}
+
+	if ok := ValidatePipeline(pl); !ok {
+		return nil, fmt.Errorf("Pipeline config is not correct")
+	}
+
+	exp := &exporter{pipeline: pl, system: system, warnings: []string{}}
+	exp.checkUnmapped()
+
+	content := ""
+	if format == api.GITLAB_CI {
+		content = exp.exportGitlabCI()
+	} else {
+		content = exp.exportGithubActions()
+	}
+
+	return &api.PipelineExport{
+		Pipeline: fullNameOf(pl),
+		Format:   format,
+		Path:     path,
+		Content:  content,
+		Warnings: exp.warnings,
+	}, nil
+}
+
+// ExportPipeline Exports the saved config of the pipeline, the fan-out pipelines are exported with their branch patterns
+func (mgr *Manager) ExportPipeline(plName string, format api.ExportFormat) (*api.PipelineExport, error) {
+	pl, err := mgr.getFanout(plName)
+	if err == store.ErrNotFound {
+		pl, err = getPipeline(mgr.store, plName)
+	}
+	if err == nil {
+		pl, err = decodeSavedPipeline(pl)
+	}
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	export, err := ExportPipeline(pl, format)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+	return export, nil
+}
+
+func (exp *exporter) warn(format string, args ...interface{}) {
+	exp.warnings = append(exp.warnings, fmt.Sprintf(format, args...))
+}
+
+// checkUnmapped Checks the features which neither GitLab CI nor GitHub Actions have
+func (exp *exporter) checkUnmapped() {
+	pl := exp.pipeline
+
+	if pl.Repo != nil {
+		if len(pl.Repo.Type) > 0 && pl.Repo.Type != api.GIT {
+			exp.warn("The %s repo is not mapped, %s builds the Git repo containing the config", pl.Repo.Type, exp.system)
+		}
+		if len(pl.Repo.Revision) > 0 || len(pl.Repo.Tag) > 0 {
+			exp.warn("The fixed revision of the repo is not mapped, the pushed commits are built")
+		}
+	}
+	if pl.Branches != nil && len(pl.Branches.Overrides) > 0 {
+		exp.warn("The overrides of the branches are not mapped")
+	}
+	if pl.Upstream != nil {
+		exp.warn("The upstream pipelines are not mapped, trigger the pipeline from them in %s", exp.system)
+	}
+	if len(pl.Downstream) > 0 {
+		exp.warn("The downstream pipelines are not mapped, trigger them from the pipeline in %s", exp.system)
+	}
+	if pl.Notifications != nil {
+		exp.warn("The notifications are not mapped, use the notification settings of %s", exp.system)
+	}
+	if len(pl.Credentials) > 0 {
+		exp.warn("The credential bindings are not mapped, define the variables %s as the secrets in %s",
+			strings.Join(credentialVariables(pl.Credentials), ", "), exp.system)
+	}
+	if pl.Retention != nil && (pl.Retention.DaysToKeep > 0 || pl.Retention.NumToKeep > 0) {
+		exp.warn("The retention of the builds is not mapped")
+	}
+	if pl.Artifacts != nil && pl.Artifacts.Fingerprint {
+		exp.warn("The fingerprints of the artifacts are not mapped")
+	}
+	if pl.Concurrency != nil && pl.Concurrency.Throttle != nil {
+		exp.warn("The throttle of the builds is not mapped")
+	}
+	if cache := cacheOf(pl); cache != nil && (len(cache.Path) > 0 || cache.Offline || cache.ForceUpdate) {
+		exp.warn("The path, offline and force update options of the dependency cache are not mapped")
+	}
+	if containStage(pl.Stages, api.DEPLOY) {
+		exp.warn("The %s stage is not mapped, as goline does not generate it", api.DEPLOY)
+	}
+	for _, stage := range exportStages(pl) {
+		if option := pl.StageOptions[stage]; option != nil && len(option.Locks) > 0 {
+			exp.warn("The locks of the %s stage are not mapped", stage)
+		}
+	}
+	for _, param := range pl.Parameters {
+		if param.Type == api.PASSWORD_PARAM {
+			exp.warn("The password parameter %s is exported as a plain variable, define it as a secret in %s", param.Name, exp.system)
+		}
+	}
+}
+
+// exportGitlabCI Exports the pipeline as .gitlab-ci.yml, each stage runs as a job in the image of its JDK
+func (exp *exporter) exportGitlabCI() string {
+	pl := exp.pipeline
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "# Exported from the goline pipeline %s\n", fullNameOf(pl))
+
+	// Build the branch of the pipeline and the merge requests into it
+	rules := []string{}
+	if condition := exp.branchCondition("$CI_COMMIT_BRANCH"); len(condition) > 0 {
+		rules = append(rules, condition)
+		if pl.PullRequests != nil {
+			rules = append(rules, `$CI_PIPELINE_SOURCE == "merge_request_event" && `+exp.branchCondition("$CI_MERGE_REQUEST_TARGET_BRANCH_NAME"))
+		}
+	}
+	if len(rules) > 0 {
+		out.WriteString("\nworkflow:\n  rules:\n")
+		for _, rule := range rules {
+			fmt.Fprintf(out, "    - if: %s\n", yamlString(rule))
+		}
+	}
+
+	stages := exportStages(pl)
+	out.WriteString("\nstages:\n")
+	for _, stage := range stages {
+		fmt.Fprintf(out, "  - %s\n", stage)
+	}
+
+	// The JDK of the matrix cells is set by the variable of the parallel jobs
+	jdkVersion := majorVersion(pl.Jdk)
+	if pl.Matrix != nil && len(pl.Matrix.Jdks) > 0 {
+		jdkVersion = "$JDK_VERSION"
+	}
+	out.WriteString("\ndefault:\n")
+	fmt.Fprintf(out, "  image: %s\n", yamlString(strings.Replace(gitlabImages[pl.ProjectType], "${jdk.version}", jdkVersion, 1)))
+	if len(pl.NodeLabel) > 0 {
+		fmt.Fprintf(out, "  tags:\n    - %s\n", yamlString(pl.NodeLabel))
+	}
+	if pl.Concurrency != nil && pl.Concurrency.Supersede {
+		out.WriteString("  interruptible: true\n")
+	}
+	if platformOf(pl).OS == api.WINDOWS {
+		exp.warn("The Windows agents are not mapped, the jobs run in the Linux images")
+	}
+	if pl.Timeout != nil {
+		exp.warn("The timeout of the pipeline is not mapped, set the timeout in the CI/CD settings of the project")
+	}
+	if pl.PeriodTrigger != nil && !pl.PeriodTrigger.Skipped {
+		schedules, _ := cronSchedules(pl.PeriodTrigger.Strategy)
+		exp.warn("The period trigger is not mapped, create the pipeline schedules in GitLab with cron %s", strings.Join(schedules, "; "))
+	}
+
+	exp.writeGitlabVariables(out)
+	for i, stage := range stages {
+		// The artifacts are archived after the last stage
+		exp.writeGitlabJob(out, stage, i == len(stages)-1)
+	}
+
+	return out.String()
+}
+
+// writeGitlabVariables Writes the variables of the checkout, the dependency cache and the parameters
+func (exp *exporter) writeGitlabVariables(out *bytes.Buffer) {
+	pl := exp.pipeline
+	out.WriteString("\nvariables:\n")
+
+	// The whole history is cloned by default, same as Jenkins
+	depth := 0
+	if pl.Repo != nil {
+		depth = pl.Repo.Depth
+		if pl.Repo.Submodules {
+			out.WriteString("  GIT_SUBMODULE_STRATEGY: recursive\n")
+		}
+		if len(pl.Repo.SparsePaths) > 0 {
+			exp.warn("The sparse checkout is not mapped, the whole repo is checked out")
+		}
+	}
+	fmt.Fprintf(out, "  GIT_DEPTH: %s\n", yamlString(strconv.Itoa(depth)))
+
+	if cacheOf(pl) != nil {
+		switch pl.ProjectType {
+		case api.MAVEN:
+			out.WriteString("  MAVEN_OPTS: \"-Dmaven.repo.local=$CI_PROJECT_DIR/.m2/repository\"\n")
+		case api.GRADLE:
+			out.WriteString("  GRADLE_USER_HOME: \"$CI_PROJECT_DIR/.gradle\"\n")
+		}
+	}
+
+	for _, param := range pl.Parameters {
+		fmt.Fprintf(out, "  %s:\n    value: %s\n", param.Name, yamlString(parameterDefault(param)))
+		if len(param.Description) > 0 {
+			fmt.Fprintf(out, "    description: %s\n", yamlString(param.Description))
+		}
+		if param.Type == api.CHOICE_PARAM {
+			out.WriteString("    options:\n")
+			for _, choice := range param.Choices {
+				fmt.Fprintf(out, "      - %s\n", yamlString(choice))
+			}
+		}
+	}
+}
+
+// writeGitlabJob Writes the job of the stage with its options, the test reports and the artifacts
+func (exp *exporter) writeGitlabJob(out *bytes.Buffer, stage api.Stage, last bool) {
+	pl := exp.pipeline
+	command, reportPatterns := stageCommand(pl, stage)
+	fmt.Fprintf(out, "\n%s:\n  stage: %s\n  script:\n    - %s\n", stage, stage, yamlValue(strings.TrimSpace(command), "      "))
+
+	if option := pl.StageOptions[stage]; option != nil {
+		if option.Timeout != nil {
+			fmt.Fprintf(out, "  timeout: %dm\n", minutesOf(option.Timeout))
+		}
+		if option.Retry > 0 {
+			// GitLab retries a job at most twice
+			retry := option.Retry
+			if retry > 2 {
+				retry = 2
+				exp.warn("The %s stage is retried at most 2 times by GitLab CI instead of %d", stage, option.Retry)
+			}
+			fmt.Fprintf(out, "  retry: %d\n", retry)
+		}
+		if option.RetryBackoff > 0 {
+			exp.warn("The retry backoff of the %s stage is not mapped", stage)
+		}
+		if len(option.NodeLabel) > 0 {
+			fmt.Fprintf(out, "  tags:\n    - %s\n", yamlString(option.NodeLabel))
+		}
+		if option.Approval != nil {
+			// The pipeline is blocked until the manual job is played
+			out.WriteString("  when: manual\n  allow_failure: false\n")
+			if len(option.Approval.Approvers) > 0 || option.Approval.Timeout != nil {
+				exp.warn("The approvers and the timeout of the %s approval are not mapped, use the protected environments of GitLab", stage)
+			}
+		}
+	}
+	if pl.Concurrency != nil && pl.Concurrency.DisableConcurrent {
+		fmt.Fprintf(out, "  resource_group: %s\n", yamlString(fullNameOf(pl)))
+	}
+
+	if cacheOf(pl) != nil {
+		switch pl.ProjectType {
+		case api.MAVEN:
+			out.WriteString("  cache:\n    paths:\n      - .m2/repository\n")
+		case api.GRADLE:
+			out.WriteString("  cache:\n    paths:\n      - .gradle/caches\n      - .gradle/wrapper\n")
+		}
+	}
+
+	if pl.Matrix != nil {
+		out.WriteString("  parallel:\n    matrix:\n")
+		for _, cell := range matrixCells(pl) {
+			variables := []string{}
+			if len(pl.Matrix.Jdks) > 0 {
+				variables = append(variables, "JDK_VERSION: "+yamlString(majorVersion(cell.Jdk)))
+			}
+			for _, name := range sortedKeys(cell.Env) {
+				variables = append(variables, name+": "+yamlString(cell.Env[name]))
+			}
+			fmt.Fprintf(out, "      - %s\n", strings.Join(variables, "\n        "))
+		}
+		if len(pl.Matrix.NodeLabels) > 0 && stage == exportStages(pl)[0] {
+			exp.warn("The node labels of the matrix are not mapped")
+		}
+	}
+
+	reports := splitPatterns(reportPatterns)
+	archive := last && pl.Artifacts != nil
+	if len(reports) == 0 && !archive {
+		return
+	}
+
+	out.WriteString("  artifacts:\n")
+	when := "always"
+	if archive {
+		artifacts := pl.Artifacts
+		out.WriteString("    paths:\n")
+		for _, include := range artifactIncludes(pl) {
+			fmt.Fprintf(out, "      - %s\n", yamlString(include))
+		}
+		if len(artifacts.Excludes) > 0 {
+			out.WriteString("    exclude:\n")
+			for _, exclude := range artifacts.Excludes {
+				fmt.Fprintf(out, "      - %s\n", yamlString(exclude))
+			}
+		}
+		if artifacts.OnlyOnSuccess {
+			when = "on_success"
+		}
+		if pl.Retention != nil && pl.Retention.ArtifactDaysToKeep > 0 {
+			fmt.Fprintf(out, "    expire_in: %d days\n", pl.Retention.ArtifactDaysToKeep)
+		}
+		if pl.Retention != nil && pl.Retention.ArtifactNumToKeep > 0 {
+			exp.warn("The number of the builds to keep the artifacts is not mapped")
+		}
+	}
+	fmt.Fprintf(out, "    when: %s\n", when)
+	if len(reports) > 0 {
+		out.WriteString("    reports:\n      junit:\n")
+		for _, report := range reports {
+			fmt.Fprintf(out, "        - %s\n", yamlString(report))
+		}
+	}
+}
+
+// exportGithubActions Exports the pipeline as a GitHub Actions workflow, the stages run as the steps of a job
+func (exp *exporter) exportGithubActions() string {
+	pl := exp.pipeline
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "# Exported from the goline pipeline %s\nname: %s\n", fullNameOf(pl), yamlString(fullNameOf(pl)))
+
+	// Build the branch of the pipeline and the pull requests into it
+	out.WriteString("\non:\n  push:\n")
+	branch := ""
+	if pl.Branches != nil {
+		branch = pl.Branches.Pattern
+	} else if pl.Repo != nil {
+		branch = pl.Repo.Branch
+	}
+	if len(branch) > 0 {
+		fmt.Fprintf(out, "    branches:\n      - %s\n", yamlString(branch))
+	}
+	if pl.PullRequests != nil {
+		out.WriteString("  pull_request:\n")
+		if len(branch) > 0 {
+			fmt.Fprintf(out, "    branches:\n      - %s\n", yamlString(branch))
+		}
+	}
+	if pl.PeriodTrigger != nil && !pl.PeriodTrigger.Skipped {
+		schedules, approximated := cronSchedules(pl.PeriodTrigger.Strategy)
+		out.WriteString("  schedule:\n")
+		for _, schedule := range schedules {
+			fmt.Fprintf(out, "    - cron: %s\n", yamlString(schedule))
+		}
+		if approximated {
+			exp.warn("The period trigger is approximated as cron %s, GitHub Actions has no hashed schedules and runs them in UTC",
+				strings.Join(schedules, "; "))
+		}
+	}
+	exp.writeGithubInputs(out)
+
+	if concurrency := pl.Concurrency; concurrency != nil && (concurrency.DisableConcurrent || concurrency.Supersede) {
+		fmt.Fprintf(out, "\nconcurrency:\n  group: ${{ github.workflow }}-${{ github.ref }}\n  cancel-in-progress: %t\n", concurrency.Supersede)
+	}
+
+	// The parameters are exposed as the environment variables, same as Jenkins. The inputs only exist in the manual
+	// runs, the other runs take the defaults. The inputs are round-tripped by JSON, as the false and empty inputs
+	// are falsy in the expressions and would be replaced by the defaults.
+	if len(pl.Parameters) > 0 {
+		out.WriteString("\nenv:\n")
+		for _, param := range pl.Parameters {
+			defaultValue, _ := json.Marshal(parameterDefault(param))
+			if param.Type == api.BOOLEAN_PARAM {
+				defaultValue = []byte(parameterDefault(param))
+			}
+			fmt.Fprintf(out, "  %s: %s\n", param.Name, yamlString(fmt.Sprintf(
+				"${{ fromJSON(github.event_name == 'workflow_dispatch' && toJSON(inputs.%s) || '%s') }}",
+				param.Name, strings.Replace(string(defaultValue), "'", "''", -1))))
+		}
+	}
+
+	out.WriteString("\njobs:\n  pipeline:\n")
+	platform := platformOf(pl)
+	switch {
+	case len(pl.NodeLabel) > 0:
+		fmt.Fprintf(out, "    runs-on: [self-hosted, %s]\n", yamlString(pl.NodeLabel))
+	case platform.OS == api.WINDOWS:
+		out.WriteString("    runs-on: windows-latest\n")
+	default:
+		out.WriteString("    runs-on: ubuntu-latest\n")
+	}
+	if pl.Timeout != nil {
+		fmt.Fprintf(out, "    timeout-minutes: %d\n", minutesOf(pl.Timeout))
+	}
+
+	javaVersion := yamlString(majorVersion(pl.Jdk))
+	artifactSuffix := ""
+	if pl.Matrix != nil {
+		exp.writeGithubMatrix(out)
+		if len(pl.Matrix.Jdks) > 0 {
+			javaVersion = "${{ matrix.java }}"
+		}
+		// The artifacts of the cells are uploaded separately
+		artifactSuffix = "-${{ strategy.job-index }}"
+	}
+
+	out.WriteString("    steps:\n      - uses: actions/checkout@v4\n        with:\n")
+	depth := 0
+	if pl.Repo != nil {
+		depth = pl.Repo.Depth
+	}
+	fmt.Fprintf(out, "          fetch-depth: %d\n", depth)
+	if pl.Repo != nil {
+		if pl.Repo.Submodules {
+			out.WriteString("          submodules: recursive\n")
+		}
+		if pl.Repo.Lfs {
+			out.WriteString("          lfs: true\n")
+		}
+		if len(pl.Repo.SparsePaths) > 0 {
+			fmt.Fprintf(out, "          sparse-checkout: %s\n", yamlValue(strings.Join(pl.Repo.SparsePaths, "\n"), "            "))
+		}
+	}
+
+	fmt.Fprintf(out, "      - uses: actions/setup-java@v4\n        with:\n          distribution: zulu\n          java-version: %s\n", javaVersion)
+	if cacheOf(pl) != nil && (pl.ProjectType == api.MAVEN || pl.ProjectType == api.GRADLE) {
+		fmt.Fprintf(out, "          cache: %s\n", pl.ProjectType)
+	}
+
+	for _, stage := range exportStages(pl) {
+		command, reportPatterns := stageCommand(pl, stage)
+		fmt.Fprintf(out, "      - name: %s\n        run: %s\n", stageNames[stage], yamlValue(strings.TrimSpace(command), "          "))
+		if pl.ProjectType == api.BATCH {
+			out.WriteString("        shell: cmd\n")
+		}
+		exp.writeGithubStageOption(out, stage)
+
+		if reports := splitPatterns(reportPatterns); len(reports) > 0 {
+			fmt.Fprintf(out, "      - name: Publish Test Reports\n        if: always()\n        uses: actions/upload-artifact@v4\n"+
+				"        with:\n          name: test-reports%s\n          path: %s\n", artifactSuffix, yamlValue(strings.Join(reports, "\n"), "            "))
+		}
+	}
+
+	if artifacts := pl.Artifacts; artifacts != nil {
+		paths := artifactIncludes(pl)
+		for _, exclude := range artifacts.Excludes {
+			paths = append(paths, "!"+exclude)
+		}
+		condition, ifNoFiles := "always()", "error"
+		if artifacts.OnlyOnSuccess {
+			condition = "success()"
+		}
+		if artifacts.AllowEmpty {
+			ifNoFiles = "ignore"
+		}
+		fmt.Fprintf(out, "      - name: Archive Artifacts\n        if: %s\n        uses: actions/upload-artifact@v4\n"+
+			"        with:\n          name: artifacts%s\n          path: %s\n          if-no-files-found: %s\n",
+			condition, artifactSuffix, yamlValue(strings.Join(paths, "\n"), "            "), ifNoFiles)
+		if pl.Retention != nil && pl.Retention.ArtifactDaysToKeep > 0 {
+			fmt.Fprintf(out, "          retention-days: %d\n", pl.Retention.ArtifactDaysToKeep)
+		}
+		if pl.Retention != nil && pl.Retention.ArtifactNumToKeep > 0 {
+			exp.warn("The number of the builds to keep the artifacts is not mapped")
+		}
+	}
+
+	return out.String()
+}
+
+// writeGithubInputs Writes the parameters as the inputs of the manual runs, which are always enabled as performing the pipeline
+func (exp *exporter) writeGithubInputs(out *bytes.Buffer) {
+	pl := exp.pipeline
+	out.WriteString("  workflow_dispatch:\n")
+	if len(pl.Parameters) == 0 {
+		return
+	}
+
+	out.WriteString("    inputs:\n")
+	for _, param := range pl.Parameters {
+		inputType := "string"
+		switch param.Type {
+		case api.CHOICE_PARAM:
+			inputType = "choice"
+		case api.BOOLEAN_PARAM:
+			inputType = "boolean"
+		}
+
+		fmt.Fprintf(out, "      %s:\n", param.Name)
+		if len(param.Description) > 0 {
+			fmt.Fprintf(out, "        description: %s\n", yamlString(param.Description))
+		}
+		defaultValue := yamlString(parameterDefault(param))
+		if param.Type == api.BOOLEAN_PARAM {
+			defaultValue = parameterDefault(param)
+		}
+		fmt.Fprintf(out, "        type: %s\n        default: %s\n", inputType, defaultValue)
+		if param.Type == api.CHOICE_PARAM {
+			out.WriteString("        options:\n")
+			for _, choice := range param.Choices {
+				fmt.Fprintf(out, "          - %s\n", yamlString(choice))
+			}
+		}
+	}
+}
+
+// writeGithubMatrix Writes the cells of the matrix as the included combinations, so that the excluded ones are not built
+func (exp *exporter) writeGithubMatrix(out *bytes.Buffer) {
+	pl := exp.pipeline
+	out.WriteString("    strategy:\n      fail-fast: false\n      matrix:\n        include:\n")
+	for _, cell := range matrixCells(pl) {
+		values := []string{}
+		if len(pl.Matrix.Jdks) > 0 {
+			values = append(values, "java: "+yamlString(majorVersion(cell.Jdk)))
+		}
+		for _, name := range sortedKeys(cell.Env) {
+			values = append(values, name+": "+yamlString(cell.Env[name]))
+		}
+		fmt.Fprintf(out, "          - %s\n", strings.Join(values, "\n            "))
+	}
+	if len(pl.Matrix.NodeLabels) > 0 {
+		exp.warn("The node labels of the matrix are not mapped")
+	}
+
+	if len(pl.Matrix.Env) > 0 {
+		names := []string{}
+		for name := range pl.Matrix.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		out.WriteString("    env:\n")
+		for _, name := range names {
+			fmt.Fprintf(out, "      %s: ${{ matrix.%s }}\n", name, name)
+		}
+	}
+}
+
+// writeGithubStageOption Writes the timeout of the stage step, the other options have no equivalents for the steps
+func (exp *exporter) writeGithubStageOption(out *bytes.Buffer, stage api.Stage) {
+	option := exp.pipeline.StageOptions[stage]
+	if option == nil {
+		return
+	}
+
+	if option.Timeout != nil {
+		fmt.Fprintf(out, "        timeout-minutes: %d\n", minutesOf(option.Timeout))
+	}
+	if option.Retry > 0 {
+		exp.warn("The retry of the %s stage is not mapped", stage)
+	}
+	if len(option.NodeLabel) > 0 {
+		exp.warn("The node label of the %s stage is not mapped, all the stages run on the runner of the job", stage)
+	}
+	if option.Approval != nil {
+		exp.warn("The approval of the %s stage is not mapped, use the environments with required reviewers of GitHub", stage)
+	}
+}
+
+// branchCondition Gets the GitLab CI condition of the branch variable matching the branch or the pattern of the pipeline
+func (exp *exporter) branchCondition(variable string) string {
+	pl := exp.pipeline
+	if pl.Branches != nil {
+		expr, err := antPatternRegexp(pl.Branches.Pattern)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%s =~ /%s/", variable, strings.Replace(expr.String(), "/", `\/`, -1))
+	}
+	if pl.Repo != nil && len(pl.Repo.Branch) > 0 {
+		return fmt.Sprintf("%s == %s", variable, yamlString(pl.Repo.Branch))
+	}
+	return ""
+}
+
+// cronSchedules Converts the Jenkins cron spec into the standard cron expressions. The hashed values H are replaced
+// with the first values of their ranges, and the time zones are ignored, in which case the schedules are approximated.
+func cronSchedules(spec string) ([]string, bool) {
+	schedules := []string{}
+	approximated := false
+	for _, line := range strings.Split(spec, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "TZ=") {
+			approximated = true
+			continue
+		}
+		if alias, ok := cronAliases[line]; ok {
+			line = alias
+		}
+
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if !strings.Contains(field, "H") || i >= len(cronFieldMinimums) {
+				continue
+			}
+			approximated = true
+			field = strings.Replace(field, "H/", "*/", -1)
+			field = hashRangePattern.ReplaceAllString(field, "$1")
+			fields[i] = strings.Replace(field, "H", cronFieldMinimums[i], -1)
+		}
+		schedules = append(schedules, strings.Join(fields, " "))
+	}
+
+	return schedules, approximated
+}
+
+// exportStages Gets the stages run by the pipeline in order
+func exportStages(pl *api.Pipeline) []api.Stage {
+	stages := []api.Stage{}
+	for _, stage := range []api.Stage{api.COMPILE, api.UT, api.BUILD} {
+		if containStage(pl.Stages, stage) {
+			stages = append(stages, stage)
+		}
+	}
+	return stages
+}
+
+// artifactIncludes Gets the patterns of the artifacts, the default ones of the project type if not specified
+func artifactIncludes(pl *api.Pipeline) []string {
+	includes := []string{}
+	for _, include := range pl.Artifacts.Includes {
+		includes = append(includes, splitPatterns(include)...)
+	}
+	if len(includes) == 0 {
+		includes = append(includes, defaultArtifacts[pl.ProjectType]...)
+	}
+	return includes
+}
+
+// credentialVariables Gets the environment variables bound to the credentials
+func credentialVariables(bindings []*api.CredentialBinding) []string {
+	variables := []string{}
+	for _, binding := range bindings {
+		for _, variable := range []string{binding.Variable, binding.UsernameVariable, binding.PasswordVariable} {
+			if len(variable) > 0 {
+				variables = append(variables, variable)
+			}
+		}
+	}
+	return variables
+}
+
+// parameterDefault Gets the default value of the parameter, the first choice is the default of the choice parameter
+func parameterDefault(param *api.Parameter) string {
+	switch param.Type {
+	case api.BOOLEAN_PARAM:
+		b, _ := strconv.ParseBool(param.Default)
+		return strconv.FormatBool(b)
+	case api.CHOICE_PARAM:
+		if len(param.Default) == 0 && len(param.Choices) > 0 {
+			return param.Choices[0]
+		}
+	}
+	return param.Default
+}
+
+// splitPatterns Splits the comma-separated patterns, such as the test reports of the Jenkins JUnit step
+func splitPatterns(patterns string) []string {
+	result := []string{}
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); len(pattern) > 0 {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
+
+// majorVersion Gets the major version of the JDK, such as 8 of jdk1.8
+func majorVersion(jdk string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(jdk, "jdk"), "1.")
+}
+
+// minutesOf Gets the minutes of the timeout, rounded up
+func minutesOf(timeout *api.Timeout) int {
+	return int(math.Ceil(durationOf(timeout).Minutes()))
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// yamlString Quotes the value as a YAML double-quoted string, which is the same as a JSON string
+func yamlString(value string) string {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// yamlValue Gets the value as a literal block indented by the indent if it has multiple lines, otherwise a quoted string
+func yamlValue(value, indent string) string {
+	value = strings.TrimRight(value, "\n")
+	if !strings.Contains(value, "\n") {
+		return yamlString(value)
+	}
+
+	block := "|"
+	for _, line := range strings.Split(value, "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			block += "\n"
+			continue
+		}
+		block += "\n" + indent + line
+	}
+	return block
+}
@@ -0,0 +1,10 @@
+package pipeline
+
+// GeneratePipelineJobConfig exports the generator of the Jenkins job configs for the tests
+var GeneratePipelineJobConfig = generatePipelineJobConfig
+
+// ParsePasswordDefaults exports the parser of the password defaults in the Jenkins job configs for the tests
+var ParsePasswordDefaults = parsePasswordDefaults
+
+// FullNameOfUrl exports the parser of the Jenkins job urls for the tests
+var FullNameOfUrl = fullNameOfUrl
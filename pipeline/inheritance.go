@@ -0,0 +1,384 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/store"
+)
+
+const templateKind = "templates"
+
+var (
+	templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+	// The fields owned by each pipeline, which can not be defined by the templates
+	pipelineOwnedFields = []string{"name", "namespace", "template", "overrides", "branch_of"}
+)
+
+// DecodePipeline Decodes the pipeline definition, the project is decoded according to the project type
+func DecodePipeline(definition map[string]interface{}) (*api.Pipeline, error) {
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := &api.Pipeline{}
+	if err = json.Unmarshal(data, pipeline); err != nil {
+		return nil, err
+	}
+
+	projectData, err := json.Marshal(pipeline.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	projectType := pipeline.ProjectType
+	switch projectType {
+	case api.SHELL, api.BATCH:
+		project := api.ScriptProject{}
+		err = json.Unmarshal(projectData, &project)
+		pipeline.Project = project
+	case api.MAVEN:
+		project := api.MavenProject{}
+		err = json.Unmarshal(projectData, &project)
+		pipeline.Project = project
+	case api.GRADLE:
+		project := api.GradleProject{}
+		err = json.Unmarshal(projectData, &project)
+		pipeline.Project = project
+	default:
+		return nil, fmt.Errorf("The project type %s is not supported", projectType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pipeline, nil
+}
+
+// decodeSavedPipeline Decodes the project of the saved pipeline config, which is loaded from the store as a map
+func decodeSavedPipeline(pl *api.Pipeline) (*api.Pipeline, error) {
+	data, err := json.Marshal(pl)
+	if err != nil {
+		return nil, err
+	}
+	definition := map[string]interface{}{}
+	if err = json.Unmarshal(data, &definition); err != nil {
+		return nil, err
+	}
+
+	return DecodePipeline(definition)
+}
+
+// MergePipeline Merges the overrides into the template definition deeply, the template is not changed.
+// The objects such as project, repo and triggers are merged field by field, the arrays such as stages
+// are replaced as a whole, and the null values remove the fields of the template.
+func MergePipeline(template, overrides map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range template {
+		merged[key] = value
+	}
+
+	for key, value := range overrides {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+
+		base, baseIsMap := merged[key].(map[string]interface{})
+		override, overrideIsMap := value.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			merged[key] = MergePipeline(base, override)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+// RenderPipeline Renders the pipeline from the definition of the request. The definition referencing
+// a template is merged into the template, and kept as the overrides of the pipeline to re-render it.
+func (mgr *Manager) RenderPipeline(definition map[string]interface{}) (*api.Pipeline, error) {
+	delete(definition, "overrides")
+
+	templateName, ok := definition["template"].(string)
+	if !ok && definition["template"] != nil {
+		return nil, fmt.Errorf("The template should be a template name")
+	}
+	if len(templateName) == 0 {
+		return DecodePipeline(definition)
+	}
+
+	tpl, err := mgr.GetTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	pl, err := DecodePipeline(MergePipeline(tpl.Pipeline, definition))
+	if err != nil {
+		return nil, err
+	}
+	pl.Overrides = definition
+
+	return pl, nil
+}
+
+// CreateTemplate Creates the pipeline template
+func (mgr *Manager) CreateTemplate(tpl *api.PipelineTemplate) error {
+	if ok := ValidateTemplate(tpl); !ok {
+		return fmt.Errorf("The template is not correct")
+	}
+
+	if _, err := mgr.GetTemplate(tpl.Name); err == nil {
+		return fmt.Errorf("The template %s already exists", tpl.Name)
+	}
+
+	return mgr.store.Put(templateKind, tpl.Name, tpl)
+}
+
+// UpdateTemplate Updates the pipeline template, the pipelines using it are not re-rendered until it is rolled out
+func (mgr *Manager) UpdateTemplate(tpl *api.PipelineTemplate) error {
+	if ok := ValidateTemplate(tpl); !ok {
+		return fmt.Errorf("The template is not correct")
+	}
+
+	if _, err := mgr.GetTemplate(tpl.Name); err != nil {
+		return err
+	}
+
+	return mgr.store.Put(templateKind, tpl.Name, tpl)
+}
+
+// DeleteTemplate Deletes the pipeline template which is not used by any pipeline
+func (mgr *Manager) DeleteTemplate(name string) error {
+	pls, err := mgr.templatePipelines(name)
+	if err != nil {
+		return err
+	}
+	if len(pls) > 0 {
+		return fmt.Errorf("The template %s is used by %d pipelines", name, len(pls))
+	}
+
+	err = mgr.store.Delete(templateKind, name)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Errorf("The template %s does not exist", name)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetTemplate Gets the pipeline template
+func (mgr *Manager) GetTemplate(name string) (*api.PipelineTemplate, error) {
+	tpl := &api.PipelineTemplate{}
+	err := mgr.store.Get(templateKind, name, tpl)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, fmt.Errorf("The template %s does not exist", name)
+		}
+		return nil, fmt.Errorf("Fail to get the template %s as %s", name, err.Error())
+	}
+
+	return tpl, nil
+}
+
+// ListTemplates Lists all the pipeline templates
+func (mgr *Manager) ListTemplates() ([]*api.PipelineTemplate, error) {
+	names, err := mgr.store.List(templateKind)
+	if err != nil {
+		return nil, err
+	}
+
+	tpls := []*api.PipelineTemplate{}
+	for _, name := range names {
+		tpl, err := mgr.GetTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		tpls = append(tpls, tpl)
+	}
+
+	return tpls, nil
+}
+
+// PreviewTemplate Re-renders the pipelines using the template without updating them
+func (mgr *Manager) PreviewTemplate(name string) ([]*api.TemplateRendering, error) {
+	tpl, err := mgr.GetTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return mgr.renderTemplate(tpl)
+}
+
+// PreviewTemplateChange Re-renders the pipelines using the template with the proposed template,
+// neither the template nor the pipelines are updated.
+func (mgr *Manager) PreviewTemplateChange(tpl *api.PipelineTemplate) ([]*api.TemplateRendering, error) {
+	if ok := ValidateTemplate(tpl); !ok {
+		return nil, fmt.Errorf("The template is not correct")
+	}
+
+	if _, err := mgr.GetTemplate(tpl.Name); err != nil {
+		return nil, err
+	}
+
+	return mgr.renderTemplate(tpl)
+}
+
+// renderTemplate Re-renders the pipelines using the template with the given template
+func (mgr *Manager) renderTemplate(tpl *api.PipelineTemplate) ([]*api.TemplateRendering, error) {
+	pls, err := mgr.templatePipelines(tpl.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	renderings := []*api.TemplateRendering{}
+	for _, pl := range pls {
+		rendering := &api.TemplateRendering{Pipeline: fullNameOf(pl)}
+		renderings = append(renderings, rendering)
+
+		config, err := DecodePipeline(MergePipeline(tpl.Pipeline, pl.Overrides))
+		if err != nil {
+			rendering.Error = err.Error()
+			continue
+		}
+		config.Overrides = pl.Overrides
+		rendering.Config = config
+
+		if ok := ValidatePipeline(config); !ok {
+			rendering.Error = "Pipeline config is not correct"
+			continue
+		}
+
+		rendering.Changed, err = isPipelineChanged(pl, config)
+		if err != nil {
+			rendering.Error = err.Error()
+		}
+	}
+
+	return renderings, nil
+}
+
+// RolloutTemplate Re-renders the pipelines using the template, and updates the changed ones.
+// The failures of some pipelines do not stop updating the others.
+func (mgr *Manager) RolloutTemplate(name string) ([]*api.TemplateRendering, error) {
+	renderings, err := mgr.PreviewTemplate(name)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	for _, rendering := range renderings {
+		if len(rendering.Error) > 0 || !rendering.Changed {
+			continue
+		}
+
+		// The rendered config has no defaults of the password parameters, as the overrides are saved without them
+		config := rendering.Config
+		if config.Branches != nil {
+			config, err = mgr.withFanoutPasswordDefaults(config)
+		} else {
+			config, err = mgr.withPasswordDefaults(config, rendering.Pipeline)
+		}
+		if err == nil {
+			err = mgr.Update(config)
+		}
+		if err != nil {
+			rendering.Error = err.Error()
+			continue
+		}
+		log.Infof("Roll out the template %s to the pipeline %s", name, rendering.Pipeline)
+	}
+
+	return renderings, nil
+}
+
+// templatePipelines Gets the configs of the pipelines using the template
+func (mgr *Manager) templatePipelines(name string) ([]*api.Pipeline, error) {
+	names, err := mgr.store.List(pipelineKind)
+	if err != nil {
+		return nil, err
+	}
+
+	pls := []*api.Pipeline{}
+	for _, plName := range names {
+		pl, err := getPipeline(mgr.store, plName)
+		if err != nil {
+			return nil, err
+		}
+		if pl.Template == name {
+			pls = append(pls, pl)
+		}
+	}
+
+	// The branch pipelines are rolled out by their fan-out pipelines
+	fanouts, err := mgr.listFanouts()
+	if err != nil {
+		return nil, err
+	}
+	for _, pl := range fanouts {
+		if pl.Template == name {
+			pls = append(pls, pl)
+		}
+	}
+
+	return pls, nil
+}
+
+// isPipelineChanged Compares the pipeline configs by their JSON values, as the projects of
+// the saved configs are not decoded to the project types.
+func isPipelineChanged(current, rendered *api.Pipeline) (bool, error) {
+	values := []interface{}{}
+	for _, pl := range []*api.Pipeline{current, rendered} {
+		data, err := json.Marshal(pl)
+		if err != nil {
+			return false, err
+		}
+		var value interface{}
+		if err = json.Unmarshal(data, &value); err != nil {
+			return false, err
+		}
+		values = append(values, value)
+	}
+
+	return !reflect.DeepEqual(values[0], values[1]), nil
+}
+
+// ValidateTemplate Validates the name of the template and the fields it defines
+func ValidateTemplate(tpl *api.PipelineTemplate) bool {
+	if !templateNamePattern.MatchString(tpl.Name) {
+		log.Errorf("The template name %s should only contain letters, digits, underscores, dots and hyphens", tpl.Name)
+		return false
+	}
+
+	if len(tpl.Pipeline) == 0 {
+		log.Errorf("The template %s defines nothing", tpl.Name)
+		return false
+	}
+
+	for _, field := range pipelineOwnedFields {
+		if _, ok := tpl.Pipeline[field]; ok {
+			log.Errorf("The %s of pipeline can not be defined by the template %s", field, tpl.Name)
+			return false
+		}
+	}
+
+	// The defaults of the password parameters are not saved
+	params, _ := tpl.Pipeline["parameters"].([]interface{})
+	for _, param := range params {
+		if fields, ok := param.(map[string]interface{}); ok && fields["type"] == string(api.PASSWORD_PARAM) && fields["default"] != nil {
+			log.Errorf("The default of password parameter %v can not be defined by the template %s", fields["name"], tpl.Name)
+			return false
+		}
+	}
+
+	return true
+}
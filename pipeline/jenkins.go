@@ -0,0 +1,247 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bndr/gojenkins"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/config"
+	"github.com/supereagle/goline/store"
+)
+
+// JenkinsBackend runs the pipelines as the Jenkins pipeline jobs
+type JenkinsBackend struct {
+	Jenkins      *gojenkins.Jenkins
+	credentialId string
+	retention    *api.Retention
+	store        *store.Store
+	// The Jenkins user of goline, which submits the approvals
+	user string
+}
+
+func NewJenkinsBackend(cfg *config.Config, st *store.Store) (*JenkinsBackend, error) {
+	if len(cfg.JenkinsServer) == 0 {
+		return nil, fmt.Errorf("The Jenkins server url should not be empty")
+	}
+
+	// Create the Jenkins Instance
+	jenkins, err := gojenkins.CreateJenkins(cfg.JenkinsServer, cfg.JenkinsUser, cfg.JenkinsPassword).Init()
+	if err != nil {
+		return nil, fmt.Errorf("Fail to create Jenkins Instance as %s", err.Error())
+	}
+
+	return &JenkinsBackend{
+		Jenkins:      jenkins,
+		credentialId: cfg.JenkinsCredentialId,
+		user:         cfg.JenkinsUser,
+		retention:    cfg.Retention,
+		store:        st,
+	}, nil
+}
+
+// Create Creates the pipeline job and adds it into the views of its tags
+func (backend *JenkinsBackend) Create(pl *api.Pipeline) error {
+	// Generate the pipeline job config
+	jobCfg, err := generatePipelineJobConfig(pl, backend.credentialId, backend.retention, backend.user)
+	if err != nil {
+		return fmt.Errorf("Fail to generate pipeline config as %s", err.Error())
+	}
+
+	// Check the credentials referenced by the pipeline
+	err = backend.checkCredentials(pl)
+	if err != nil {
+		return err
+	}
+
+	// Create the pipeline job
+	err = backend.createJob(pl, jobCfg)
+	if err != nil {
+		return err
+	}
+
+	// Add the pipeline into the views of its tags, the failure does not undo the created job
+	if err = backend.syncViews(fullNameOf(pl), nil, pl.Tags); err != nil {
+		log.Warnf("Fail to sync the views of pipeline %s as %s", fullNameOf(pl), err.Error())
+	}
+
+	return nil
+}
+
+// Update Updates the pipeline job and moves it into the views of its new tags
+func (backend *JenkinsBackend) Update(pl *api.Pipeline) error {
+	// Check the existence of the pipeline job
+	job, err := backend.getJob(fullNameOf(pl))
+	if err != nil {
+		return err
+	}
+
+	// Generate the pipeline job config
+	jobCfg, err := generatePipelineJobConfig(pl, backend.credentialId, backend.retention, backend.user)
+	if err != nil {
+		return fmt.Errorf("Fail to generate pipeline config as %s", err.Error())
+	}
+
+	// Check the credentials referenced by the pipeline
+	err = backend.checkCredentials(pl)
+	if err != nil {
+		return err
+	}
+
+	// Update the pipeline job
+	err = job.UpdateConfig(jobCfg)
+	if err != nil {
+		return err
+	}
+
+	// The old tags are got from the pipeline config not saved yet,
+	// the failure of the views does not undo the updated job
+	oldTags, err := backend.storedTags(fullNameOf(pl))
+	if err == nil {
+		err = backend.syncViews(fullNameOf(pl), oldTags, pl.Tags)
+	}
+	if err != nil {
+		log.Warnf("Fail to sync the views of pipeline %s as %s", fullNameOf(pl), err.Error())
+	}
+
+	return nil
+}
+
+// Delete Deletes the pipeline job
+func (backend *JenkinsBackend) Delete(plName string) error {
+	// Check the existence of the pipeline job
+	job, err := backend.getJob(plName)
+	if err != nil {
+		return err
+	}
+
+	_, err = job.Delete()
+	return err
+}
+
+// Perform Invokes the pipeline job with the perform parameters
+func (backend *JenkinsBackend) Perform(plName string, pParams *api.PerformParams) error {
+	// Check the existence of the pipeline job
+	job, err := backend.getJob(plName)
+	if err != nil {
+		return err
+	}
+
+	// Check the params against the parameters declared by the pipeline
+	definitions, err := backend.getParameterDefinitions(job)
+	if err != nil {
+		return err
+	}
+	err = validatePerformParams(definitions, pParams.Params)
+	if err != nil {
+		return err
+	}
+
+	params := make(map[string]string)
+	for name, value := range pParams.Params {
+		params[name] = value
+	}
+	// The default values are used for the params not specified. The empty branch and perform phases
+	// are not sent either, they used to override the defaults and check out or perform nothing.
+	if len(pParams.Branch) > 0 {
+		params["branch"] = pParams.Branch
+	}
+	if len(pParams.Revision) > 0 {
+		params["revision"] = pParams.Revision
+	}
+	if len(pParams.PerformPhases) > 0 {
+		params["performPhases"] = pParams.PerformPhases
+	}
+	if pParams.PurgeCache {
+		if !containParameter(definitions, purgeCacheParameter) {
+			return fmt.Errorf("The pipeline %s has no dependency cache to purge", plName)
+		}
+		params[purgeCacheParameter] = "true"
+	}
+
+	// Invoke the pipeline job with params
+	//_, err = job.Invoke(nil, false, params, "", "")
+	_, err = job.InvokeSimple(params)
+	return err
+}
+
+// GetBuildLog Gets the console text of the build by the url of the job, which works for the jobs in folders
+func (backend *JenkinsBackend) GetBuildLog(plName string, number int64) (string, error) {
+	job, err := backend.getJob(plName)
+	if err != nil {
+		return "", err
+	}
+
+	content := ""
+	resp, err := backend.Jenkins.Requester.Get(fmt.Sprintf("%s/%d/consoleText", job.Base, number), &content, nil)
+	if err != nil {
+		return "", fmt.Errorf("Fail to get the log of build %d of pipeline %s as %s", number, plName, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Fail to get the log of build %d of pipeline %s as %s", number, plName, resp.Status)
+	}
+
+	return content, nil
+}
+
+// GetPasswordDefaults Gets the defaults of the password parameters from the config of the pipeline job.
+// They are encrypted by Jenkins, which decrypts them again when the job is updated with them.
+func (backend *JenkinsBackend) GetPasswordDefaults(plName string) (map[string]string, error) {
+	job, err := backend.getJob(plName)
+	if err != nil {
+		return nil, err
+	}
+
+	jobCfg, err := job.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Fail to get the config of pipeline %s as %s", plName, err.Error())
+	}
+
+	return parsePasswordDefaults(jobCfg)
+}
+
+// getParameterDefinitions Gets the parameter definitions of the pipeline job
+func (backend *JenkinsBackend) getParameterDefinitions(job *gojenkins.Job) ([]parameterDefinition, error) {
+	result := struct {
+		Property []struct {
+			ParameterDefinitions []parameterDefinition `json:"parameterDefinitions"`
+		} `json:"property"`
+	}{}
+
+	querystring := map[string]string{
+		"tree": "property[parameterDefinitions[name,type,choices]]",
+	}
+	_, err := backend.Jenkins.Requester.GetJSON(job.Base, &result, querystring)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to get the parameters of pipeline %s as %s", job.GetName(), err.Error())
+	}
+
+	definitions := []parameterDefinition{}
+	for _, property := range result.Property {
+		definitions = append(definitions, property.ParameterDefinitions...)
+	}
+
+	return definitions, nil
+}
+
+// getJob Gets the specified pipeline job, return NotExistError if not exists
+func (backend *JenkinsBackend) getJob(plName string) (*gojenkins.Job, error) {
+	job, err := backend.Jenkins.GetJob(jobId(plName))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, &NotExistError{Pipeline: plName}
+		}
+		err = fmt.Errorf("Fail to get the pipeline %s as %s", plName, err.Error())
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// isNotFound Judges whether the error returned by Jenkins is caused by 404 Not Found
+func isNotFound(err error) bool {
+	return strings.Contains(err.Error(), strconv.Itoa(http.StatusNotFound))
+}
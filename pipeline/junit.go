@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/supereagle/goline/api"
+)
+
+// junitSuite is the test suite in the JUnit XML reports, the reports of some tools nest the suites
+type junitSuite struct {
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []struct {
+		Failures []struct{} `xml:"failure"`
+		Errors   []struct{} `xml:"error"`
+		Skipped  *struct{}  `xml:"skipped"`
+	} `xml:"testcase"`
+}
+
+// antPatternRegexp Converts the Ant-style pattern used by the Jenkins JUnit step into the regexp of the slash paths
+func antPatternRegexp(pattern string) (*regexp.Regexp, error) {
+	expr := ""
+	for i := 0; i < len(pattern); {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr += "(.*/)?"
+			i += 3
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr += ".*"
+			i += 2
+		case pattern[i] == '*':
+			expr += "[^/]*"
+			i++
+		case pattern[i] == '?':
+			expr += "[^/]"
+			i++
+		default:
+			expr += regexp.QuoteMeta(pattern[i : i+1])
+			i++
+		}
+	}
+
+	return regexp.Compile("^" + expr + "$")
+}
+
+// findTestReports Finds the files in the workspace matching the comma-separated Ant-style patterns
+func findTestReports(workspace, patterns string) ([]string, error) {
+	exprs := []*regexp.Regexp{}
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "./")
+		if len(pattern) == 0 {
+			continue
+		}
+		expr, err := antPatternRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("The test report pattern %s is not correct as %s", pattern, err.Error())
+		}
+		exprs = append(exprs, expr)
+	}
+
+	reports := []string{}
+	err := filepath.Walk(workspace, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(workspace, path)
+		if err != nil {
+			return err
+		}
+		for _, expr := range exprs {
+			if expr.MatchString(filepath.ToSlash(rel)) {
+				reports = append(reports, path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Fail to find the test reports as %s", err.Error())
+	}
+
+	return reports, nil
+}
+
+// summarizeTestReport Counts the test cases of the JUnit XML report into the summary
+func summarizeTestReport(path string, summary *api.TestSummary) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Fail to read the test report %s as %s", path, err.Error())
+	}
+
+	suite := junitSuite{}
+	if err = xml.Unmarshal(contents, &suite); err != nil {
+		return fmt.Errorf("Fail to parse the test report %s as %s", path, err.Error())
+	}
+
+	countJunitSuite(suite, summary)
+	return nil
+}
+
+func countJunitSuite(suite junitSuite, summary *api.TestSummary) {
+	for _, c := range suite.Cases {
+		status := "PASSED"
+		if len(c.Failures) > 0 || len(c.Errors) > 0 {
+			status = "FAILED"
+		} else if c.Skipped != nil {
+			status = "SKIPPED"
+		}
+		countTestCase(summary, status)
+	}
+
+	for _, nested := range suite.Suites {
+		countJunitSuite(nested, summary)
+	}
+}
@@ -0,0 +1,867 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/config"
+	"github.com/supereagle/goline/store"
+)
+
+const (
+	// localPipelineKind is the kind of the pipelines known by the local backend
+	localPipelineKind = "localpipelines"
+	// localBuildKind is the kind of the build history of the local backend, keyed by <pipeline>/<number>
+	localBuildKind = "localbuilds"
+)
+
+// The names of the stages in the build status and the exported configs, same as the ones of the Jenkins builds
+var stageNames = map[api.Stage]string{
+	api.COMPILE: "Compile",
+	api.UT:      "Unit Test",
+	api.BUILD:   "Build",
+}
+
+// localPipeline is the pipeline known by the local backend, LastBuild is the number of its last build.
+// PasswordDefaults are the defaults of the password parameters, which are not saved with the pipeline config.
+type localPipeline struct {
+	LastBuild        int64             `json:"last_build"`
+	PasswordDefaults map[string]string `json:"password_defaults,omitempty"`
+}
+
+// localBuild is the build in the history of the local backend
+type localBuild struct {
+	Number    int64              `json:"number"`
+	Params    map[string]string  `json:"params"`
+	Running   bool               `json:"running"`
+	Result    string             `json:"result,omitempty"`
+	Stages    []*api.StageStatus `json:"stages"`
+	Tests     api.TestSummary    `json:"tests"`
+	StartTime time.Time          `json:"start_time"`
+}
+
+// LocalBackend runs the stages of the pipelines as the local shell processes in the temp workspaces,
+// which needs no Jenkins. The build history is saved in the store, and the build logs in the log dir.
+type LocalBackend struct {
+	logDir string
+	store  *store.Store
+	// mutex protects the build history, the running builds and the event handler
+	mutex sync.Mutex
+	// The cancel functions of the running builds of the pipelines
+	running map[string]map[int64]context.CancelFunc
+	handler func(*api.BuildEvent)
+}
+
+func NewLocalBackend(cfg *config.Config, st *store.Store) (*LocalBackend, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("The local backend needs git to check out the source code as %s", err.Error())
+	}
+
+	logDir := filepath.Join(cfg.DataDir, "logs")
+	if err := os.MkdirAll(logDir, 0700); err != nil {
+		return nil, fmt.Errorf("Fail to create the log dir %s as %s", logDir, err.Error())
+	}
+
+	// The builds running when goline stopped can not be resumed
+	keys, err := st.List(localBuildKind)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		build := &localBuild{}
+		if err = st.Get(localBuildKind, key, build); err != nil {
+			return nil, err
+		}
+		if build.Running {
+			build.Running = false
+			build.Result = "ABORTED"
+			if err = st.Put(localBuildKind, key, build); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &LocalBackend{
+		logDir:  logDir,
+		store:   st,
+		running: map[string]map[int64]context.CancelFunc{},
+	}, nil
+}
+
+// Create Creates the pipeline after checking it can be run locally
+func (backend *LocalBackend) Create(pl *api.Pipeline) error {
+	if err := checkLocalPipeline(pl); err != nil {
+		return err
+	}
+
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	name := fullNameOf(pl)
+	_, err := backend.getLocalPipeline(name)
+	if err == nil {
+		return fmt.Errorf("The pipeline %s already exists", name)
+	}
+	if _, ok := err.(*NotExistError); !ok {
+		return err
+	}
+
+	return backend.store.Put(localPipelineKind, name, &localPipeline{PasswordDefaults: passwordDefaultsOf(pl)})
+}
+
+// Update Checks the new config of the pipeline can be run locally, which is used by the next builds,
+// and keeps the new defaults of its password parameters
+func (backend *LocalBackend) Update(pl *api.Pipeline) error {
+	if err := checkLocalPipeline(pl); err != nil {
+		return err
+	}
+
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	name := fullNameOf(pl)
+	local, err := backend.getLocalPipeline(name)
+	if err != nil {
+		return err
+	}
+	local.PasswordDefaults = passwordDefaultsOf(pl)
+
+	return backend.store.Put(localPipelineKind, name, local)
+}
+
+// Delete Deletes the pipeline with its build history and logs, the running builds are aborted
+func (backend *LocalBackend) Delete(plName string) error {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	local, err := backend.getLocalPipeline(plName)
+	if err != nil {
+		return err
+	}
+
+	for _, cancel := range backend.running[plName] {
+		cancel()
+	}
+	delete(backend.running, plName)
+
+	for number := int64(1); number <= local.LastBuild; number++ {
+		err = backend.store.Delete(localBuildKind, localBuildKey(plName, number))
+		if err != nil && err != store.ErrNotFound {
+			return err
+		}
+	}
+	if err = os.RemoveAll(backend.pipelineLogDir(plName)); err != nil {
+		return fmt.Errorf("Fail to delete the logs of pipeline %s as %s", plName, err.Error())
+	}
+
+	return backend.store.Delete(localPipelineKind, plName)
+}
+
+// Perform Starts a build of the pipeline with the perform parameters, the build runs in background
+func (backend *LocalBackend) Perform(plName string, pParams *api.PerformParams) error {
+	local, err := backend.getLocalPipeline(plName)
+	if err != nil {
+		return err
+	}
+
+	pl, err := getPipeline(backend.store, plName)
+	if err != nil {
+		return err
+	}
+	pl, err = decodeSavedPipeline(pl)
+	if err != nil {
+		return err
+	}
+	pl = restorePasswordDefaults(pl, local.PasswordDefaults)
+
+	params, err := localBuildParameters(pl, pParams)
+	if err != nil {
+		return err
+	}
+
+	backend.mutex.Lock()
+	local, err = backend.getLocalPipeline(plName)
+	if err != nil {
+		backend.mutex.Unlock()
+		return err
+	}
+	local.LastBuild++
+	build := &localBuild{
+		Number:    local.LastBuild,
+		Params:    params,
+		Running:   true,
+		Stages:    []*api.StageStatus{},
+		StartTime: time.Now(),
+	}
+	err = backend.store.Put(localBuildKind, localBuildKey(plName, build.Number), build)
+	if err == nil {
+		err = backend.store.Put(localPipelineKind, plName, local)
+	}
+	if err != nil {
+		backend.mutex.Unlock()
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if backend.running[plName] == nil {
+		backend.running[plName] = map[int64]context.CancelFunc{}
+	}
+	backend.running[plName][build.Number] = cancel
+	backend.mutex.Unlock()
+
+	go backend.run(ctx, pl, build)
+	return nil
+}
+
+// GetPasswordDefaults Gets the defaults of the password parameters kept for the pipeline
+func (backend *LocalBackend) GetPasswordDefaults(plName string) (map[string]string, error) {
+	local, err := backend.getLocalPipeline(plName)
+	if err != nil {
+		return nil, err
+	}
+
+	return local.PasswordDefaults, nil
+}
+
+// GetBuildStatus Gets the status of the build and its stages from the build history
+func (backend *LocalBackend) GetBuildStatus(plName string, number int64) (*api.BuildStatus, error) {
+	build, err := backend.getBuild(plName, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.BuildStatus{
+		Pipeline: plName,
+		Build:    number,
+		Running:  build.Running,
+		Result:   build.Result,
+		Stages:   build.Stages,
+	}, nil
+}
+
+// GetBuildLog Gets the log of the build, which is empty before the build starts
+func (backend *LocalBackend) GetBuildLog(plName string, number int64) (string, error) {
+	if _, err := backend.getBuild(plName, number); err != nil {
+		return "", err
+	}
+
+	content, err := ioutil.ReadFile(backend.buildLogPath(plName, number))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("Fail to read the log of build %d of pipeline %s as %s", number, plName, err.Error())
+	}
+
+	return string(content), nil
+}
+
+// GetTestReport Gets the summary of the JUnit reports of the build
+func (backend *LocalBackend) GetTestReport(plName string, number int64) (*api.TestReport, error) {
+	build, err := backend.getBuild(plName, number)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	return &api.TestReport{
+		Pipeline:    plName,
+		Build:       number,
+		TestSummary: build.Tests,
+	}, nil
+}
+
+// GetBuildParameters Gets the parameters of the build from the build history
+func (backend *LocalBackend) GetBuildParameters(plName string, number int64) (map[string]string, error) {
+	build, err := backend.getBuild(plName, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return build.Params, nil
+}
+
+// WatchBuilds Emits the build events to the handler, the local builds emit their events when they change
+// so that the interval is not used.
+func (backend *LocalBackend) WatchBuilds(interval time.Duration, handler func(*api.BuildEvent)) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	backend.handler = handler
+}
+
+// run Runs the build and triggers the pipelines depending on its result
+func (backend *LocalBackend) run(ctx context.Context, pl *api.Pipeline, build *localBuild) {
+	plName := fullNameOf(pl)
+	backend.emit(newBuildEvent(api.BUILD_STARTED, plName, build.Number))
+
+	build.Result = backend.runStages(ctx, pl, build)
+	build.Running = false
+
+	backend.mutex.Lock()
+	err := backend.saveBuild(plName, build)
+	delete(backend.running[plName], build.Number)
+	backend.mutex.Unlock()
+	if err != nil {
+		log.Errorf("Fail to save the build %d of pipeline %s as %s", build.Number, plName, err.Error())
+	}
+
+	event := newBuildEvent(api.BUILD_COMPLETED, plName, build.Number)
+	event.Result = build.Result
+	backend.emit(event)
+
+	backend.triggerDependents(pl, build.Result)
+}
+
+// runStages Checks out the source code into a temp workspace and runs the stages in it, returns the build result.
+// The build is aborted when it is timeout or its pipeline is deleted.
+func (backend *LocalBackend) runStages(ctx context.Context, pl *api.Pipeline, build *localBuild) string {
+	plName := fullNameOf(pl)
+	logFile, err := backend.createBuildLog(plName, build.Number)
+	if err != nil {
+		log.Errorln(err.Error())
+		return string(api.FAILURE)
+	}
+	defer logFile.Close()
+
+	timeout := pl.Timeout
+	if timeout == nil {
+		timeout = defaultPipelineTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, durationOf(timeout))
+	defer cancel()
+
+	workspace, err := ioutil.TempDir("", "goline-workspace-")
+	if err != nil {
+		fmt.Fprintf(logFile, "[goline] Fail to create the workspace as %s\n", err.Error())
+		return string(api.FAILURE)
+	}
+	defer os.RemoveAll(workspace)
+
+	result := string(api.SUCCESS)
+	env := localEnv(pl, build, workspace)
+	if err = localCheckout(ctx, pl.Repo, build.Params, workspace, env, logFile); err != nil {
+		fmt.Fprintf(logFile, "[goline] Fail to check out the source code as %s\n", err.Error())
+		result = string(api.FAILURE)
+		if ctx.Err() != nil {
+			result = "ABORTED"
+		}
+	}
+
+	for _, stage := range []api.Stage{api.COMPILE, api.UT, api.BUILD} {
+		if result != string(api.SUCCESS) && result != string(api.UNSTABLE) {
+			break
+		}
+		if !containStage(pl.Stages, stage) || !strings.Contains(build.Params["performPhases"], string(stage)) {
+			continue
+		}
+
+		status := &api.StageStatus{Name: stageNames[stage], Status: "IN_PROGRESS"}
+		build.Stages = append(build.Stages, status)
+		backend.mutex.Lock()
+		err = backend.saveBuild(plName, build)
+		backend.mutex.Unlock()
+		if err != nil {
+			log.Errorf("Fail to save the build %d of pipeline %s as %s", build.Number, plName, err.Error())
+		}
+
+		fmt.Fprintf(logFile, "[goline] Stage %s\n", status.Name)
+		status.Status = runLocalStage(ctx, pl, stage, build, workspace, env, logFile)
+		switch status.Status {
+		case "UNSTABLE":
+			result = string(api.UNSTABLE)
+		case "FAILED":
+			result = string(api.FAILURE)
+		case "ABORTED":
+			result = "ABORTED"
+		}
+
+		event := newBuildEvent(api.STAGE_FINISHED, plName, build.Number)
+		event.Stage = status.Name
+		event.Result = status.Status
+		backend.emit(event)
+	}
+
+	fmt.Fprintf(logFile, "Finished: %s\n", result)
+	return result
+}
+
+// runLocalStage Runs the command of the stage with its retries and timeout, and summarizes the test reports
+// of the unit test stage. The unit test stage is unstable if any test fails, same as the Jenkins builds.
+func runLocalStage(ctx context.Context, pl *api.Pipeline, stage api.Stage, build *localBuild, workspace string, env []string, out io.Writer) string {
+	command, reportPatterns := stageCommand(pl, stage)
+	option := pl.StageOptions[stage]
+
+	attempts, backoff := 1, 0
+	if option != nil {
+		attempts += option.Retry
+		backoff = option.RetryBackoff
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			fmt.Fprintf(out, "[goline] Retry in %d seconds\n", backoff)
+			select {
+			case <-ctx.Done():
+				return "ABORTED"
+			case <-time.After(time.Duration(backoff) * time.Second):
+			}
+		}
+
+		stageCtx, cancel := ctx, context.CancelFunc(func() {})
+		if option != nil && option.Timeout != nil {
+			stageCtx, cancel = context.WithTimeout(ctx, durationOf(option.Timeout))
+		}
+		fmt.Fprintf(out, "[goline] %s\n", command)
+		err = runLocalCommand(stageCtx, workspace, env, out, "sh", "-c", command)
+		cancel()
+		if ctx.Err() != nil {
+			return "ABORTED"
+		}
+		if err == nil {
+			break
+		}
+		fmt.Fprintf(out, "[goline] The command failed as %s\n", err.Error())
+	}
+	if err != nil {
+		return "FAILED"
+	}
+
+	if len(reportPatterns) == 0 {
+		return "SUCCESS"
+	}
+	reports, err := findTestReports(workspace, reportPatterns)
+	if err == nil && len(reports) == 0 {
+		err = fmt.Errorf("No test report files were found matching %s", reportPatterns)
+	}
+	for _, report := range reports {
+		if err != nil {
+			break
+		}
+		err = summarizeTestReport(report, &build.Tests)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "[goline] %s\n", err.Error())
+		return "FAILED"
+	}
+
+	fmt.Fprintf(out, "[goline] Tests: %d, Failed: %d, Skipped: %d\n", build.Tests.Total, build.Tests.Failed, build.Tests.Skipped)
+	if build.Tests.Failed > 0 {
+		return "UNSTABLE"
+	}
+	return "SUCCESS"
+}
+
+// stageCommand Gets the shell command of the stage from the project config, and the patterns of the
+// test reports for the unit test stage
+func stageCommand(pl *api.Pipeline, stage api.Stage) (string, string) {
+	switch pl.ProjectType {
+	case api.MAVEN:
+		project := pl.Project.(api.MavenProject)
+		replacer := strings.NewReplacer("${maven.rootpom}", project.RootPom, "${mvn.options}", project.Options)
+		switch stage {
+		case api.COMPILE:
+			return replacer.Replace(LOCAL_MAVEN_COMPILE_COMMAND), ""
+		case api.UT:
+			return replacer.Replace(LOCAL_MAVEN_UNIT_TEST_COMMAND),
+				strings.Replace(LOCAL_MAVEN_TEST_REPORT, "${test.report.path}", project.UnitTest.TestReportPath, 1)
+		default:
+			return replacer.Replace(LOCAL_MAVEN_BUILD_COMMAND), ""
+		}
+	case api.GRADLE:
+		project := pl.Project.(api.GradleProject)
+		replacer := strings.NewReplacer("${gradle.gradleOpts}", project.Options)
+		switch stage {
+		case api.COMPILE:
+			return replacer.Replace(LOCAL_GRADLE_COMPILE_COMMAND), ""
+		case api.UT:
+			return replacer.Replace(LOCAL_GRADLE_UNIT_TEST_COMMAND), project.UnitTest.TestReportPath
+		default:
+			return replacer.Replace(LOCAL_GRADLE_BUILD_COMMAND), ""
+		}
+	default:
+		project := pl.Project.(api.ScriptProject)
+		switch stage {
+		case api.COMPILE:
+			return project.Compile.Command, ""
+		case api.UT:
+			return project.UnitTest.Command, project.UnitTest.TestReportPath
+		default:
+			return project.Build.Command, ""
+		}
+	}
+}
+
+// localCheckout Checks out the source code into the workspace with the Git credentials of the goline host.
+// The head of the pull request is merged into the branch, same as the Jenkins builds.
+func localCheckout(ctx context.Context, repo *api.Repo, params map[string]string, workspace string, env []string, out io.Writer) error {
+	clone := []string{"clone"}
+	if repo.Depth > 0 {
+		clone = append(clone, "--depth", strconv.Itoa(repo.Depth), "--no-single-branch")
+	}
+	commands := [][]string{append(clone, "--", repo.RepoPath, ".")}
+
+	revision := params["revision"]
+	if ref := params[pullRequestRefParameter]; len(ref) > 0 {
+		if len(revision) == 0 {
+			revision = "origin/pull-request"
+		}
+		commands = append(commands, []string{"checkout", params["branch"]},
+			[]string{"fetch", "origin", "+" + ref + ":refs/remotes/origin/pull-request"},
+			[]string{"-c", "user.name=goline", "-c", "user.email=goline@localhost", "merge", "--no-edit", revision})
+	} else if len(revision) > 0 {
+		commands = append(commands, []string{"checkout", revision})
+	} else {
+		commands = append(commands, []string{"checkout", params["branch"]})
+	}
+
+	if repo.Submodules {
+		commands = append(commands, []string{"submodule", "update", "--init", "--recursive"})
+	}
+	if repo.Lfs {
+		commands = append(commands, []string{"lfs", "pull"})
+	}
+
+	// Never prompt for the credentials
+	env = append(env, "GIT_TERMINAL_PROMPT=0")
+	for _, args := range commands {
+		fmt.Fprintf(out, "[goline] git %s\n", strings.Join(args, " "))
+		if err := runLocalCommand(ctx, workspace, env, out, "git", args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// localEnv Gets the environment of the build, with the JDK and the build parameters
+func localEnv(pl *api.Pipeline, build *localBuild, workspace string) []string {
+	env := append(os.Environ(), "WORKSPACE="+workspace,
+		"JOB_NAME="+fullNameOf(pl),
+		"BUILD_NUMBER="+strconv.FormatInt(build.Number, 10))
+
+	// The JDK of the host is used if the one of the pipeline is not installed at the path of the Jenkins agents
+	if jdkHome, ok := api.JDK_PATH[pl.Jdk]; ok && isDir(jdkHome) {
+		env = append(env, "JAVA_HOME="+jdkHome,
+			"PATH="+filepath.Join(jdkHome, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+	}
+
+	for name, value := range build.Params {
+		env = append(env, name+"="+value)
+	}
+
+	return env
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// runLocalCommand Runs the command in its own process group, the whole group is killed when the context is done,
+// so that the processes started by the command do not outlive the aborted or timed out builds.
+func runLocalCommand(ctx context.Context, dir string, env []string, out io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// localBuildParameters Gets the parameters of the build, the ones not specified take the defaults of the pipeline
+func localBuildParameters(pl *api.Pipeline, pParams *api.PerformParams) (map[string]string, error) {
+	params := map[string]string{
+		"branch":        pl.Repo.Branch,
+		"revision":      defaultRevision(pl.Repo),
+		"performPhases": convertStagesToString(pl.Stages),
+	}
+
+	definitions := []parameterDefinition{}
+	for _, param := range pl.Parameters {
+		definitions = append(definitions, parameterDefinition{
+			Name:    param.Name,
+			Type:    parameterClasses[param.Type],
+			Choices: param.Choices,
+		})
+
+		value := param.Default
+		switch param.Type {
+		case api.BOOLEAN_PARAM:
+			b, _ := strconv.ParseBool(value)
+			value = strconv.FormatBool(b)
+		case api.CHOICE_PARAM:
+			// The first choice is the default, same as Jenkins
+			if len(value) == 0 && len(param.Choices) > 0 {
+				value = param.Choices[0]
+			}
+		}
+		params[param.Name] = value
+	}
+	if pl.PullRequests != nil {
+		definitions = append(definitions, parameterDefinition{Name: pullRequestRefParameter, Type: parameterClasses[api.STRING_PARAM]})
+		params[pullRequestRefParameter] = ""
+	}
+
+	if err := validatePerformParams(definitions, pParams.Params); err != nil {
+		return nil, err
+	}
+	for name, value := range pParams.Params {
+		params[name] = value
+	}
+
+	if len(pParams.Branch) > 0 {
+		params["branch"] = pParams.Branch
+	}
+	if len(pParams.Revision) > 0 {
+		params["revision"] = pParams.Revision
+	}
+	if len(pParams.PerformPhases) > 0 {
+		params["performPhases"] = pParams.PerformPhases
+	}
+	// The branch and revision are passed to git, they can not be taken as options
+	for _, name := range []string{"branch", "revision"} {
+		if strings.HasPrefix(params[name], "-") {
+			return nil, fmt.Errorf("The %s %s should not start with -", name, params[name])
+		}
+	}
+	// The local builds use no dependency cache
+	if pParams.PurgeCache && cacheOf(pl) == nil {
+		return nil, fmt.Errorf("The pipeline %s has no dependency cache to purge", fullNameOf(pl))
+	}
+
+	return params, nil
+}
+
+// triggerDependents Performs the downstream pipelines after the build succeeds, and the pipelines whose upstream
+// is the pipeline when the result is not worse than their thresholds, same as the Jenkins triggers.
+func (backend *LocalBackend) triggerDependents(pl *api.Pipeline, result string) {
+	plName := fullNameOf(pl)
+	if result == string(api.SUCCESS) {
+		for _, downstream := range pl.Downstream {
+			if err := backend.Perform(downstream.Pipeline, downstreamPerformParams(downstream.Params)); err != nil {
+				log.Warnf("Fail to trigger the downstream pipeline %s of %s as %s", downstream.Pipeline, plName, err.Error())
+			}
+		}
+	}
+
+	switch api.BuildResult(result) {
+	case api.SUCCESS, api.UNSTABLE, api.FAILURE:
+	default:
+		return
+	}
+
+	names, err := backend.store.List(localPipelineKind)
+	if err != nil {
+		log.Warnf("Fail to trigger the pipelines after %s as %s", plName, err.Error())
+		return
+	}
+	for _, name := range names {
+		dependent, err := getPipeline(backend.store, name)
+		if err != nil || dependent.Upstream == nil || !containString(dependent.Upstream.Pipelines, plName) {
+			continue
+		}
+		if thresholdOf(api.BuildResult(result)).ordinal > thresholdOf(dependent.Upstream.Threshold).ordinal {
+			continue
+		}
+		if err = backend.Perform(name, &api.PerformParams{}); err != nil {
+			log.Warnf("Fail to trigger the pipeline %s after its upstream %s as %s", name, plName, err.Error())
+		}
+	}
+}
+
+// downstreamPerformParams Gets the perform params from the params passed to the downstream pipeline
+func downstreamPerformParams(params map[string]string) *api.PerformParams {
+	pParams := &api.PerformParams{Params: map[string]string{}}
+	for name, value := range params {
+		switch name {
+		case "branch":
+			pParams.Branch = value
+		case "revision":
+			pParams.Revision = value
+		case "performPhases":
+			pParams.PerformPhases = value
+		default:
+			pParams.Params[name] = value
+		}
+	}
+
+	return pParams
+}
+
+// checkLocalPipeline Checks the pipeline can be run locally, the features only for the Jenkins agents are ignored
+func checkLocalPipeline(pl *api.Pipeline) error {
+	if ok := ValidatePipeline(pl); !ok {
+		return fmt.Errorf("Pipeline config is not correct")
+	}
+
+	unsupported := []string{}
+	if pl.Repo.Type != "" && pl.Repo.Type != api.GIT {
+		unsupported = append(unsupported, "the repos other than Git")
+	}
+	if platformOf(pl).OS == api.WINDOWS {
+		unsupported = append(unsupported, "the Windows agents")
+	}
+	if pl.Matrix != nil {
+		unsupported = append(unsupported, "the matrix builds")
+	}
+	if len(pl.Credentials) > 0 {
+		unsupported = append(unsupported, "the credential bindings")
+	}
+	if len(pl.Repo.SparsePaths) > 0 {
+		unsupported = append(unsupported, "the sparse checkout")
+	}
+	for _, option := range pl.StageOptions {
+		if option != nil && option.Approval != nil {
+			unsupported = append(unsupported, "the approvals")
+			break
+		}
+	}
+	for _, downstream := range pl.Downstream {
+		if downstream.Wait {
+			unsupported = append(unsupported, "waiting for the downstream builds")
+			break
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("The local backend does not support %s", strings.Join(unsupported, ", "))
+	}
+
+	ignored := []string{}
+	if pl.PeriodTrigger != nil && !pl.PeriodTrigger.Skipped {
+		ignored = append(ignored, "period trigger")
+	}
+	if pl.Notifications != nil {
+		ignored = append(ignored, "notifications")
+	}
+	if pl.Artifacts != nil {
+		ignored = append(ignored, "artifacts")
+	}
+	if cacheOf(pl) != nil {
+		ignored = append(ignored, "dependency cache")
+	}
+	if pl.Concurrency != nil {
+		ignored = append(ignored, "concurrency")
+	}
+	if pl.Retention != nil {
+		ignored = append(ignored, "retention")
+	}
+	if len(pl.NodeLabel) > 0 || hasStageAgents(pl) {
+		ignored = append(ignored, "node labels")
+	}
+	if len(ignored) > 0 {
+		log.Warnf("The %s of pipeline %s are ignored by the local backend", strings.Join(ignored, ", "), fullNameOf(pl))
+	}
+
+	return nil
+}
+
+// getLocalPipeline Gets the pipeline known by the local backend, return NotExistError if not exists
+func (backend *LocalBackend) getLocalPipeline(plName string) (*localPipeline, error) {
+	local := &localPipeline{}
+	err := backend.store.Get(localPipelineKind, plName, local)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, &NotExistError{Pipeline: plName}
+		}
+		return nil, err
+	}
+
+	return local, nil
+}
+
+// getBuild Gets the build in the build history
+func (backend *LocalBackend) getBuild(plName string, number int64) (*localBuild, error) {
+	if _, err := backend.getLocalPipeline(plName); err != nil {
+		return nil, err
+	}
+
+	build := &localBuild{}
+	err := backend.store.Get(localBuildKind, localBuildKey(plName, number), build)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, fmt.Errorf("The build %d of pipeline %s does not exist", number, plName)
+		}
+		return nil, err
+	}
+
+	return build, nil
+}
+
+// saveBuild Saves the running build into the build history, the builds aborted by deleting their pipelines
+// are not saved. The caller should hold the mutex.
+func (backend *LocalBackend) saveBuild(plName string, build *localBuild) error {
+	if _, ok := backend.running[plName][build.Number]; !ok {
+		return nil
+	}
+
+	return backend.store.Put(localBuildKind, localBuildKey(plName, build.Number), build)
+}
+
+// createBuildLog Creates the log file of the build
+func (backend *LocalBackend) createBuildLog(plName string, number int64) (*os.File, error) {
+	if err := os.MkdirAll(backend.pipelineLogDir(plName), 0700); err != nil {
+		return nil, fmt.Errorf("Fail to create the log of build %d of pipeline %s as %s", number, plName, err.Error())
+	}
+
+	file, err := os.Create(backend.buildLogPath(plName, number))
+	if err != nil {
+		return nil, fmt.Errorf("Fail to create the log of build %d of pipeline %s as %s", number, plName, err.Error())
+	}
+
+	return file, nil
+}
+
+func (backend *LocalBackend) emit(event *api.BuildEvent) {
+	backend.mutex.Lock()
+	handler := backend.handler
+	backend.mutex.Unlock()
+
+	if handler != nil {
+		handler(event)
+	}
+}
+
+// pipelineLogDir Gets the log dir of the pipeline, the full name is escaped so that the logs of the
+// pipelines in a namespace are not in the dir of the pipeline named as the namespace
+func (backend *LocalBackend) pipelineLogDir(plName string) string {
+	return filepath.Join(backend.logDir, url.QueryEscape(plName))
+}
+
+func (backend *LocalBackend) buildLogPath(plName string, number int64) string {
+	return filepath.Join(backend.pipelineLogDir(plName), strconv.FormatInt(number, 10)+".log")
+}
+
+func localBuildKey(plName string, number int64) string {
+	return fmt.Sprintf("%s/%d", plName, number)
+}
@@ -0,0 +1,214 @@
+package pipeline_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/config"
+	"github.com/supereagle/goline/pipeline"
+	"github.com/supereagle/goline/store"
+)
+
+const localJunitReport = `<testsuite name="svc"><testcase name="pass"/><testcase name="fail"><failure/></testcase><testcase name="skip"><skipped/></testcase></testsuite>`
+
+func TestLocalBackend(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("The local backend needs git")
+	}
+
+	dir, err := ioutil.TempDir("", "goline-local")
+	if err != nil {
+		t.Fatalf("Fail to create the temp dir as %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	// Create the repo to check out
+	repo := filepath.Join(dir, "repo")
+	if err = os.MkdirAll(repo, 0700); err != nil {
+		t.Fatalf("Fail to create the repo dir as %s", err.Error())
+	}
+	if err = ioutil.WriteFile(filepath.Join(repo, "report.xml"), []byte(localJunitReport), 0600); err != nil {
+		t.Fatalf("Fail to write the test report as %s", err.Error())
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"checkout", "-q", "-b", "master"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@localhost", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Fail to run git %s as %s: %s", strings.Join(args, " "), err.Error(), out)
+		}
+	}
+
+	st, err := store.NewStore(filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatalf("Fail to create the store as %s", err.Error())
+	}
+	cfg := &config.Config{Backend: api.LOCAL_BACKEND, DataDir: filepath.Join(dir, "data")}
+	pm, err := pipeline.NewPipelineManager(cfg, st)
+	if err != nil {
+		t.Fatalf("Fail to create the manager as %s", err.Error())
+	}
+
+	pl := &api.Pipeline{
+		Name:        "svc",
+		Jdk:         "jdk1.8",
+		Repo:        &api.Repo{RepoPath: repo, Branch: "master"},
+		ProjectType: api.SHELL,
+		Project: api.ScriptProject{
+			Compile:  &api.ScriptCompile{Command: `test -f report.xml && test "$TOKEN" = s3cret`},
+			UnitTest: &api.ScriptUnitTest{Command: "mkdir -p reports && cp report.xml reports/", TestReportPath: "reports/*.xml"},
+			Build:    &api.ScriptBuild{Command: "echo building $BUILD_NUMBER of $branch"},
+		},
+		Stages: []api.Stage{api.COMPILE, api.UT, api.BUILD},
+		// The default of the password parameter is kept by the local backend, not saved with the config
+		Parameters: []*api.Parameter{&api.Parameter{Name: "TOKEN", Type: api.PASSWORD_PARAM, Default: "s3cret"}},
+	}
+	if err = pm.Create(pl); err != nil {
+		t.Fatalf("Fail to create the pipeline as %s", err.Error())
+	}
+	if err = pm.Perform("svc", &api.PerformParams{}); err != nil {
+		t.Fatalf("Fail to perform the pipeline as %s", err.Error())
+	}
+
+	status := &api.BuildStatus{Running: true}
+	for i := 0; i < 100 && status.Running; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if status, err = pm.GetBuildStatus("svc", 1); err != nil {
+			t.Fatalf("Fail to get the build status as %s", err.Error())
+		}
+	}
+
+	buildLog, err := pm.GetBuildLog("svc", 1)
+	if err != nil {
+		t.Fatalf("Fail to get the build log as %s", err.Error())
+	}
+	if status.Running || status.Result != string(api.UNSTABLE) || len(status.Stages) != 3 {
+		t.Fatalf("The build is expected to be unstable with 3 stages, but got %+v, log: %s", status, buildLog.Log)
+	}
+	if !strings.Contains(buildLog.Log, "building 1 of master") {
+		t.Errorf("The build log does not contain the output of the build stage: %s", buildLog.Log)
+	}
+
+	report, err := pm.GetTestReport("svc", 1)
+	if err != nil {
+		t.Fatalf("Fail to get the test report as %s", err.Error())
+	}
+	expected := api.TestSummary{Total: 3, Failed: 1, Skipped: 1}
+	if report.TestSummary != expected {
+		t.Errorf("The test summary is expected to be %+v, but got %+v", expected, report.TestSummary)
+	}
+
+	if err = pm.Perform("svc", &api.PerformParams{Branch: "--upload-pack=touch leaked"}); err == nil {
+		t.Errorf("The pipeline is performed with the branch taken as a git option")
+	}
+
+	if err = pm.Delete("svc"); err != nil {
+		t.Fatalf("Fail to delete the pipeline as %s", err.Error())
+	}
+	if _, err = pm.GetBuildStatus("svc", 1); err == nil {
+		t.Errorf("The build of the deleted pipeline still exists")
+	}
+
+	// The processes started by the stages are killed with the timed out build
+	leaked := filepath.Join(dir, "leaked")
+	slow := &api.Pipeline{
+		Name:        "slow",
+		Jdk:         "jdk1.8",
+		Repo:        &api.Repo{RepoPath: repo, Branch: "master"},
+		ProjectType: api.SHELL,
+		Project: api.ScriptProject{
+			Compile: &api.ScriptCompile{Command: "(sleep 2; touch " + leaked + ") & sleep 10"},
+			Build:   &api.ScriptBuild{Command: "true"},
+		},
+		Stages:  []api.Stage{api.COMPILE, api.BUILD},
+		Timeout: &api.Timeout{Time: 1, Unit: api.SECONDS},
+	}
+	if err = pm.Create(slow); err != nil {
+		t.Fatalf("Fail to create the pipeline as %s", err.Error())
+	}
+	if err = pm.Perform("slow", &api.PerformParams{}); err != nil {
+		t.Fatalf("Fail to perform the pipeline as %s", err.Error())
+	}
+
+	status = &api.BuildStatus{Running: true}
+	for i := 0; i < 100 && status.Running; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if status, err = pm.GetBuildStatus("slow", 1); err != nil {
+			t.Fatalf("Fail to get the build status as %s", err.Error())
+		}
+	}
+	if status.Running || status.Result == string(api.SUCCESS) {
+		t.Fatalf("The build is expected to time out, but got %+v", status)
+	}
+	time.Sleep(2 * time.Second)
+	if _, err = os.Stat(leaked); err == nil {
+		t.Errorf("The process started by the timed out build is not killed")
+	}
+}
+
+func TestLocalDependencyCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goline-local")
+	if err != nil {
+		t.Fatalf("Fail to create the temp dir as %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	st, err := store.NewStore(filepath.Join(dir, "data"))
+	if err != nil {
+		t.Fatalf("Fail to create the store as %s", err.Error())
+	}
+	cfg := &config.Config{Backend: api.LOCAL_BACKEND, DataDir: filepath.Join(dir, "data")}
+	pm, err := pipeline.NewPipelineManager(cfg, st)
+	if err != nil {
+		t.Fatalf("Fail to create the manager as %s", err.Error())
+	}
+
+	newPipeline := func(name string, downstream string) *api.Pipeline {
+		return &api.Pipeline{
+			Name:        name,
+			Jdk:         "jdk1.8",
+			Repo:        &api.Repo{RepoPath: "https://github.com/example/" + name + ".git", Branch: "master"},
+			ProjectType: api.SHELL,
+			Project: api.ScriptProject{
+				Compile: &api.ScriptCompile{Command: "make compile"},
+				Build:   &api.ScriptBuild{Command: "make"},
+			},
+			Stages:     []api.Stage{api.COMPILE, api.BUILD},
+			Downstream: []*api.Downstream{&api.Downstream{Pipeline: downstream}},
+		}
+	}
+
+	if err = pm.Create(newPipeline("a", "b")); err != nil {
+		t.Fatalf("Fail to create the pipeline a as %s", err.Error())
+	}
+	if err = pm.Create(newPipeline("b", "a")); err == nil {
+		t.Fatalf("The pipeline b is created with a dependency cycle")
+	}
+	b := newPipeline("b", "c")
+	if err = pm.Create(b); err != nil {
+		t.Fatalf("Fail to create the pipeline b as %s", err.Error())
+	}
+	b.Downstream = []*api.Downstream{&api.Downstream{Pipeline: "a"}}
+	if err = pm.Update(b); err == nil {
+		t.Fatalf("The pipeline b is updated with a dependency cycle")
+	}
+
+	graph, err := pm.GetDependencyGraph("a")
+	if err != nil {
+		t.Fatalf("Fail to get the dependency graph as %s", err.Error())
+	}
+	if len(graph.Upstream) != 0 || strings.Join(graph.Downstream, ",") != "b,c" || len(graph.Edges) != 2 {
+		t.Errorf("The dependency graph is expected to be a -> b -> c, but got upstream %v, downstream %v and %d edges",
+			graph.Upstream, graph.Downstream, len(graph.Edges))
+	}
+}
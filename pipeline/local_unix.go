@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package pipeline
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup Runs the command in its own process group, so that the processes started by it can be killed together
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup Kills the process group of the command
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,13 @@
+package pipeline
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup The local builds are not supported on Windows hosts, the command runs in the same process group
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup Kills the process of the command
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -2,52 +2,109 @@ package pipeline
 
 import (
 	"fmt"
-	"net/http"
 	"strings"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
-	"github.com/bndr/gojenkins"
 	"github.com/supereagle/goline/api"
 	"github.com/supereagle/goline/config"
+	"github.com/supereagle/goline/reporter"
+	"github.com/supereagle/goline/store"
 )
 
+const pipelineKind = "pipelines"
+
 type Manager struct {
-	Jenkins      *gojenkins.Jenkins
-	credentialId string
+	backend Backend
+	// jenkins is the backend for the features only supported by Jenkins, nil for the other backends
+	jenkins   *JenkinsBackend
+	store     *store.Store
+	reporters map[string]reporter.StatusReporter
+	// The url of goline to link the builds in the commit statuses
+	externalUrl string
+	// The secret to verify the webhook events of the source code repos
+	scmEventSecret string
+	// branchMutex protects the branches of the fan-out pipelines
+	branchMutex sync.Mutex
 }
 
-func NewPipelineManager(cfg *config.Config) (mgr *Manager, err error) {
-	if len(cfg.JenkinsServer) == 0 {
-		return nil, fmt.Errorf("The Jenkins server url should not be empty")
+// NewPipelineManager Creates the pipeline manager with the backend selected in the config
+func NewPipelineManager(cfg *config.Config, st *store.Store) (mgr *Manager, err error) {
+	if cfg.Retention != nil && !validateRetention(cfg.Retention) {
+		return nil, fmt.Errorf("The default retention is not correct")
 	}
 
-	// Create the Jenkins Instance
-	jenkins, err := gojenkins.CreateJenkins(cfg.JenkinsServer, cfg.JenkinsUser, cfg.JenkinsPassword).Init()
+	var backend Backend
+	switch cfg.Backend {
+	case api.JENKINS_BACKEND:
+		backend, err = NewJenkinsBackend(cfg, st)
+	case api.LOCAL_BACKEND:
+		backend, err = NewLocalBackend(cfg, st)
+	default:
+		err = fmt.Errorf("The backend %s is not supported", cfg.Backend)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("Fail to create Jenkins Instance as %s", err.Error())
+		return nil, err
 	}
 
-	mgr = &Manager{
-		Jenkins:      jenkins,
-		credentialId: cfg.JenkinsCredentialId,
+	return NewManager(backend, cfg, st)
+}
+
+// NewManager Creates the pipeline manager with the backend, which can be any implementation of Backend
+func NewManager(backend Backend, cfg *config.Config, st *store.Store) (*Manager, error) {
+	reporters, err := newStatusReporters(cfg.StatusReporters)
+	if err != nil {
+		return nil, err
 	}
 
-	return
+	mgr := &Manager{
+		backend:        backend,
+		store:          st,
+		reporters:      reporters,
+		externalUrl:    strings.TrimSuffix(cfg.ExternalUrl, "/"),
+		scmEventSecret: cfg.ScmEventSecret,
+	}
+	if jenkins, ok := backend.(*JenkinsBackend); ok {
+		mgr.jenkins = jenkins
+	}
 
+	return mgr, nil
 }
 
 // Create Creates the pipeline according to the pipeline config
 func (mgr *Manager) Create(pl *api.Pipeline) error {
-	// Generate the pipeline job config
-	jobCfg, err := generatePipelineJobConfig(pl, mgr.credentialId)
+	// The branch fan-out pipeline has no job, but the jobs of its branch pipelines
+	if pl.Branches != nil {
+		err := mgr.createFanout(pl)
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+		return err
+	}
+
+	// Check the status reporter of the pull request mode
+	err := mgr.checkReporter(pl)
 	if err != nil {
-		err = fmt.Errorf("Fail to generate pipeline config as %s", err.Error())
 		log.Errorln(err.Error())
 		return err
 	}
 
-	// Create the pipeline job
-	_, err = mgr.Jenkins.CreateJob(jobCfg, pl.Name)
+	// Check the dependency cycle with the existing pipelines, whatever the backend is
+	err = checkDependencyCycle(mgr.store, pl)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	// Create the pipeline in the backend
+	err = mgr.backend.Create(pl)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	// Save the pipeline config
+	err = mgr.store.Put(pipelineKind, fullNameOf(pl), withoutPasswordDefaults(pl))
 	if err != nil {
 		log.Errorln(err.Error())
 		return err
@@ -57,23 +114,37 @@ func (mgr *Manager) Create(pl *api.Pipeline) error {
 
 // Update Updates the pipeline according to the pipeline config
 func (mgr *Manager) Update(pl *api.Pipeline) error {
-	// Check the existence of the pipeline job
-	job, err := mgr.getJob(pl.Name)
+	if pl.Branches != nil {
+		err := mgr.updateFanout(pl)
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+		return err
+	}
+
+	// Check the status reporter of the pull request mode
+	err := mgr.checkReporter(pl)
 	if err != nil {
 		log.Errorln(err.Error())
 		return err
 	}
 
-	// Generate the pipeline job config
-	jobCfg, err := generatePipelineJobConfig(pl, mgr.credentialId)
+	// Check the dependency cycle with the existing pipelines, whatever the backend is
+	err = checkDependencyCycle(mgr.store, pl)
 	if err != nil {
-		err = fmt.Errorf("Fail to generate pipeline config as %s", err.Error())
 		log.Errorln(err.Error())
 		return err
 	}
 
-	// Update the pipeline job
-	err = job.UpdateConfig(jobCfg)
+	// Update the pipeline in the backend
+	err = mgr.backend.Update(pl)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	// Save the pipeline config
+	err = mgr.store.Put(pipelineKind, fullNameOf(pl), withoutPasswordDefaults(pl))
 	if err != nil {
 		log.Errorln(err.Error())
 		return err
@@ -83,16 +154,24 @@ func (mgr *Manager) Update(pl *api.Pipeline) error {
 
 // Delete Deletes the pipeline according to the pipeline name
 func (mgr *Manager) Delete(plName string) error {
-	// Check the existence of the pipeline job
-	job, err := mgr.getJob(plName)
+	if _, err := mgr.getFanout(plName); err == nil {
+		err = mgr.deleteFanout(plName)
+		if err != nil {
+			log.Errorln(err.Error())
+		}
+		return err
+	}
+
+	// Delete the pipeline in the backend
+	err := mgr.backend.Delete(plName)
 	if err != nil {
 		log.Errorln(err.Error())
 		return err
 	}
 
-	// Delete the pipeline job
-	_, err = job.Delete()
-	if err != nil {
+	// Delete the pipeline config, which does not exist for the pipelines created before goline stores them
+	err = mgr.store.Delete(pipelineKind, plName)
+	if err != nil && err != store.ErrNotFound {
 		log.Errorln(err.Error())
 		return err
 	}
@@ -101,38 +180,61 @@ func (mgr *Manager) Delete(plName string) error {
 
 // Perform Performs the pipeline with the perform parameters
 func (mgr *Manager) Perform(plName string, pParams *api.PerformParams) error {
-	// Check the existence of the pipeline job
-	job, err := mgr.getJob(plName)
+	// Check the revision against the repo, as the one out of the clone fails the checkout
+	if len(pParams.Revision) > 0 {
+		pl, err := getPipeline(mgr.store, plName)
+		if err == nil {
+			err = validateRevision(pl.Repo, pParams.Revision, len(pParams.Params[pullRequestRefParameter]) > 0)
+		}
+		if err != nil {
+			log.Errorln(err.Error())
+			return err
+		}
+	}
+
+	err := mgr.backend.Perform(plName, pParams)
 	if err != nil {
 		log.Errorln(err.Error())
 		return err
 	}
+	return nil
+}
 
-	params := make(map[string]string)
-	params["branch"] = pParams.Branch
-	params["performPhases"] = pParams.PerformPhases
+// GetBuildStatus Gets the status of the build and its stages, reported per cell for the matrix builds
+func (mgr *Manager) GetBuildStatus(plName string, number int64) (*api.BuildStatus, error) {
+	status, err := mgr.backend.GetBuildStatus(plName, number)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+	return status, nil
+}
 
-	// Invoke the pipeline job with params
-	//_, err = job.Invoke(nil, false, params, "", "")
-	_, err = job.InvokeSimple(params)
+// GetBuildLog Gets the console log of the build
+func (mgr *Manager) GetBuildLog(plName string, number int64) (*api.BuildLog, error) {
+	content, err := mgr.backend.GetBuildLog(plName, number)
 	if err != nil {
 		log.Errorln(err.Error())
-		return err
+		return nil, err
 	}
-	return nil
+
+	return &api.BuildLog{
+		Pipeline: plName,
+		Build:    number,
+		Log:      content,
+	}, nil
 }
 
-//getJob Gets the specified pipeline job, return error if not exists
-func (mgr *Manager) getJob(plName string) (*gojenkins.Job, error) {
-	job, err := mgr.Jenkins.GetJob(plName)
+// getPipeline Gets the pipeline config saved by goline, return error if not exists
+func getPipeline(st *store.Store, plName string) (*api.Pipeline, error) {
+	pl := &api.Pipeline{}
+	err := st.Get(pipelineKind, plName, pl)
 	if err != nil {
-		if strings.Contains(err.Error(), string(http.StatusNotFound)) {
-			err = fmt.Errorf("The pipeline %s does not exist", plName)
-			return nil, err
+		if err == store.ErrNotFound {
+			return nil, fmt.Errorf("The config of pipeline %s does not exist", plName)
 		}
-		err = fmt.Errorf("Fail to get the pipeline %s as %s", plName, err.Error())
-		return nil, err
+		return nil, fmt.Errorf("Fail to get the config of pipeline %s as %s", plName, err.Error())
 	}
 
-	return job, nil
+	return pl, nil
 }
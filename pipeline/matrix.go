@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+const (
+	MATRIX_CELL_ENV = "MATRIX_CELL"
+
+	jdkAxis       = "jdk"
+	nodeLabelAxis = "node_label"
+)
+
+// matrixCells Gets the cells of the matrix except the excluded ones. The JDK and node label
+// of the pipeline are used if they are not the axes of the matrix.
+func matrixCells(pipeline *api.Pipeline) []*api.MatrixCell {
+	matrix := pipeline.Matrix
+
+	jdks := matrix.Jdks
+	if len(jdks) == 0 {
+		jdks = []string{pipeline.Jdk}
+	}
+	labels := matrix.NodeLabels
+	if len(labels) == 0 {
+		labels = []string{pipeline.NodeLabel}
+	}
+	envNames := []string{}
+	for name := range matrix.Env {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+
+	// Combine the axes one by one, the combinations are in the order of the axis values
+	cells := []*api.MatrixCell{}
+	for _, jdk := range jdks {
+		for _, label := range labels {
+			cells = append(cells, &api.MatrixCell{Jdk: jdk, NodeLabel: label, Env: map[string]string{}})
+		}
+	}
+	for _, name := range envNames {
+		combined := []*api.MatrixCell{}
+		for _, cell := range cells {
+			for _, value := range matrix.Env[name] {
+				env := map[string]string{name: value}
+				for k, v := range cell.Env {
+					env[k] = v
+				}
+				combined = append(combined, &api.MatrixCell{Jdk: cell.Jdk, NodeLabel: cell.NodeLabel, Env: env})
+			}
+		}
+		cells = combined
+	}
+
+	result := []*api.MatrixCell{}
+	for _, cell := range cells {
+		if isExcluded(cell, matrix.Excludes) {
+			continue
+		}
+
+		// Name the cell with the values of the axes
+		values := []string{}
+		if len(matrix.Jdks) > 0 {
+			values = append(values, cell.Jdk)
+		}
+		if len(matrix.NodeLabels) > 0 {
+			values = append(values, cell.NodeLabel)
+		}
+		for _, name := range envNames {
+			values = append(values, fmt.Sprintf("%s=%s", name, cell.Env[name]))
+		}
+		cell.Name = strings.Join(values, ", ")
+
+		result = append(result, cell)
+	}
+
+	return result
+}
+
+func isExcluded(cell *api.MatrixCell, excludes []map[string]string) bool {
+	for _, exclude := range excludes {
+		matched := true
+		for key, value := range exclude {
+			switch key {
+			case jdkAxis:
+				matched = matched && cell.Jdk == value
+			case nodeLabelAxis:
+				matched = matched && cell.NodeLabel == value
+			default:
+				matched = matched && cell.Env[key] == value
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// generateMatrixTmpl Generates the parallel cells, each cell runs the node template on its own node
+func generateMatrixTmpl(pipeline *api.Pipeline, platform *Platform, envTmpl string) string {
+	cellsTmpl := ""
+	for _, cell := range matrixCells(pipeline) {
+		cellEnvTmpl := fmt.Sprintf(`, "%s=%s"`, MATRIX_CELL_ENV, escapeGroovyGString(cell.Name))
+		names := []string{}
+		for name := range cell.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cellEnvTmpl += fmt.Sprintf(`, "%s=%s"`, name, escapeGroovyGString(cell.Env[name]))
+		}
+
+		nodeTmpl := generateThrottleTmpl(generateNodeTmpl(cell.NodeLabel, cell.Jdk, platform, envTmpl+cellEnvTmpl), pipeline.Concurrency)
+		cellsTmpl += strings.NewReplacer("${matrix.cell.name}", escapeGroovyString(cell.Name),
+			"${pipeline.script.node}", nodeTmpl).Replace(MATRIX_CELL_TEMPLATE)
+	}
+
+	return strings.Replace(MATRIX_TEMPLATE, "${matrix.cells}", cellsTmpl, 1)
+}
+
+// validateMatrix Validates the axes and the excludes of the matrix
+func validateMatrix(pipeline *api.Pipeline, platform *Platform) bool {
+	matrix := pipeline.Matrix
+	if len(matrix.Jdks) == 0 && len(matrix.NodeLabels) == 0 && len(matrix.Env) == 0 {
+		log.Errorln("The matrix has no axis")
+		return false
+	}
+
+	for _, jdk := range matrix.Jdks {
+		if _, ok := platform.JdkPaths[jdk]; !ok {
+			log.Errorf("The jdk version %s of the matrix is not supported on %s", jdk, platform.OS)
+			return false
+		}
+	}
+
+	for name, values := range matrix.Env {
+		if !parameterNamePattern.MatchString(name) || name == MATRIX_CELL_ENV {
+			log.Errorf("The env name %s of the matrix is not correct", name)
+			return false
+		}
+		if len(values) == 0 {
+			log.Errorf("The env %s of the matrix has no value", name)
+			return false
+		}
+	}
+
+	for _, exclude := range matrix.Excludes {
+		if len(exclude) == 0 {
+			log.Errorln("The matrix exclude is empty")
+			return false
+		}
+		for key := range exclude {
+			if _, ok := matrix.Env[key]; !ok && key != jdkAxis && key != nodeLabelAxis {
+				log.Errorf("The matrix exclude key %s is not an axis", key)
+				return false
+			}
+		}
+	}
+
+	if len(matrixCells(pipeline)) == 0 {
+		log.Errorln("All the cells of the matrix are excluded")
+		return false
+	}
+
+	// The input steps of the cells would have the same id
+	for stage, option := range pipeline.StageOptions {
+		if option != nil && option.Approval != nil {
+			log.Errorf("The approval of the %s stage is not supported by the matrix builds", stage)
+			return false
+		}
+	}
+
+	return true
+}
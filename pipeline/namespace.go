@@ -0,0 +1,217 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bndr/gojenkins"
+	"github.com/supereagle/goline/api"
+)
+
+var namespacePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// FullName Gets the full name of the pipeline, which is "<namespace>/<name>" for the namespaced pipelines.
+// The pipelines are identified by their full names, such as in the upstream and downstream pipelines.
+func FullName(namespace, name string) string {
+	if len(namespace) == 0 {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+func fullNameOf(pipeline *api.Pipeline) string {
+	return FullName(pipeline.Namespace, pipeline.Name)
+}
+
+// jobId Gets the id of the Jenkins job of the pipeline, the namespaces are the Jenkins folders
+func jobId(fullName string) string {
+	return strings.Replace(fullName, "/", "/job/", -1)
+}
+
+// fullNameOfUrl Gets the full name of the pipeline from the url of its Jenkins job,
+// such as http://jenkins/job/team/job/service/ for team/service.
+func fullNameOfUrl(jobUrl string) (string, error) {
+	u, err := url.Parse(jobUrl)
+	if err != nil {
+		return "", err
+	}
+
+	names := []string{}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i] == "job" {
+			i++
+			name, err := url.PathUnescape(segments[i])
+			if err != nil {
+				return "", err
+			}
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("The url %s is not a job url", jobUrl)
+	}
+
+	return strings.Join(names, "/"), nil
+}
+
+// upstreamsOf Gets the full names of the upstream pipelines recorded by Jenkins
+func upstreamsOf(job *gojenkins.Job) ([]string, error) {
+	urls := []string{}
+	for _, upstream := range job.GetUpstreamJobsMetadata() {
+		urls = append(urls, upstream.Url)
+	}
+	return fullNamesOfUrls(urls)
+}
+
+// downstreamsOf Gets the full names of the downstream pipelines recorded by Jenkins
+func downstreamsOf(job *gojenkins.Job) ([]string, error) {
+	urls := []string{}
+	for _, downstream := range job.GetDownstreamJobsMetadata() {
+		urls = append(urls, downstream.Url)
+	}
+	return fullNamesOfUrls(urls)
+}
+
+func fullNamesOfUrls(urls []string) ([]string, error) {
+	names := []string{}
+	for _, jobUrl := range urls {
+		name, err := fullNameOfUrl(jobUrl)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// jobRef Gets the name to reference the pipeline in the Jenkins job of the given pipeline.
+// The names are absolute in the namespaced pipelines, as they are relative to the folders.
+func jobRef(pipeline *api.Pipeline, fullName string) string {
+	if len(pipeline.Namespace) == 0 {
+		return fullName
+	}
+	return "/" + fullName
+}
+
+// createJob Creates the Jenkins job of the pipeline, in the folder of its namespace if any
+func (backend *JenkinsBackend) createJob(pl *api.Pipeline, jobCfg string) error {
+	endpoint := "/createItem"
+	if len(pl.Namespace) > 0 {
+		if err := backend.ensureFolder(pl.Namespace); err != nil {
+			return err
+		}
+		endpoint = "/job/" + pl.Namespace + "/createItem"
+	}
+
+	resp, err := backend.Jenkins.Requester.PostXML(endpoint, jobCfg, nil, map[string]string{"name": pl.Name})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Fail to create the job of pipeline %s as %s", fullNameOf(pl), resp.Status)
+	}
+
+	return nil
+}
+
+// ensureFolder Creates the Jenkins folder of the namespace if not exists
+func (backend *JenkinsBackend) ensureFolder(namespace string) error {
+	_, err := backend.Jenkins.GetJob(namespace)
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("Fail to get the folder of namespace %s as %s", namespace, err.Error())
+	}
+
+	folderCfg := strings.Replace(FOLDER_TEMPLATE, "${namespace.name}", escapeXml(namespace), 1)
+	resp, err := backend.Jenkins.Requester.PostXML("/createItem", folderCfg, nil, map[string]string{"name": namespace})
+	if err != nil {
+		return fmt.Errorf("Fail to create the folder of namespace %s as %s", namespace, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Fail to create the folder of namespace %s as %s", namespace, resp.Status)
+	}
+
+	log.Infof("Create the folder of namespace %s", namespace)
+	return nil
+}
+
+// ListPipelines Lists the configs of the pipelines in the namespace, the empty namespace is the root.
+// The branch fan-out pipelines are listed together with their branch pipelines.
+func (mgr *Manager) ListPipelines(namespace string) ([]*api.Pipeline, error) {
+	names, err := mgr.store.List(pipelineKind)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	pls := []*api.Pipeline{}
+	for _, name := range names {
+		pl, err := getPipeline(mgr.store, name)
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil, err
+		}
+		if pl.Namespace == namespace {
+			pls = append(pls, pl)
+		}
+	}
+
+	fanouts, err := mgr.listFanouts()
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+	for _, pl := range fanouts {
+		if pl.Namespace == namespace {
+			pls = append(pls, pl)
+		}
+	}
+
+	return pls, nil
+}
+
+// buildInfo is the brief info of the build
+type buildInfo struct {
+	Building bool   `json:"building"`
+	Result   string `json:"result"`
+}
+
+// getBuildInfo Gets the brief info of the build by the url of the job, which works for the jobs in folders
+func (backend *JenkinsBackend) getBuildInfo(job *gojenkins.Job, number int64) (*buildInfo, error) {
+	info := &buildInfo{}
+	querystring := map[string]string{
+		"tree": "building,result",
+	}
+	resp, err := backend.Jenkins.Requester.GetJSON(fmt.Sprintf("%s/%d", job.Base, number), info, querystring)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fail to get the build %d as %s", number, resp.Status)
+	}
+
+	return info, nil
+}
+
+// validateNamespace Validates the namespace and the name of the pipeline
+func validateNamespace(pipeline *api.Pipeline) bool {
+	if strings.Contains(pipeline.Name, "/") {
+		log.Errorf("The pipeline name %s should not contain slashes, use the namespace instead", pipeline.Name)
+		return false
+	}
+
+	if len(pipeline.Namespace) > 0 && !namespacePattern.MatchString(pipeline.Namespace) {
+		log.Errorf("The namespace %s should only contain letters, digits, underscores, dots and hyphens", pipeline.Namespace)
+		return false
+	}
+
+	return true
+}
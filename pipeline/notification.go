@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+var (
+	emailPattern      = regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*$`)
+	webhookUrlPattern = regexp.MustCompile(`^https?://`)
+)
+
+// generateSlackPropertyTmpl Generates the Slack job property, which only records the settings
+// as the notifications of pipeline are sent by the script.
+func generateSlackPropertyTmpl(jobTmpl string, notifications *api.Notifications) string {
+	slack := &api.SlackNotification{}
+	events := []api.NotifyEvent{}
+	includeTestSummary, includeCulprits := false, false
+	if notifications != nil && notifications.Slack != nil {
+		slack = notifications.Slack
+		events = notifications.Events
+		includeTestSummary, includeCulprits = notifications.IncludeTestSummary, notifications.IncludeCulprits
+	}
+
+	always := containEvent(events, api.NOTIFY_ALWAYS)
+	return strings.NewReplacer("${slack.team.domain}", escapeXml(slack.TeamDomain),
+		"${slack.room}", escapeXml(slack.Room),
+		"${slack.notify.start}", strconv.FormatBool(containEvent(events, api.NOTIFY_START)),
+		"${slack.notify.success}", strconv.FormatBool(always),
+		"${slack.notify.unstable}", strconv.FormatBool(always || containEvent(events, api.NOTIFY_UNSTABLE)),
+		"${slack.notify.failure}", strconv.FormatBool(always || containEvent(events, api.NOTIFY_FAILURE)),
+		"${slack.notify.fixed}", strconv.FormatBool(always || containEvent(events, api.NOTIFY_FIXED)),
+		"${slack.include.test.summary}", strconv.FormatBool(includeTestSummary),
+		"${slack.show.commit.list}", strconv.FormatBool(includeCulprits)).Replace(jobTmpl)
+}
+
+// generateNotifyTmpl Generates the script to notify when the build starts and completes,
+// and the functions to send the notifications.
+func generateNotifyTmpl(notifications *api.Notifications) (startTmpl, resultTmpl, functionTmpl string) {
+	if notifications == nil || len(notifications.Events) == 0 {
+		return "// No notifications", "// No notifications", ""
+	}
+
+	startTmpl = "// No start notifications"
+	if containEvent(notifications.Events, api.NOTIFY_START) {
+		startTmpl = `sendNotification("start", "STARTED")`
+	}
+	resultTmpl = "notifyResult()"
+
+	events := []string{}
+	for _, event := range notifications.Events {
+		events = append(events, fmt.Sprintf("'%s'", event))
+	}
+
+	channels := []string{}
+	if slack := notifications.Slack; slack != nil {
+		channels = append(channels, strings.NewReplacer("${slack.team.domain}", escapeGroovyString(slack.TeamDomain),
+			"${slack.room}", escapeGroovyString(slack.Room),
+			"${slack.token.credentialId}", escapeGroovyString(slack.TokenCredentialId)).Replace(NOTIFY_SLACK))
+	}
+	if email := notifications.Email; email != nil {
+		providers := ""
+		if email.NotifyCulprits {
+			providers = "[$class: 'CulpritsRecipientProvider']"
+		}
+		channels = append(channels, strings.NewReplacer("${email.recipients}", escapeGroovyString(strings.Join(email.Recipients, ",")),
+			"${email.recipient.providers}", providers).Replace(NOTIFY_EMAIL))
+	}
+	if webhook := notifications.Webhook; webhook != nil {
+		channels = append(channels, strings.Replace(NOTIFY_WEBHOOK, "${webhook.url}", escapeGroovyString(webhook.Url), 1))
+	}
+
+	summaryTmpl, culpritsTmpl := "", ""
+	if notifications.IncludeTestSummary {
+		summaryTmpl = NOTIFY_TEST_SUMMARY
+	}
+	if notifications.IncludeCulprits {
+		culpritsTmpl = NOTIFY_CULPRITS
+	}
+
+	functionTmpl = strings.NewReplacer("${notify.events}", strings.Join(events, ", "),
+		"${notify.summary}", summaryTmpl,
+		"${notify.culprits}", culpritsTmpl,
+		"${notify.channels}", strings.Join(channels, "\n        ")).Replace(NOTIFY_FUNCTION)
+	return
+}
+
+// validateNotifications Validates the channels and events of the notifications.
+func validateNotifications(notifications *api.Notifications) bool {
+	if notifications.Slack == nil && notifications.Email == nil && notifications.Webhook == nil {
+		log.Errorln("No channel is specified for the notifications")
+		return false
+	}
+
+	if slack := notifications.Slack; slack != nil {
+		if len(slack.Room) == 0 || len(slack.TokenCredentialId) == 0 {
+			log.Errorln("The Slack room or token credential is empty")
+			return false
+		}
+	}
+
+	if email := notifications.Email; email != nil {
+		if len(email.Recipients) == 0 && !email.NotifyCulprits {
+			log.Errorln("The email recipients are empty")
+			return false
+		}
+		for _, recipient := range email.Recipients {
+			if !emailPattern.MatchString(recipient) {
+				log.Errorf("The email recipient %s is not correct", recipient)
+				return false
+			}
+		}
+	}
+
+	if webhook := notifications.Webhook; webhook != nil {
+		if !webhookUrlPattern.MatchString(webhook.Url) {
+			log.Errorf("The webhook url %s is not correct", webhook.Url)
+			return false
+		}
+	}
+
+	// Nothing is sent without the events
+	if len(notifications.Events) == 0 {
+		log.Errorln("No event is specified for the notifications")
+		return false
+	}
+	for _, event := range notifications.Events {
+		switch event {
+		case api.NOTIFY_START, api.NOTIFY_FAILURE, api.NOTIFY_FIXED, api.NOTIFY_UNSTABLE, api.NOTIFY_ALWAYS:
+		default:
+			log.Errorf("The notify event %s is not supported", event)
+			return false
+		}
+	}
+
+	return true
+}
+
+func containEvent(events []api.NotifyEvent, desiredEvent api.NotifyEvent) bool {
+	for _, event := range events {
+		if event == desiredEvent {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,372 @@
+package pipeline
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+var (
+	// The classes of Jenkins parameter definitions for parameter types
+	parameterClasses = map[api.ParameterType]string{
+		api.STRING_PARAM:   "StringParameterDefinition",
+		api.CHOICE_PARAM:   "ChoiceParameterDefinition",
+		api.BOOLEAN_PARAM:  "BooleanParameterDefinition",
+		api.PASSWORD_PARAM: "PasswordParameterDefinition",
+		api.TEXT_PARAM:     "TextParameterDefinition",
+	}
+
+	// The parameters defined by goline for every pipeline
+	reservedParameters = []string{"branch", "revision", "performPhases", purgeCacheParameter, pullRequestRefParameter}
+
+	parameterNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// parameterDefinition is the parameter definition of Jenkins job
+type parameterDefinition struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Choices []string `json:"choices"`
+}
+
+func generateParametersTmpl(parameters []*api.Parameter) string {
+	paramsTmpl := ""
+	for _, param := range parameters {
+		if param.Type == api.CHOICE_PARAM {
+			// Jenkins takes the first choice as the default
+			choices := []string{}
+			if len(param.Default) > 0 {
+				choices = append(choices, param.Default)
+			}
+			for _, choice := range param.Choices {
+				if choice != param.Default {
+					choices = append(choices, choice)
+				}
+			}
+
+			choicesTmpl := ""
+			for _, choice := range choices {
+				choicesTmpl += strings.Replace(CHOICE_TEMPLATE, "${parameter.choice}", escapeXml(choice), 1)
+			}
+
+			paramsTmpl += strings.NewReplacer("${parameter.name}", param.Name,
+				"${parameter.description}", escapeXml(param.Description),
+				"${parameter.choices}", choicesTmpl).Replace(CHOICE_PARAMETER_TEMPLATE)
+			continue
+		}
+
+		defaultValue := param.Default
+		if param.Type == api.BOOLEAN_PARAM {
+			value, _ := strconv.ParseBool(param.Default)
+			defaultValue = strconv.FormatBool(value)
+		}
+
+		paramsTmpl += strings.NewReplacer("${parameter.class}", parameterClasses[param.Type],
+			"${parameter.name}", param.Name,
+			"${parameter.description}", escapeXml(param.Description),
+			"${parameter.default}", escapeXml(defaultValue)).Replace(PARAMETER_TEMPLATE)
+	}
+
+	return paramsTmpl
+}
+
+// generateParametersEnvTmpl Generates the environment variables of the parameters in stages.
+// The password parameters are not interpolated into the script, Jenkins exports them as they are.
+func generateParametersEnvTmpl(parameters []*api.Parameter) string {
+	envTmpl := ""
+	for _, param := range parameters {
+		if param.Type == api.PASSWORD_PARAM {
+			continue
+		}
+		envTmpl += fmt.Sprintf(`, "%s=${%s}"`, param.Name, param.Name)
+	}
+
+	return envTmpl
+}
+
+// generateMaskPasswordsTmpl Wraps the nodes to mask the values of the password parameters in the build log
+func generateMaskPasswordsTmpl(nodeTmpl string, parameters []*api.Parameter) string {
+	pairs := []string{}
+	for _, param := range parameters {
+		if param.Type == api.PASSWORD_PARAM {
+			pairs = append(pairs, strings.Replace(PASSWORD_PAIR_TEMPLATE, "${parameter.name}", param.Name, -1))
+		}
+	}
+	if len(pairs) == 0 {
+		return nodeTmpl
+	}
+
+	return strings.NewReplacer("${parameter.passwords}", strings.Join(pairs, ", "),
+		"${pipeline.script.node}", nodeTmpl).Replace(MASK_PASSWORDS_TEMPLATE)
+}
+
+// withoutPasswordDefaults Gets the copy of the pipeline config to save, the defaults of the password parameters
+// are removed from it and its overrides, as they are only kept in the backend.
+func withoutPasswordDefaults(pl *api.Pipeline) *api.Pipeline {
+	saved := *pl
+	if pl.Parameters != nil {
+		saved.Parameters = []*api.Parameter{}
+		for _, param := range pl.Parameters {
+			if param.Type == api.PASSWORD_PARAM {
+				stripped := *param
+				stripped.Default = ""
+				param = &stripped
+			}
+			saved.Parameters = append(saved.Parameters, param)
+		}
+	}
+	if pl.Overrides != nil {
+		saved.Overrides = withoutRawPasswordDefaults(pl.Overrides)
+	}
+
+	return &saved
+}
+
+// withoutRawPasswordDefaults Gets the copy of the pipeline definition without the defaults of the password parameters
+func withoutRawPasswordDefaults(definition map[string]interface{}) map[string]interface{} {
+	params, ok := definition["parameters"].([]interface{})
+	if !ok {
+		return definition
+	}
+
+	stripped := map[string]interface{}{}
+	for key, value := range definition {
+		stripped[key] = value
+	}
+	strippedParams := []interface{}{}
+	for _, param := range params {
+		if fields, ok := param.(map[string]interface{}); ok && fields["type"] == string(api.PASSWORD_PARAM) {
+			strippedFields := map[string]interface{}{}
+			for key, value := range fields {
+				if key != "default" {
+					strippedFields[key] = value
+				}
+			}
+			param = strippedFields
+		}
+		strippedParams = append(strippedParams, param)
+	}
+	stripped["parameters"] = strippedParams
+
+	return stripped
+}
+
+// withPasswordDefaults Gets the copy of the pipeline config loaded from the store, with the defaults of the
+// password parameters kept by the backend for the pipeline plName. The defaults which are specified are not changed.
+func (mgr *Manager) withPasswordDefaults(pl *api.Pipeline, plName string) (*api.Pipeline, error) {
+	getter, ok := mgr.backend.(PasswordDefaultGetter)
+	if !ok || !hasPasswordParameters(pl) {
+		return pl, nil
+	}
+
+	defaults, err := getter.GetPasswordDefaults(plName)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to get the defaults of the password parameters of pipeline %s as %s", plName, err.Error())
+	}
+
+	return restorePasswordDefaults(pl, defaults), nil
+}
+
+// restorePasswordDefaults Gets the copy of the pipeline config with the given defaults of the password parameters,
+// the defaults which are specified are not changed.
+func restorePasswordDefaults(pl *api.Pipeline, defaults map[string]string) *api.Pipeline {
+	restored := *pl
+	restored.Parameters = []*api.Parameter{}
+	for _, param := range pl.Parameters {
+		if value, ok := defaults[param.Name]; ok && param.Type == api.PASSWORD_PARAM && len(param.Default) == 0 {
+			copied := *param
+			copied.Default = value
+			param = &copied
+		}
+		restored.Parameters = append(restored.Parameters, param)
+	}
+
+	return &restored
+}
+
+// passwordDefaultsOf Gets the defaults of the password parameters of the pipeline by their names
+func passwordDefaultsOf(pl *api.Pipeline) map[string]string {
+	defaults := map[string]string{}
+	for _, param := range pl.Parameters {
+		if param.Type == api.PASSWORD_PARAM && len(param.Default) > 0 {
+			defaults[param.Name] = param.Default
+		}
+	}
+
+	return defaults
+}
+
+// withFanoutPasswordDefaults Gets the copy of the fan-out pipeline config loaded from the store, with the defaults
+// of the password parameters kept for its branch pipelines, as the fan-out pipeline is not in the backend.
+// The branch pipelines whose parameters are overridden are skipped.
+func (mgr *Manager) withFanoutPasswordDefaults(pl *api.Pipeline) (*api.Pipeline, error) {
+	if !hasPasswordParameters(pl) {
+		return pl, nil
+	}
+
+	branches, err := mgr.fanoutBranches(fullNameOf(pl))
+	if err != nil {
+		return nil, err
+	}
+	for branch, branchPl := range branches {
+		overridden := false
+		for _, override := range pl.Branches.Overrides {
+			if matched, _ := path.Match(override.Pattern, branch); matched && override.Pipeline["parameters"] != nil {
+				overridden = true
+			}
+		}
+		if !overridden {
+			return mgr.withPasswordDefaults(pl, branchPl.Pipeline)
+		}
+	}
+
+	return nil, fmt.Errorf("The defaults of the password parameters of pipeline %s are not kept by any of its branch pipelines, update it with the defaults", fullNameOf(pl))
+}
+
+func hasPasswordParameters(pl *api.Pipeline) bool {
+	for _, param := range pl.Parameters {
+		if param.Type == api.PASSWORD_PARAM {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parsePasswordDefaults Parses the defaults of the password parameters from the Jenkins job config
+func parsePasswordDefaults(jobCfg string) (map[string]string, error) {
+	// Jenkins saves the configs as XML 1.1, which is not supported by the decoder
+	if strings.HasPrefix(jobCfg, "<?xml") {
+		if end := strings.Index(jobCfg, "?>"); end >= 0 {
+			jobCfg = jobCfg[end+len("?>"):]
+		}
+	}
+
+	defaults := map[string]string{}
+	decoder := xml.NewDecoder(strings.NewReader(jobCfg))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return defaults, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "hudson.model."+parameterClasses[api.PASSWORD_PARAM] {
+			continue
+		}
+		param := struct {
+			Name    string `xml:"name"`
+			Default string `xml:"defaultValue"`
+		}{}
+		if err = decoder.DecodeElement(&param, &start); err != nil {
+			return nil, err
+		}
+		defaults[param.Name] = param.Default
+	}
+}
+
+// validateParameters Validates the names, types and defaults of the parameters.
+func validateParameters(parameters []*api.Parameter) bool {
+	names := map[string]bool{}
+	for _, name := range reservedParameters {
+		names[name] = true
+	}
+
+	for _, param := range parameters {
+		if param == nil {
+			log.Errorln("The parameter is empty")
+			return false
+		}
+		if !parameterNamePattern.MatchString(param.Name) {
+			log.Errorf("The parameter name %s should only contain letters, digits and underscores", param.Name)
+			return false
+		}
+		if names[param.Name] {
+			log.Errorf("The parameter %s is duplicated or reserved", param.Name)
+			return false
+		}
+		names[param.Name] = true
+
+		if _, ok := parameterClasses[param.Type]; !ok {
+			log.Errorf("The type %s of parameter %s is not supported", param.Type, param.Name)
+			return false
+		}
+
+		switch param.Type {
+		case api.CHOICE_PARAM:
+			if len(param.Choices) == 0 {
+				log.Errorf("The choice parameter %s has no choices", param.Name)
+				return false
+			}
+			if len(param.Default) > 0 && !containString(param.Choices, param.Default) {
+				log.Errorf("The default %s of parameter %s is not one of the choices", param.Default, param.Name)
+				return false
+			}
+		case api.BOOLEAN_PARAM:
+			if _, err := strconv.ParseBool(param.Default); len(param.Default) > 0 && err != nil {
+				log.Errorf("The default %s of boolean parameter %s is not a boolean", param.Default, param.Name)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// validatePerformParams Validates the perform params against the parameter definitions of the pipeline job.
+func validatePerformParams(definitions []parameterDefinition, params map[string]string) error {
+	defs := map[string]parameterDefinition{}
+	for _, def := range definitions {
+		defs[def.Name] = def
+	}
+
+	for name, value := range params {
+		def, ok := defs[name]
+		if !ok {
+			return fmt.Errorf("The parameter %s is not declared", name)
+		}
+
+		switch def.Type {
+		case parameterClasses[api.BOOLEAN_PARAM]:
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("The value %s of boolean parameter %s is not a boolean", value, name)
+			}
+		case parameterClasses[api.CHOICE_PARAM]:
+			if !containString(def.Choices, value) {
+				return fmt.Errorf("The value %s of parameter %s is not one of the choices %v", value, name, def.Choices)
+			}
+		}
+	}
+
+	return nil
+}
+
+func containString(values []string, desiredValue string) bool {
+	for _, value := range values {
+		if value == desiredValue {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containParameter(definitions []parameterDefinition, name string) bool {
+	for _, def := range definitions {
+		if def.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
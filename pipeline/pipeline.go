@@ -2,47 +2,201 @@ package pipeline
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/supereagle/goline/api"
 )
 
-func generatePipelineJobConfig(pipeline *api.Pipeline, credentialId string) (jobCfg string, err error) {
+func generatePipelineJobConfig(pipeline *api.Pipeline, credentialId string, defaultRetention *api.Retention, jenkinsUser string) (jobCfg string, err error) {
 	// Validate the pipeline config
 	if ok := ValidatePipeline(pipeline); !ok {
 		return "", fmt.Errorf("Pipeline config is not correct")
 	}
 
-	pipelineScriptTmpl, err := generatePipelineScriptTmpl(pipeline, credentialId)
+	pipelineScriptTmpl, err := generatePipelineScriptTmpl(pipeline, credentialId, jenkinsUser)
 	if err != nil {
 		err = fmt.Errorf("Fail to generate the script template for pipeline: %s", pipeline.Name)
-		fmt.Printf(err.Error())
+		log.Errorln(err.Error())
 		return
 	}
 
-	jobTmpl := strings.Replace(PIPELINE_JOB_TEMPLATE, "${pipeline.script}", pipelineScriptTmpl, 1)
+	jobTmpl := strings.Replace(PIPELINE_JOB_TEMPLATE, "${pipeline.script}", escapeXml(pipelineScriptTmpl), 1)
 	jobTmpl = strings.NewReplacer("${pipeline.perform.phases}", convertStagesToString(pipeline.Stages),
-		"${project.branch}", pipeline.Repo.Branch).Replace(jobTmpl)
+		"${project.branch}", pipeline.Repo.Branch,
+		"${project.revision}", defaultRevision(pipeline.Repo),
+		"${pipeline.parameters}", generateParametersTmpl(pipeline.Parameters)+generateCacheParameterTmpl(cacheOf(pipeline))+
+			generatePullRequestParameterTmpl(pipeline.PullRequests)).Replace(jobTmpl)
+
+	jobTmpl = strings.Replace(jobTmpl, "${pipeline.triggers}", generatePipelineTriggersTmpl(pipeline), 1)
+
+	// The retention of the pipeline takes precedence over the default one
+	retention := pipeline.Retention
+	if retention == nil {
+		retention = defaultRetention
+	}
+	jobTmpl = strings.Replace(jobTmpl, "${pipeline.build.discarder}", generateBuildDiscarderTmpl(retention), 1)
+	jobTmpl = strings.Replace(jobTmpl, "${pipeline.concurrency}", generateConcurrencyPropertyTmpl(pipeline.Concurrency), 1)
+	jobTmpl = generateSlackPropertyTmpl(jobTmpl, pipeline.Notifications)
+
+	jobCfg = jobTmpl
+	return
+}
+
+func generatePipelineTriggersTmpl(pipeline *api.Pipeline) string {
+	triggersTmpl := ""
 
 	// Generate the period trigger
 	if pipeline.PeriodTrigger != nil && !pipeline.PeriodTrigger.Skipped {
-		periodTriggerTmpl := strings.Replace(PIPELINE_TRIGGERS_TEMPLATE, "${period.trigger.strategy}",
+		triggersTmpl += strings.Replace(PERIOD_TRIGGER_TEMPLATE, "${period.trigger.strategy}",
 			pipeline.PeriodTrigger.Strategy, 1)
-		jobTmpl = strings.Replace(jobTmpl, "${pipeline.triggers}", periodTriggerTmpl, 1)
-	} else {
-		jobTmpl = strings.Replace(jobTmpl, "${pipeline.triggers}", "<triggers/>", 1)
 	}
 
-	jobCfg = jobTmpl
-	return
+	// Generate the upstream trigger
+	if pipeline.Upstream != nil && len(pipeline.Upstream.Pipelines) > 0 {
+		threshold := thresholdOf(pipeline.Upstream.Threshold)
+		upstreams := []string{}
+		for _, name := range pipeline.Upstream.Pipelines {
+			upstreams = append(upstreams, jobRef(pipeline, name))
+		}
+		triggersTmpl += strings.NewReplacer("${upstream.trigger.projects}", strings.Join(upstreams, ","),
+			"${upstream.trigger.threshold.name}", string(threshold.name),
+			"${upstream.trigger.threshold.ordinal}", strconv.Itoa(threshold.ordinal),
+			"${upstream.trigger.threshold.color}", threshold.color).Replace(UPSTREAM_TRIGGER_TEMPLATE)
+	}
+
+	if triggersTmpl == "" {
+		return "<triggers/>"
+	}
+
+	return strings.Replace(PIPELINE_TRIGGERS_TEMPLATE, "${pipeline.triggers.list}", triggersTmpl, 1)
 }
 
-func generatePipelineScriptTmpl(pipeline *api.Pipeline, credenitalId string) (pipelineScriptTmpl string, err error) {
-	scriptTmpl := strings.NewReplacer("${pipeline.label.node}", pipeline.NodeLabel,
-		"${jenkins.credentialId}", credenitalId,
-		"${project.repoPath}", pipeline.Repo.RepoPath,
-		"${jdk.version}", api.JDK_PATH[pipeline.Jdk]).Replace(PIPELINE_SCRIPT_TEMPLATE)
+func generateDownstreamTmpl(pipeline *api.Pipeline) string {
+	downstreams := pipeline.Downstream
+	if len(downstreams) == 0 {
+		return "// No downstream pipelines"
+	}
+
+	builds := []string{}
+	for _, downstream := range downstreams {
+		// Sort the params to keep the generated script stable
+		names := []string{}
+		for name := range downstream.Params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		params := []string{}
+		for _, name := range names {
+			params = append(params, strings.NewReplacer("${downstream.param.name}", name,
+				"${downstream.param.value}", escapeGroovyString(downstream.Params[name])).Replace(DOWNSTREAM_PARAM_TEMPLATE))
+		}
+
+		builds = append(builds, strings.NewReplacer("${downstream.pipeline}", jobRef(pipeline, downstream.Pipeline),
+			"${downstream.params}", strings.Join(params, ", "),
+			"${downstream.wait}", strconv.FormatBool(downstream.Wait)).Replace(DOWNSTREAM_BUILD_TEMPLATE))
+	}
+
+	return strings.Replace(DOWNSTREAM_TEMPLATE, "${downstream.builds}", strings.Join(builds, "\n\t"), 1)
+}
+
+var defaultPipelineTimeout = &api.Timeout{
+	Time: 1,
+	Unit: api.HOURS,
+}
+
+// timeUnitOf Gets the unit of the timeout, MINUTES is the default one.
+func timeUnitOf(timeout *api.Timeout) api.TimeUnit {
+	if len(timeout.Unit) == 0 {
+		return api.MINUTES
+	}
+	return timeout.Unit
+}
+
+// durationOf Gets the duration of the timeout
+func durationOf(timeout *api.Timeout) time.Duration {
+	switch timeUnitOf(timeout) {
+	case api.SECONDS:
+		return time.Duration(timeout.Time) * time.Second
+	case api.HOURS:
+		return time.Duration(timeout.Time) * time.Hour
+	default:
+		return time.Duration(timeout.Time) * time.Minute
+	}
+}
+
+type threshold struct {
+	name    api.BuildResult
+	ordinal int
+	color   string
+}
+
+// thresholdOf Gets the Jenkins result threshold, SUCCESS is the default one.
+func thresholdOf(result api.BuildResult) threshold {
+	switch result {
+	case api.UNSTABLE:
+		return threshold{api.UNSTABLE, 1, "YELLOW"}
+	case api.FAILURE:
+		return threshold{api.FAILURE, 2, "RED"}
+	default:
+		return threshold{api.SUCCESS, 0, "BLUE"}
+	}
+}
+
+// escapeXml Escapes the value to be put in the text of XML config.
+func escapeXml(value string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(value)
+}
+
+// escapeGroovyString Escapes the value to be put in a single-quoted Groovy string.
+func escapeGroovyString(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(value)
+}
+
+// escapeGroovyGString Escapes the value to be put in a double-quoted Groovy string.
+func escapeGroovyGString(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "$", "\\$").Replace(value)
+}
+
+func generatePipelineScriptTmpl(pipeline *api.Pipeline, credenitalId string, jenkinsUser string) (pipelineScriptTmpl string, err error) {
+	timeout := pipeline.Timeout
+	if timeout == nil {
+		timeout = defaultPipelineTimeout
+	}
+
+	// The credential of the repo takes precedence over the global one
+	if len(pipeline.Repo.CredentialId) > 0 {
+		credenitalId = pipeline.Repo.CredentialId
+	}
+
+	platform := platformOf(pipeline)
+	scmGenerator, err := newSCMGenerator(pipeline.Repo, credenitalId, platform, pipeline.PullRequests != nil)
+	if err != nil {
+		return
+	}
+
+	// Run on one node, on the nodes of the matrix cells in parallel, or on the nodes of the stages in order
+	envTmpl := generateParametersEnvTmpl(pipeline.Parameters)
+	nodeTmpl := ""
+	if pipeline.Matrix != nil {
+		nodeTmpl = generateMatrixTmpl(pipeline, platform, envTmpl)
+	} else if hasStageAgents(pipeline) {
+		nodeTmpl = generateThrottleTmpl(generateAgentsTmpl(pipeline, platform, envTmpl), pipeline.Concurrency)
+	} else {
+		nodeTmpl = generateThrottleTmpl(generateNodeTmpl(pipeline.NodeLabel, pipeline.Jdk, platform, envTmpl), pipeline.Concurrency)
+	}
+	nodeTmpl = generateMaskPasswordsTmpl(nodeTmpl, pipeline.Parameters)
+
+	scriptTmpl := strings.NewReplacer("${pipeline.script.node}", nodeTmpl,
+		"${pipeline.script.milestone}", generateMilestoneTmpl(pipeline.Concurrency, "")).Replace(PIPELINE_SCRIPT_TEMPLATE)
+	scriptTmpl = strings.NewReplacer("${pipeline.timeout.time}", strconv.Itoa(timeout.Time),
+		"${pipeline.timeout.unit}", string(timeUnitOf(timeout)),
+		"${pipeline.script.checkout}", scmGenerator.GenerateCheckout()).Replace(scriptTmpl)
+	scriptTmpl += STAGE_NAME_FUNCTION + TEST_REPORT_FUNCTION
 
 	// Judge the project type
 	var stageGenerator StageGenerator
@@ -51,18 +205,17 @@ func generatePipelineScriptTmpl(pipeline *api.Pipeline, credenitalId string) (pi
 	case api.MAVEN:
 		stageGenerator = &MavenPiplineStageGenerator{pipeline.Project.(api.MavenProject)}
 
-		scriptTmpl += MAVEN_COMMAND_FUNCTION
+		scriptTmpl += strings.NewReplacer("${platform.shell}", platform.ShellStep,
+			"${maven.command}", platform.Command(platform.MavenCommand)).Replace(MAVEN_COMMAND_FUNCTION)
 	case api.GRADLE:
-		stageGenerator = &GradlePiplineStageGenerator{pipeline.Project.(api.GradleProject)}
+		stageGenerator = &GradlePiplineStageGenerator{
+			ProjectConfig: pipeline.Project.(api.GradleProject),
+			Platform:      platform,
+		}
 	case api.SHELL, api.BATCH:
 		stageGenerator = &ScriptPiplineStageGenerator{
 			ProjectConfig: pipeline.Project.(api.ScriptProject),
-			ProjectType:   pType,
-		}
-
-		if pType == api.BATCH {
-			fmt.Println(scriptTmpl)
-			scriptTmpl = strings.Replace(scriptTmpl, " sh ", " bat ", -1)
+			Platform:      platform,
 		}
 	default:
 		err = fmt.Errorf("The project type %v is not supported", pType)
@@ -73,47 +226,98 @@ func generatePipelineScriptTmpl(pipeline *api.Pipeline, credenitalId string) (pi
 
 	// Add the compile stage
 	if containStage(stages, api.COMPILE) {
-		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.compile}", generatePipelineStageTmpl(api.COMPILE), 1)
+		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.compile}", generatePipelineStageTmpl(api.COMPILE, pipeline, jenkinsUser), -1)
 		stageTmpl := stageGenerator.GenerateCompileStage()
 
 		scriptTmpl += stageTmpl
 	} else {
-		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.compile}", "// Skipped", 1)
+		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.compile}", "// Skipped", -1)
 	}
 
 	// Add the unit test stage
 	if containStage(stages, api.UT) {
-		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.unittest}", generatePipelineStageTmpl(api.UT), 1)
+		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.unittest}", generatePipelineStageTmpl(api.UT, pipeline, jenkinsUser), -1)
 		stageTmpl := stageGenerator.GenerateUnitTestStage()
 
 		scriptTmpl += stageTmpl
 	} else {
-		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.unittest}", "// Skipped", 1)
+		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.unittest}", "// Skipped", -1)
 	}
 
 	// Add the build stage
 	if containStage(stages, api.BUILD) {
-		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.build}", generatePipelineStageTmpl(api.BUILD), 1)
+		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.build}", generatePipelineStageTmpl(api.BUILD, pipeline, jenkinsUser), -1)
 		stageTmpl := stageGenerator.GenerateBuildStage()
 
 		scriptTmpl += stageTmpl
 	} else {
-		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.build}", "// Skipped", 1)
+		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.stage.build}", "// Skipped", -1)
 	}
 
-	// Whether archive the workspace
-	if pipeline.ArchiveWorkspace {
-		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.archive.workspace}", ARCHIVE_WORKSPACE, 1)
-	} else {
-		scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.archive.workspace}", "// Not need to archive workspace", 1)
+	// Add the retry function if any stage needs retry
+	for _, option := range pipeline.StageOptions {
+		if option != nil && option.Retry > 0 {
+			scriptTmpl += RETRY_FUNCTION
+			break
+		}
 	}
 
+	// Purge the dependency cache if required
+	scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.cache.purge}", generateCachePurgeTmpl(cacheOf(pipeline)), -1)
+
+	// Archive the artifacts
+	scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.archive}", generateArchiveTmpl(pipeline), -1)
+
+	// Send the notifications
+	notifyStartTmpl, notifyResultTmpl, notifyFunctionTmpl := generateNotifyTmpl(pipeline.Notifications)
+	scriptTmpl = strings.NewReplacer("${pipeline.script.notify.start}", notifyStartTmpl,
+		"${pipeline.script.notify.result}", notifyResultTmpl).Replace(scriptTmpl)
+	scriptTmpl += notifyFunctionTmpl
+
+	// Trigger the downstream pipelines
+	scriptTmpl = strings.Replace(scriptTmpl, "${pipeline.script.downstream}", generateDownstreamTmpl(pipeline), 1)
+
 	pipelineScriptTmpl = scriptTmpl
 	return
 }
 
-func generatePipelineStageTmpl(stage api.Stage) string {
+// generateNodeTmpl Generates the node to run the stages with the JDK and the env
+func generateNodeTmpl(label, jdk string, platform *Platform, envTmpl string) string {
+	return nodeReplacer(label, jdk, platform, envTmpl).Replace(PIPELINE_NODE_TEMPLATE)
+}
+
+// nodeReplacer Replaces the node label and the environment of the node
+func nodeReplacer(label, jdk string, platform *Platform, envTmpl string) *strings.Replacer {
+	return strings.NewReplacer("${pipeline.label.node}", escapeGroovyGString(label),
+		"${jdk.bin}", platform.JdkBin(jdk),
+		"${jdk.home}", platform.JdkHome(jdk),
+		"${pipeline.script.env}", envTmpl)
+}
+
+func generatePipelineStageTmpl(stage api.Stage, pipeline *api.Pipeline, jenkinsUser string) string {
 	stageTmpl := strings.Replace(STAGE_TEMPLATE, "${pipeline.stage}", string(stage), 1)
+
+	// Wrap the stage function with the approval, locks, retry and timeout, each retry has its own timeout
+	if option := pipeline.StageOptions[stage]; option != nil {
+		if option.Approval != nil {
+			stageTmpl = generateApprovalTmpl(stageTmpl, stage, pipeline, option.Approval, jenkinsUser)
+		}
+		stageTmpl = generateLocksTmpl(stageTmpl, option.Locks)
+		if option.Retry > 0 {
+			stageTmpl = strings.Replace(stageTmpl, "${pipeline.script.stage.function}", strings.NewReplacer("${stage.retry.times}", strconv.Itoa(option.Retry),
+				"${stage.retry.backoff}", strconv.Itoa(option.RetryBackoff)).Replace(STAGE_RETRY_TEMPLATE), 1)
+		}
+		if option.Timeout != nil {
+			stageTmpl = strings.Replace(stageTmpl, "${pipeline.script.stage.function}", strings.NewReplacer("${stage.timeout.time}", strconv.Itoa(option.Timeout.Time),
+				"${stage.timeout.unit}", string(timeUnitOf(option.Timeout))).Replace(STAGE_TIMEOUT_TEMPLATE), 1)
+		}
+	}
+
+	// Bind the credentials used in the stage
+	if credentials := generateCredentialsTmpl(stage, pipeline.Credentials); len(credentials) > 0 {
+		stageTmpl = strings.Replace(stageTmpl, "${pipeline.script.stage.function}",
+			strings.Replace(STAGE_CREDENTIALS_TEMPLATE, "${stage.credentials}", credentials, 1), 1)
+	}
 	switch stage {
 	case api.COMPILE:
 		stageTmpl = strings.Replace(stageTmpl, "${pipeline.script.stage.function}", "compile()", 1)
@@ -125,6 +329,11 @@ func generatePipelineStageTmpl(stage api.Stage) string {
 		stageTmpl = strings.Replace(stageTmpl, "${pipeline.script.stage.function}", "deploy()", 1)
 	}
 
+	// Pass the milestone of the stage even if it is not performed, to abort the older builds
+	if milestoneTmpl := generateMilestoneTmpl(pipeline.Concurrency, stage); len(milestoneTmpl) > 0 {
+		stageTmpl = milestoneTmpl + "\n\t\t\t\t\t" + stageTmpl
+	}
+
 	return stageTmpl
 }
 
@@ -140,6 +349,9 @@ func convertStagesToString(stages []api.Stage) (stageStr string) {
 	return
 }
 
+// The stages generated into the pipeline script, the deploy stage has no project config to generate it
+var generatedStages = []api.Stage{api.COMPILE, api.UT, api.BUILD}
+
 func containStage(stages []api.Stage, desiredStage api.Stage) bool {
 	for _, stage := range stages {
 		if stage == desiredStage {
@@ -153,12 +365,33 @@ func containStage(stages []api.Stage, desiredStage api.Stage) bool {
 // validatePipeline Validates the pipeline config.
 // Returns true if correct, or false if wrong.
 func ValidatePipeline(pipeline *api.Pipeline) bool {
-	// Check the JDK
-	if _, ok := api.JDK_PATH[pipeline.Jdk]; !ok {
-		log.Errorf("The jdk version %s is not supported", pipeline.Jdk)
+	// Check the branch fan-out, whose repo has no branch
+	if pipeline.Branches != nil {
+		return validateBranches(pipeline)
+	}
+
+	// Check the namespace and the name
+	if ok := validateNamespace(pipeline); !ok {
+		return false
+	}
+
+	// Check the tags
+	if ok := validateTags(pipeline.Tags); !ok {
 		return false
 	}
 
+	// Check the agent OS and the JDK on it
+	if ok := validateAgentOS(pipeline); !ok {
+		return false
+	}
+
+	// Check the matrix
+	if pipeline.Matrix != nil {
+		if ok := validateMatrix(pipeline, platformOf(pipeline)); !ok {
+			return false
+		}
+	}
+
 	// Check the period trigger
 	if pipeline.PeriodTrigger != nil && !pipeline.PeriodTrigger.Skipped {
 		// TODO (robin) Check strategy to follow the syntax of cron
@@ -167,18 +400,85 @@ func ValidatePipeline(pipeline *api.Pipeline) bool {
 		}
 	}
 
+	// Check the timeout of the pipeline
+	if pipeline.Timeout != nil {
+		if ok := validateTimeout(pipeline.Timeout); !ok {
+			return false
+		}
+	}
+
+	// Check the options of the stages
+	for stage, option := range pipeline.StageOptions {
+		if ok := validateStageOption(stage, option); !ok {
+			return false
+		}
+	}
+
+	// Check the build parameters
+	if ok := validateParameters(pipeline.Parameters); !ok {
+		return false
+	}
+
+	// Check the credential bindings
+	if ok := validateCredentialBindings(pipeline); !ok {
+		return false
+	}
+
+	// Check the notifications
+	if pipeline.Notifications != nil {
+		if ok := validateNotifications(pipeline.Notifications); !ok {
+			return false
+		}
+	}
+
+	// Check the retention
+	if pipeline.Retention != nil {
+		if ok := validateRetention(pipeline.Retention); !ok {
+			return false
+		}
+	}
+
+	// Check the agents of the stages
+	if ok := validateStageAgents(pipeline); !ok {
+		return false
+	}
+
+	// Check the concurrency
+	if pipeline.Concurrency != nil {
+		if ok := validateConcurrency(pipeline); !ok {
+			return false
+		}
+	}
+
+	// Check the artifacts
+	if pipeline.Artifacts != nil {
+		if ok := validateArtifacts(pipeline); !ok {
+			return false
+		}
+	}
+
+	// Check the upstream and downstream pipelines
+	if ok := validateChain(pipeline); !ok {
+		return false
+	}
+
 	// Check the repo
 	repo := pipeline.Repo
 	if repo == nil {
 		log.Errorln("The source code repo is not specified")
 		return false
 	}
-	// TODO (robin) Check the repo path and branch pattern
-	if len(repo.RepoPath) == 0 || len(repo.Branch) == 0 {
-		log.Errorln("The source code repo path or branch is empty")
+	if ok := validateRepo(repo); !ok {
 		return false
 	}
 
+	// Check the pull request mode
+	if pipeline.PullRequests != nil {
+		if ok := validatePullRequests(pipeline); !ok {
+			return false
+		}
+	}
+
 	projectType := pipeline.ProjectType
 	switch projectType {
 	case api.SHELL, api.BATCH:
@@ -192,6 +492,10 @@ func ValidatePipeline(pipeline *api.Pipeline) bool {
 			log.Errorln("The project stages' configs are empty")
 			return false
 		}
+		if containStage(pipeline.Stages, api.UT) && project.UnitTest == nil {
+			log.Errorln("The unit test config is empty")
+			return false
+		}
 	case api.MAVEN:
 		project, ok := pipeline.Project.(api.MavenProject)
 		if !ok {
@@ -202,12 +506,30 @@ func ValidatePipeline(pipeline *api.Pipeline) bool {
 			log.Errorln("The maven root pom is not specified")
 			return false
 		}
+		if containStage(pipeline.Stages, api.UT) && project.UnitTest == nil {
+			log.Errorln("The unit test config is empty")
+			return false
+		}
+		if project.Cache != nil {
+			if ok := validateCache(project.Cache); !ok {
+				return false
+			}
+		}
 	case api.GRADLE:
-		_, ok := pipeline.Project.(api.GradleProject)
+		project, ok := pipeline.Project.(api.GradleProject)
 		if !ok {
 			log.Errorf("Project config is not compatiable with project type %s", projectType)
 			return false
 		}
+		if containStage(pipeline.Stages, api.UT) && project.UnitTest == nil {
+			log.Errorln("The unit test config is empty")
+			return false
+		}
+		if project.Cache != nil {
+			if ok := validateCache(project.Cache); !ok {
+				return false
+			}
+		}
 	default:
 		log.Errorf("The project type %s is not supported", pipeline.ProjectType)
 		return false
@@ -215,3 +537,109 @@ func ValidatePipeline(pipeline *api.Pipeline) bool {
 
 	return true
 }
+
+// validateChain Validates the upstream and downstream pipelines.
+// Cycles among the pipeline itself and its direct neighbours are rejected here,
+// the ones through other existing pipelines are checked by the manager.
+func validateChain(pipeline *api.Pipeline) bool {
+	upstreams := map[string]bool{}
+	if upstream := pipeline.Upstream; upstream != nil {
+		switch upstream.Threshold {
+		case "", api.SUCCESS, api.UNSTABLE, api.FAILURE:
+		default:
+			log.Errorf("The upstream threshold %s is not supported", upstream.Threshold)
+			return false
+		}
+
+		for _, name := range upstream.Pipelines {
+			if len(strings.TrimSpace(name)) == 0 {
+				log.Errorln("The upstream pipeline name is empty")
+				return false
+			}
+			if name == fullNameOf(pipeline) {
+				log.Errorf("The pipeline %s can not be the upstream of itself", name)
+				return false
+			}
+			upstreams[name] = true
+		}
+	}
+
+	for _, downstream := range pipeline.Downstream {
+		if downstream == nil || len(strings.TrimSpace(downstream.Pipeline)) == 0 {
+			log.Errorln("The downstream pipeline name is empty")
+			return false
+		}
+		if downstream.Pipeline == fullNameOf(pipeline) {
+			log.Errorf("The pipeline %s can not be the downstream of itself", downstream.Pipeline)
+			return false
+		}
+		if upstreams[downstream.Pipeline] {
+			log.Errorf("The pipeline %s is both upstream and downstream, which makes a cycle", downstream.Pipeline)
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateTimeout Validates the time and unit of the timeout.
+func validateTimeout(timeout *api.Timeout) bool {
+	if timeout.Time <= 0 {
+		log.Errorf("The timeout %d should be positive", timeout.Time)
+		return false
+	}
+
+	switch timeout.Unit {
+	case "", api.SECONDS, api.MINUTES, api.HOURS:
+	default:
+		log.Errorf("The timeout unit %s is not supported", timeout.Unit)
+		return false
+	}
+
+	return true
+}
+
+// validateStageOption Validates the options of the stage, which is only supported by the generated stages.
+func validateStageOption(stage api.Stage, option *api.StageOption) bool {
+	switch stage {
+	case api.COMPILE, api.UT, api.BUILD, api.DEPLOY:
+	default:
+		log.Errorf("The stage %s is not supported", stage)
+		return false
+	}
+
+	if option == nil {
+		return true
+	}
+
+	if !containStage(generatedStages, stage) {
+		log.Errorf("The options of stage %s can not be rendered, as the stage is not generated", stage)
+		return false
+	}
+
+	if option.Timeout != nil {
+		if ok := validateTimeout(option.Timeout); !ok {
+			return false
+		}
+	}
+
+	if option.Retry < 0 || option.RetryBackoff < 0 {
+		log.Errorf("The retry %d and retry backoff %d of stage %s should not be negative", option.Retry, option.RetryBackoff, stage)
+		return false
+	}
+
+	if option.Approval != nil {
+		if ok := validateApproval(stage, option.Approval); !ok {
+			return false
+		}
+	}
+
+	for _, lock := range option.Locks {
+		if len(strings.TrimSpace(lock)) == 0 {
+			log.Errorf("The lock of stage %s is empty", stage)
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,6 +1,8 @@
 package pipeline_test
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/supereagle/goline/api"
@@ -40,6 +42,22 @@ func TestValidatePipeline(t *testing.T) {
 			},
 			result: true,
 		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-unit-test-config",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Stages: []api.Stage{api.COMPILE, api.UT},
+			},
+			result: false,
+		},
 		PipelineValidator{
 			pipeline: &api.Pipeline{
 				Name: "validate-period-trigger",
@@ -77,11 +95,1622 @@ func TestValidatePipeline(t *testing.T) {
 			},
 			result: false,
 		},
-	}
-
-	for _, pv := range pvs {
-		if pipeline.ValidatePipeline(pv.pipeline) != pv.result {
-			t.Errorf("Pipeline %s's config is not correct!", pv.pipeline.Name)
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-stage-options",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				Timeout: &api.Timeout{
+					Time: 2,
+					Unit: api.HOURS,
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.COMPILE: &api.StageOption{
+						Timeout: &api.Timeout{
+							Time: 30,
+						},
+						Retry:        2,
+						RetryBackoff: 10,
+					},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-stage-options2",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.COMPILE: &api.StageOption{
+						Timeout: &api.Timeout{
+							Time: 30,
+							Unit: "DAYS",
+						},
+					},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-parameters",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				Parameters: []*api.Parameter{
+					&api.Parameter{
+						Name:    "TARGET_ENV",
+						Type:    api.CHOICE_PARAM,
+						Choices: []string{"qa", "prod"},
+						Default: "qa",
+					},
+					&api.Parameter{
+						Name:    "DRY_RUN",
+						Type:    api.BOOLEAN_PARAM,
+						Default: "true",
+					},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-parameters2",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				Parameters: []*api.Parameter{
+					&api.Parameter{
+						Name: "branch",
+						Type: api.STRING_PARAM,
+					},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-credentials",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Stages: []api.Stage{api.COMPILE, api.BUILD},
+				Credentials: []*api.CredentialBinding{
+					&api.CredentialBinding{
+						CredentialId:     "deployer",
+						Type:             api.USERNAME_PASSWORD,
+						UsernameVariable: "DEPLOY_USER",
+						PasswordVariable: "DEPLOY_PASSWORD",
+						Stages:           []api.Stage{api.BUILD},
+					},
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-credentials2",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Stages: []api.Stage{api.COMPILE},
+				Credentials: []*api.CredentialBinding{
+					&api.CredentialBinding{
+						CredentialId: "token",
+						Type:         api.SECRET_TEXT,
+						Variable:     "TOKEN",
+						Stages:       []api.Stage{api.BUILD},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-credentials-deploy",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Stages: []api.Stage{api.BUILD, api.DEPLOY},
+				Credentials: []*api.CredentialBinding{
+					&api.CredentialBinding{
+						CredentialId: "token",
+						Type:         api.SECRET_TEXT,
+						Variable:     "TOKEN",
+						Stages:       []api.Stage{api.DEPLOY},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-svn-repo",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					Type:      api.SVN,
+					RepoPath:  "svn://test.com/test",
+					Branch:    "trunk",
+					Revision:  "1024",
+					Externals: true,
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-git-repo",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath:  "git@test.com:test/test.git",
+					Branch:    "master",
+					Externals: true,
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-git-options",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath:    "git@test.com:test/test.git",
+					Branch:      "master",
+					Depth:       1,
+					Submodules:  true,
+					SparsePaths: []string{"services/order"},
+					Lfs:         true,
+					Clean:       true,
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-git-shallow-tag",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+					Tag:      "v1.0",
+					Depth:    1,
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-git-option-path",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "--upload-pack=touch /tmp/pwned",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-git-options2",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+					Tag:      "v1.0",
+					Revision: "8f3e2a1",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-notifications",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Notifications: &api.Notifications{
+					Email: &api.EmailNotification{
+						Recipients: []string{"team@test.com"},
+					},
+					Events:          []api.NotifyEvent{api.NOTIFY_FAILURE, api.NOTIFY_FIXED},
+					IncludeCulprits: true,
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-notifications2",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Notifications: &api.Notifications{
+					Events: []api.NotifyEvent{api.NOTIFY_ALWAYS},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-notifications3",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Notifications: &api.Notifications{
+					Email: &api.EmailNotification{
+						Recipients: []string{"team@test.com"},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-notifications4",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Notifications: &api.Notifications{
+					Email: &api.EmailNotification{
+						Recipients: []string{"o'brien@test.com"},
+					},
+					Events: []api.NotifyEvent{api.NOTIFY_FAILURE},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-os-windows",
+				Jdk:  "jdk1.8",
+				OS:   api.WINDOWS,
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-os-unknown",
+				Jdk:  "jdk1.8",
+				OS:   "macos",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-os-batch",
+				Jdk:  "jdk1.8",
+				OS:   api.LINUX,
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "batch",
+				Project: api.ScriptProject{
+					Compile: &api.ScriptCompile{Command: "build.cmd compile"},
+					Build:   &api.ScriptBuild{Command: "build.cmd package"},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-matrix",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Matrix: &api.Matrix{
+					Jdks: []string{"jdk1.7", "jdk1.8"},
+					Env:  map[string][]string{"DB": []string{"mysql", "postgres"}},
+					Excludes: []map[string]string{
+						map[string]string{"jdk": "jdk1.7", "DB": "postgres"},
+					},
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-matrix-jdk",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Matrix: &api.Matrix{
+					Jdks: []string{"jdk1.9"},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-matrix-exclude",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Matrix: &api.Matrix{
+					NodeLabels: []string{"centos"},
+					Excludes: []map[string]string{
+						map[string]string{"os": "centos"},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-matrix-all-excluded",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Matrix: &api.Matrix{
+					NodeLabels: []string{"centos"},
+					Excludes: []map[string]string{
+						map[string]string{"node_label": "centos"},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-artifacts",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Artifacts: &api.Artifacts{
+					Excludes:      []string{"**/target/*-sources.jar"},
+					Fingerprint:   true,
+					OnlyOnSuccess: true,
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-artifacts-default",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "shell",
+				Project: api.ScriptProject{
+					Compile: &api.ScriptCompile{Command: "make"},
+					Build:   &api.ScriptBuild{Command: "make dist"},
+				},
+				Artifacts: &api.Artifacts{},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-artifacts-pattern",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "shell",
+				Project: api.ScriptProject{
+					Compile: &api.ScriptCompile{Command: "make"},
+					Build:   &api.ScriptBuild{Command: "make dist"},
+				},
+				Artifacts: &api.Artifacts{
+					Includes: []string{"dist/*.tar.gz, dist/*.zip"},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-retention",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Retention: &api.Retention{
+					NumToKeep:         20,
+					ArtifactNumToKeep: 5,
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-retention-negative",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Retention: &api.Retention{
+					DaysToKeep: -1,
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-concurrency",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Concurrency: &api.Concurrency{
+					DisableConcurrent: true,
+					Throttle: &api.Throttle{
+						MaxPerNode: 1,
+						Categories: []string{"deploy"},
+					},
+					Supersede: true,
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.BUILD: &api.StageOption{
+						Locks: []string{"staging-env"},
+					},
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-concurrency-matrix",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Matrix: &api.Matrix{
+					Jdks: []string{"jdk1.7", "jdk1.8"},
+				},
+				Concurrency: &api.Concurrency{
+					Supersede: true,
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-concurrency-lock",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.BUILD: &api.StageOption{
+						Locks: []string{" "},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-approval",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.BUILD: &api.StageOption{
+						Approval: &api.Approval{
+							Approvers: []string{"alice", "bob"},
+							Timeout: &api.Timeout{
+								Time: 2,
+								Unit: api.HOURS,
+							},
+						},
+					},
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-approval-deploy",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.DEPLOY: &api.StageOption{
+						Approval: &api.Approval{
+							Approvers: []string{"alice"},
+						},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-stage-option-deploy",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.DEPLOY: &api.StageOption{
+						Timeout:   &api.Timeout{Time: 10, Unit: api.MINUTES},
+						NodeLabel: "deployer",
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-approval-approver",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.BUILD: &api.StageOption{
+						Approval: &api.Approval{
+							Approvers: []string{"alice,bob"},
+						},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-approval-matrix",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Matrix: &api.Matrix{
+					Jdks: []string{"jdk1.7", "jdk1.8"},
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.BUILD: &api.StageOption{
+						Approval: &api.Approval{},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-stage-agents",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Stash: &api.Stash{
+					Includes: []string{"pom.xml", "**/target/**"},
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.COMPILE: &api.StageOption{
+						NodeLabel: "big-builder",
+					},
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-stage-agents-stash",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Stash: &api.Stash{
+					Includes: []string{"pom.xml, **/target/**"},
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.COMPILE: &api.StageOption{
+						NodeLabel: "big-builder",
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-stage-agents-matrix",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Matrix: &api.Matrix{
+					Jdks: []string{"jdk1.7", "jdk1.8"},
+				},
+				StageOptions: map[api.Stage]*api.StageOption{
+					api.COMPILE: &api.StageOption{
+						NodeLabel: "big-builder",
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-cache",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+					Cache: &api.Cache{
+						Scope:       api.PIPELINE_CACHE,
+						ForceUpdate: true,
+					},
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-cache-path",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+					Cache: &api.Cache{
+						Scope: api.PIPELINE_CACHE,
+						Path:  "/data/m2",
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-cache-offline",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "gradle",
+				Project: api.GradleProject{
+					Cache: &api.Cache{
+						Offline:     true,
+						ForceUpdate: true,
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name:      "validate-namespace",
+				Namespace: "team-a",
+				Jdk:       "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				Downstream: []*api.Downstream{
+					&api.Downstream{
+						Pipeline: "team-b/downstream-pipeline",
+					},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "team-a/validate-namespace-name",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name:      "validate-chain-namespace",
+				Namespace: "team-a",
+				Jdk:       "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				Upstream: &api.Upstream{
+					Pipelines: []string{"team-a/validate-chain-namespace"},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-tags",
+				Tags: []string{"team-a", "backend"},
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-tags-duplicated",
+				Tags: []string{"team-a", "team-a"},
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-branches",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Branches: &api.Branches{
+					Pattern: "release/*",
+					Overrides: []*api.BranchOverride{
+						&api.BranchOverride{
+							Pattern: "release/2.*",
+							Pipeline: map[string]interface{}{
+								"stages": []string{"compile", "build"},
+							},
+						},
+					},
+					GracePeriod: &api.Timeout{
+						Time: 72,
+						Unit: api.HOURS,
+					},
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-branches-override-name",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Branches: &api.Branches{
+					Pattern: "feature/*",
+					Overrides: []*api.BranchOverride{
+						&api.BranchOverride{
+							Pattern: "feature/*",
+							Pipeline: map[string]interface{}{
+								"name": "feature-pipeline",
+							},
+						},
+					},
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-branches-pattern",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				Branches: &api.Branches{
+					Pattern: "release/[",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-pull-requests",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "https://github.com/test/test.git",
+					Branch:   "master",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				PullRequests: &api.PullRequests{
+					Reporter: "github",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-pull-requests-svn",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					Type:     api.SVN,
+					RepoPath: "https://svn.test.com/repos/test",
+					Branch:   "trunk",
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+				PullRequests: &api.PullRequests{
+					Reporter: "github",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-chain",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				Upstream: &api.Upstream{
+					Pipelines: []string{"upstream-pipeline"},
+					Threshold: api.UNSTABLE,
+				},
+				Downstream: []*api.Downstream{
+					&api.Downstream{
+						Pipeline: "downstream-pipeline",
+					},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: true,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-chain-cycle",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				Upstream: &api.Upstream{
+					Pipelines: []string{"other-pipeline"},
+				},
+				Downstream: []*api.Downstream{
+					&api.Downstream{
+						Pipeline: "other-pipeline",
+					},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+		PipelineValidator{
+			pipeline: &api.Pipeline{
+				Name: "validate-chain-self",
+				Jdk:  "jdk1.8",
+				Repo: &api.Repo{
+					RepoPath: "git@test.com:test/test.git",
+					Branch:   "master",
+				},
+				Downstream: []*api.Downstream{
+					&api.Downstream{
+						Pipeline: "validate-chain-self",
+					},
+				},
+				ProjectType: "maven",
+				Project: api.MavenProject{
+					RootPom: "pom.xml",
+				},
+			},
+			result: false,
+		},
+	}
+
+	for _, pv := range pvs {
+		if pipeline.ValidatePipeline(pv.pipeline) != pv.result {
+			t.Errorf("Pipeline %s's config is not correct!", pv.pipeline.Name)
+		}
+	}
+}
+
+func TestValidateCredential(t *testing.T) {
+	type CredentialValidator struct {
+		credential *api.Credential
+		result     bool
+	}
+
+	cvs := []CredentialValidator{
+		CredentialValidator{
+			credential: &api.Credential{
+				Id:         "deploy-key",
+				Type:       api.SSH_KEY,
+				Username:   "git",
+				PrivateKey: "private key",
+			},
+			result: true,
+		},
+		CredentialValidator{
+			credential: &api.Credential{
+				Id:   "nexus-token",
+				Type: api.SECRET_TEXT,
+			},
+			result: false,
+		},
+		CredentialValidator{
+			credential: &api.Credential{
+				Id:     "invalid/id",
+				Type:   api.SECRET_TEXT,
+				Secret: "token",
+			},
+			result: false,
+		},
+		CredentialValidator{
+			credential: &api.Credential{
+				Id:   "secret-file",
+				Type: api.SECRET_FILE,
+			},
+			result: false,
+		},
+	}
+
+	for _, cv := range cvs {
+		if pipeline.ValidateCredential(cv.credential) != cv.result {
+			t.Errorf("Credential %s's config is not correct!", cv.credential.Id)
+		}
+	}
+}
+
+func TestMergePipeline(t *testing.T) {
+	template := map[string]interface{}{
+		"jdk":    "jdk1.8",
+		"stages": []interface{}{"compile", "unit_test", "build"},
+		"type":   "maven",
+		"project": map[string]interface{}{
+			"root_pom": "pom.xml",
+			"options":  "-Pci",
+		},
+		"period_trigger": map[string]interface{}{
+			"strategy": "H 2 * * *",
+		},
+	}
+	overrides := map[string]interface{}{
+		"name":   "service",
+		"stages": []interface{}{"compile", "build"},
+		"project": map[string]interface{}{
+			"options": "-Pci -Pservice",
+		},
+		"period_trigger": nil,
+	}
+	expected := map[string]interface{}{
+		"name":   "service",
+		"jdk":    "jdk1.8",
+		"stages": []interface{}{"compile", "build"},
+		"type":   "maven",
+		"project": map[string]interface{}{
+			"root_pom": "pom.xml",
+			"options":  "-Pci -Pservice",
+		},
+	}
+
+	merged := pipeline.MergePipeline(template, overrides)
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected the merged pipeline %v, but got %v", expected, merged)
+	}
+	if _, ok := template["period_trigger"]; !ok {
+		t.Errorf("The template is changed by merging")
+	}
+
+	pl, err := pipeline.DecodePipeline(merged)
+	if err != nil {
+		t.Fatalf("Fail to decode the merged pipeline as %s", err.Error())
+	}
+	if project, ok := pl.Project.(api.MavenProject); !ok || project.RootPom != "pom.xml" {
+		t.Errorf("The project of the merged pipeline is not decoded: %v", pl.Project)
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	testCases := map[string]struct {
+		template *api.PipelineTemplate
+		expected bool
+	}{
+		"valid": {
+			template: &api.PipelineTemplate{
+				Name:     "maven-service",
+				Pipeline: map[string]interface{}{"type": "maven"},
+			},
+			expected: true,
+		},
+		"invalid-name": {
+			template: &api.PipelineTemplate{
+				Name:     "maven/service",
+				Pipeline: map[string]interface{}{"type": "maven"},
+			},
+			expected: false,
+		},
+		"owned-field": {
+			template: &api.PipelineTemplate{
+				Name:     "maven-service",
+				Pipeline: map[string]interface{}{"name": "service"},
+			},
+			expected: false,
+		},
+		"password-default": {
+			template: &api.PipelineTemplate{
+				Name: "maven-service",
+				Pipeline: map[string]interface{}{"parameters": []interface{}{
+					map[string]interface{}{"name": "DB_PASSWORD", "type": "password", "default": "secret"},
+				}},
+			},
+			expected: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		if result := pipeline.ValidateTemplate(tc.template); result != tc.expected {
+			t.Errorf("Case %s: expected %v, but got %v", name, tc.expected, result)
+		}
+	}
+}
+
+func TestExportPipeline(t *testing.T) {
+	pl := &api.Pipeline{
+		Name:          "order-service",
+		Jdk:           "jdk1.8",
+		Repo:          &api.Repo{RepoPath: "git@example.com:team/order.git", Branch: "master"},
+		PeriodTrigger: &api.PeriodTrigger{Strategy: "H 2 * * 1-5"},
+		ProjectType:   api.MAVEN,
+		Project: api.MavenProject{
+			RootPom:  "pom.xml",
+			UnitTest: &api.MavenUnitTest{TestReportPath: "target/surefire-reports"},
+		},
+		Stages:     []api.Stage{api.COMPILE, api.UT, api.BUILD},
+		Artifacts:  &api.Artifacts{OnlyOnSuccess: true},
+		Downstream: []*api.Downstream{&api.Downstream{Pipeline: "deploy"}},
+		Parameters: []*api.Parameter{
+			&api.Parameter{Name: "DEPLOY", Type: api.BOOLEAN_PARAM, Default: "true"},
+			&api.Parameter{Name: "TAG", Type: api.STRING_PARAM, Default: "it's"},
+		},
+	}
+
+	testCases := map[string]struct {
+		format   api.ExportFormat
+		path     string
+		contains []string
+		warnings int
+	}{
+		"gitlab": {
+			format: api.GITLAB_CI,
+			path:   ".gitlab-ci.yml",
+			contains: []string{
+				`    - if: "$CI_COMMIT_BRANCH == \"master\""`,
+				`  image: "maven:3-jdk-8"`,
+				"    - \"mvn -B -f pom.xml clean package -e -DskipTests=true -Dfindbugs.skip=true\"\n",
+				"      junit:\n        - \"**/target/surefire-reports/TEST-*.xml\"",
+				"      - \"**/target/*.jar\"\n      - \"**/target/*.war\"\n    when: on_success",
+			},
+			// The period trigger and the downstream pipeline
+			warnings: 2,
+		},
+		"github": {
+			format: api.GITHUB_ACTIONS,
+			path:   ".github/workflows/order-service.yml",
+			contains: []string{
+				"  push:\n    branches:\n      - \"master\"",
+				`    - cron: "0 2 * * 1-5"`,
+				`          java-version: "8"`,
+				"      - name: Unit Test\n        run: \"mvn -B -f pom.xml clean org.jacoco",
+				"          path: \"**/target/surefire-reports/TEST-*.xml\"",
+				"        if: success()",
+				"      DEPLOY:\n        type: boolean\n        default: true",
+				`  DEPLOY: "${{ fromJSON(github.event_name == 'workflow_dispatch' && toJSON(inputs.DEPLOY) || 'true') }}"`,
+				`  TAG: "${{ fromJSON(github.event_name == 'workflow_dispatch' && toJSON(inputs.TAG) || '\"it''s\"') }}"`,
+			},
+			// The approximated period trigger and the downstream pipeline
+			warnings: 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		export, err := pipeline.ExportPipeline(pl, tc.format)
+		if err != nil {
+			t.Errorf("Case %s: fail to export the pipeline as %s", name, err.Error())
+			continue
+		}
+		if export.Path != tc.path {
+			t.Errorf("Case %s: expected path %s, but got %s", name, tc.path, export.Path)
+		}
+		for _, expected := range tc.contains {
+			if !strings.Contains(export.Content, expected) {
+				t.Errorf("Case %s: expected the content containing %s, but got %s", name, expected, export.Content)
+			}
+		}
+		if len(export.Warnings) != tc.warnings {
+			t.Errorf("Case %s: expected %d warnings, but got %v", name, tc.warnings, export.Warnings)
+		}
+	}
+
+	if _, err := pipeline.ExportPipeline(pl, "jenkins"); err == nil {
+		t.Errorf("The unsupported export format is not rejected")
+	}
+
+	pl.Stages = append(pl.Stages, api.DEPLOY)
+	export, err := pipeline.ExportPipeline(pl, api.GITLAB_CI)
+	if err != nil {
+		t.Errorf("Fail to export the pipeline with the deploy stage as %s", err.Error())
+	} else if len(export.Warnings) != 3 {
+		t.Errorf("The deploy stage is not warned, got %v", export.Warnings)
+	}
+
+	pl.Project = api.MavenProject{RootPom: "pom.xml"}
+	if _, err := pipeline.ExportPipeline(pl, api.GITLAB_CI); err == nil {
+		t.Errorf("The unit test stage without the unit test config is not rejected")
+	}
+}
+
+func TestGeneratePipelineJobConfig(t *testing.T) {
+	newPipeline := func() *api.Pipeline {
+		return &api.Pipeline{
+			Name: "service-pipeline",
+			Jdk:  "jdk1.8",
+			Repo: &api.Repo{
+				RepoPath: "git@test.com:test/test.git",
+				Branch:   "master",
+			},
+			ProjectType: "maven",
+			Project: api.MavenProject{
+				RootPom: "pom.xml",
+			},
+			Stages: []api.Stage{api.COMPILE, api.BUILD},
+		}
+	}
+
+	approval := newPipeline()
+	approval.StageOptions = map[api.Stage]*api.StageOption{
+		api.BUILD: &api.StageOption{
+			Approval: &api.Approval{Approvers: []string{"alice", "bob"}},
+		},
+	}
+	openApproval := newPipeline()
+	openApproval.StageOptions = map[api.Stage]*api.StageOption{
+		api.BUILD: &api.StageOption{Approval: &api.Approval{}},
+	}
+	timeout := newPipeline()
+	timeout.Timeout = &api.Timeout{Time: 2, Unit: api.HOURS}
+	timeout.StageOptions = map[api.Stage]*api.StageOption{
+		api.COMPILE: &api.StageOption{Timeout: &api.Timeout{Time: 10, Unit: api.MINUTES}},
+	}
+	retry := newPipeline()
+	retry.StageOptions = map[api.Stage]*api.StageOption{
+		api.COMPILE: &api.StageOption{Retry: 2, RetryBackoff: 30, Timeout: &api.Timeout{Time: 10}},
+	}
+	locks := newPipeline()
+	locks.StageOptions = map[api.Stage]*api.StageOption{
+		api.BUILD: &api.StageOption{Locks: []string{"nexus", "staging-db"}},
+	}
+	credentials := newPipeline()
+	credentials.Credentials = []*api.CredentialBinding{
+		&api.CredentialBinding{CredentialId: "nexus-token", Type: api.SECRET_TEXT, Variable: "NEXUS_TOKEN", Stages: []api.Stage{api.BUILD}},
+	}
+	quotedCredentials := newPipeline()
+	quotedCredentials.Credentials = []*api.CredentialBinding{
+		&api.CredentialBinding{CredentialId: `team's\token`, Type: api.SECRET_TEXT, Variable: "TOKEN", Stages: []api.Stage{api.BUILD}},
+	}
+	slack := newPipeline()
+	slack.Notifications = &api.Notifications{
+		Slack:  &api.SlackNotification{TeamDomain: "team", Room: "#builds", TokenCredentialId: "slack'token"},
+		Events: []api.NotifyEvent{api.NOTIFY_FAILURE},
+	}
+	agents := newPipeline()
+	agents.NodeLabel = "linux"
+	agents.StageOptions = map[api.Stage]*api.StageOption{
+		api.COMPILE: &api.StageOption{NodeLabel: "fast"},
+	}
+
+	testCases := map[string]struct {
+		pipeline *api.Pipeline
+		contains []string
+		excludes []string
+	}{
+		"approval": {
+			pipeline: approval,
+			contains: []string{
+				"input(id: 'ApproveBuild', message: 'Approve the build stage of service-pipeline?', ok: 'Approve', submitter: 'alice,bob,goline',",
+			},
+		},
+		"approval-anyone": {
+			pipeline: openApproval,
+			contains: []string{"input(id: 'ApproveBuild'"},
+			excludes: []string{"submitter"},
+		},
+		"no-options": {
+			pipeline: newPipeline(),
+			contains: []string{"timeout(time: 1, unit: 'HOURS') {", "compile()\n", "build()\n"},
+			excludes: []string{"retryWithBackoff(", "lock(resource:", "withCredentials(", "input(id:", "stash name:"},
+		},
+		"timeout": {
+			pipeline: timeout,
+			contains: []string{
+				"timeout(time: 2, unit: 'HOURS') {",
+				"timeout(time: 10, unit: 'MINUTES') {\n\t\t\t\t\t\t\tcompile()",
+			},
+		},
+		"retry": {
+			pipeline: retry,
+			contains: []string{
+				"retryWithBackoff(2, 30) {\n\t\t\t\t\t\t\ttimeout(time: 10, unit: 'MINUTES') {",
+				"def retryWithBackoff(int times, int backoff, body) {",
+			},
+		},
+		"locks": {
+			pipeline: locks,
+			contains: []string{"lock(resource: 'nexus') {", "lock(resource: 'staging-db') {"},
+		},
+		"credentials": {
+			pipeline: credentials,
+			contains: []string{"withCredentials([string(credentialsId: 'nexus-token', variable: 'NEXUS_TOKEN')]) {"},
+		},
+		"credentials-quoted": {
+			pipeline: quotedCredentials,
+			contains: []string{`withCredentials([string(credentialsId: 'team\'s\\token', variable: 'TOKEN')]) {`},
+		},
+		"slack-quoted": {
+			pipeline: slack,
+			contains: []string{`tokenCredentialId: 'slack\'token',`},
+		},
+		"stage-agents": {
+			pipeline: agents,
+			contains: []string{
+				"node(\"fast\") {",
+				"node(\"linux\") {",
+				"stash name: 'workspace'",
+				"unstash 'workspace'",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		if ok := pipeline.ValidatePipeline(tc.pipeline); !ok {
+			t.Errorf("Case %s: the pipeline is not valid", name)
+			continue
+		}
+		jobCfg, err := pipeline.GeneratePipelineJobConfig(tc.pipeline, "", nil, "goline")
+		if err != nil {
+			t.Errorf("Case %s: fail to generate the job config as %s", name, err.Error())
+			continue
+		}
+		for _, expected := range tc.contains {
+			if !strings.Contains(jobCfg, expected) {
+				t.Errorf("Case %s: expected the job config containing %s, but got %s", name, expected, jobCfg)
+			}
+		}
+		for _, unexpected := range tc.excludes {
+			if strings.Contains(jobCfg, unexpected) {
+				t.Errorf("Case %s: expected the job config not containing %s, but got %s", name, unexpected, jobCfg)
+			}
+		}
+	}
+}
+
+func TestParsePasswordDefaults(t *testing.T) {
+	pl := &api.Pipeline{
+		Name: "service-pipeline",
+		Jdk:  "jdk1.8",
+		Repo: &api.Repo{
+			RepoPath: "git@test.com:test/test.git",
+			Branch:   "master",
+		},
+		ProjectType: "maven",
+		Project: api.MavenProject{
+			RootPom: "pom.xml",
+		},
+		Parameters: []*api.Parameter{
+			&api.Parameter{Name: "TOKEN", Type: api.PASSWORD_PARAM, Default: "s3cr<t"},
+			&api.Parameter{Name: "TAG", Type: api.STRING_PARAM, Default: "latest"},
+		},
+	}
+	jobCfg, err := pipeline.GeneratePipelineJobConfig(pl, "", nil, "goline")
+	if err != nil {
+		t.Fatalf("Fail to generate the job config as %s", err.Error())
+	}
+
+	// The config saved by Jenkins is XML 1.1, with the encrypted defaults
+	savedCfg := `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job@2.3">
+  <properties>
+    <hudson.model.ParametersDefinitionProperty>
+      <parameterDefinitions>
+        <hudson.model.PasswordParameterDefinition>
+          <name>TOKEN</name>
+          <description></description>
+          <defaultValue>{AQAAABAAAAAQ}</defaultValue>
+        </hudson.model.PasswordParameterDefinition>
+      </parameterDefinitions>
+    </hudson.model.ParametersDefinitionProperty>
+  </properties>
+</flow-definition>`
+
+	testCases := map[string]struct {
+		jobCfg   string
+		expected map[string]string
+	}{
+		"generated": {jobCfg: jobCfg, expected: map[string]string{"TOKEN": "s3cr<t"}},
+		"saved":     {jobCfg: savedCfg, expected: map[string]string{"TOKEN": "{AQAAABAAAAAQ}"}},
+	}
+
+	for name, tc := range testCases {
+		defaults, err := pipeline.ParsePasswordDefaults(tc.jobCfg)
+		if err != nil {
+			t.Errorf("Case %s: fail to parse the password defaults as %s", name, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(defaults, tc.expected) {
+			t.Errorf("Case %s: expected the password defaults %v, but got %v", name, tc.expected, defaults)
+		}
+	}
+}
+
+func TestFullNameOfUrl(t *testing.T) {
+	testCases := map[string]struct {
+		url      string
+		expected string
+	}{
+		"root":       {url: "http://jenkins/job/service/", expected: "service"},
+		"namespaced": {url: "http://jenkins/job/team/job/service/", expected: "team/service"},
+		"escaped":    {url: "http://jenkins:8080/ci/job/team/job/order%20service/", expected: "team/order service"},
+		"not-job":    {url: "http://jenkins/view/all/", expected: ""},
+	}
+
+	for name, tc := range testCases {
+		fullName, err := pipeline.FullNameOfUrl(tc.url)
+		if len(tc.expected) == 0 {
+			if err == nil {
+				t.Errorf("Case %s: expected error, but got %s", name, fullName)
+			}
+			continue
+		}
+		if err != nil || fullName != tc.expected {
+			t.Errorf("Case %s: expected %s, but got %s and error %v", name, tc.expected, fullName, err)
 		}
 	}
 }
@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+// Platform decides how the steps are generated for the OS of the agent
+type Platform struct {
+	OS api.AgentOS
+	// The step to run the commands
+	ShellStep string
+	// The JDK homes of the supported versions
+	JdkPaths map[string]string
+	// The command to run Maven
+	MavenCommand string
+	// The command to run Gradle
+	GradleCommand string
+}
+
+var (
+	linuxPlatform = &Platform{
+		OS:            api.LINUX,
+		ShellStep:     "sh",
+		JdkPaths:      api.JDK_PATH,
+		MavenCommand:  "/opt/maven/latest/bin/mvn",
+		GradleCommand: "gradle",
+	}
+
+	windowsPlatform = &Platform{
+		OS:            api.WINDOWS,
+		ShellStep:     "bat",
+		JdkPaths:      api.WINDOWS_JDK_PATH,
+		MavenCommand:  `C:\maven\latest\bin\mvn.cmd`,
+		GradleCommand: "gradle.bat",
+	}
+)
+
+// platformOf Gets the platform of the pipeline, the batch projects run on Windows by default,
+// and the others run on Linux by default.
+func platformOf(pipeline *api.Pipeline) *Platform {
+	switch pipeline.OS {
+	case api.WINDOWS:
+		return windowsPlatform
+	case api.LINUX:
+		return linuxPlatform
+	}
+
+	if pipeline.ProjectType == api.BATCH {
+		return windowsPlatform
+	}
+	return linuxPlatform
+}
+
+// JdkHome Gets the JDK home to be put in a Groovy string
+func (platform *Platform) JdkHome(jdk string) string {
+	return escapeGroovyString(platform.JdkPaths[jdk])
+}
+
+// JdkBin Gets the bin dir of the JDK to be put in a Groovy string
+func (platform *Platform) JdkBin(jdk string) string {
+	separator := "/"
+	if platform.OS == api.WINDOWS {
+		separator = `\`
+	}
+	return escapeGroovyString(platform.JdkPaths[jdk] + separator + "bin")
+}
+
+// Command Gets the command to be put in a Groovy string, quoted if it contains spaces
+func (platform *Platform) Command(command string) string {
+	if strings.Contains(command, " ") {
+		command = `"` + command + `"`
+	}
+	return escapeGroovyGString(command)
+}
+
+// validateAgentOS Validates the agent OS against the project type
+func validateAgentOS(pipeline *api.Pipeline) bool {
+	switch pipeline.OS {
+	case "", api.LINUX, api.WINDOWS:
+	default:
+		log.Errorf("The agent OS %s is not supported, only supports %s and %s", pipeline.OS, api.LINUX, api.WINDOWS)
+		return false
+	}
+
+	platform := platformOf(pipeline)
+	if pipeline.ProjectType == api.BATCH && platform.OS != api.WINDOWS {
+		log.Errorf("The batch project can only run on the %s agents", api.WINDOWS)
+		return false
+	}
+	if pipeline.ProjectType == api.SHELL && platform.OS != api.LINUX {
+		log.Errorf("The shell project can only run on the %s agents, use the batch project for %s", api.LINUX, api.WINDOWS)
+		return false
+	}
+
+	// The JDKs of the matrix take place of the JDK of the pipeline
+	if pipeline.Matrix != nil && len(pipeline.Matrix.Jdks) > 0 {
+		return true
+	}
+	if _, ok := platform.JdkPaths[pipeline.Jdk]; !ok {
+		log.Errorf("The jdk version %s is not supported on %s", pipeline.Jdk, platform.OS)
+		return false
+	}
+
+	return true
+}
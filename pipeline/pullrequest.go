@@ -0,0 +1,261 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/reporter"
+)
+
+// pullRequestRefParameter is the ref of the pull request head to merge into the branch
+const pullRequestRefParameter = "pullRequestRef"
+
+// pullRequest is the pull request to build, Sha is the commit of its head
+type pullRequest struct {
+	ref          string
+	sha          string
+	targetBranch string
+}
+
+// newStatusReporters Creates the status reporters by their names in the config
+func newStatusReporters(cfgs map[string]*api.ReporterConfig) (map[string]reporter.StatusReporter, error) {
+	reporters := map[string]reporter.StatusReporter{}
+	for name, cfg := range cfgs {
+		r, err := reporter.NewStatusReporter(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("The status reporter %s is not correct as %s", name, err.Error())
+		}
+		reporters[name] = r
+	}
+
+	return reporters, nil
+}
+
+// generatePullRequestParameterTmpl Generates the parameter of the pull request ref for the pipelines in pull request mode
+func generatePullRequestParameterTmpl(pullRequests *api.PullRequests) string {
+	if pullRequests == nil {
+		return ""
+	}
+
+	return generateParametersTmpl([]*api.Parameter{
+		&api.Parameter{
+			Name:        pullRequestRefParameter,
+			Type:        api.STRING_PARAM,
+			Description: "The ref of the pull request to merge into the branch, set by goline for the pull request builds.",
+		},
+	})
+}
+
+// repoFullName Gets the full name of the repo from its url, such as owner/name
+// for https://github.com/owner/name.git and git@github.com:owner/name.git.
+func repoFullName(repoPath string) (string, error) {
+	fullName := ""
+	if u, err := url.Parse(repoPath); err == nil && len(u.Host) > 0 {
+		fullName = u.Path
+	} else if i := strings.Index(repoPath, ":"); i >= 0 {
+		// The scp-like syntax of SSH
+		fullName = repoPath[i+1:]
+	}
+
+	fullName = strings.TrimSuffix(strings.Trim(fullName, "/"), ".git")
+	if !strings.Contains(fullName, "/") {
+		return "", fmt.Errorf("Can not get the repo full name from %s", repoPath)
+	}
+	return fullName, nil
+}
+
+// buildUrl Gets the url of the build status endpoint of goline
+func (mgr *Manager) buildUrl(plName string, number int64) string {
+	prefix := ""
+	name := plName
+	if i := strings.LastIndex(plName, "/"); i >= 0 {
+		prefix = "/namespaces/" + plName[:i]
+		name = plName[i+1:]
+	}
+
+	return fmt.Sprintf("%s%s/pipelines/builds/%s/%d", mgr.externalUrl, prefix, name, number)
+}
+
+// statusContext Gets the name of the commit status of the pipeline
+func statusContext(pl *api.Pipeline) string {
+	if len(pl.PullRequests.Context) > 0 {
+		return pl.PullRequests.Context
+	}
+	return "goline/" + fullNameOf(pl)
+}
+
+// reportStatus Reports the commit status of the pipeline by its status reporter
+func (mgr *Manager) reportStatus(pl *api.Pipeline, sha string, state api.CommitState, description, targetUrl string) error {
+	r, ok := mgr.reporters[pl.PullRequests.Reporter]
+	if !ok {
+		return fmt.Errorf("The status reporter %s is not configured", pl.PullRequests.Reporter)
+	}
+
+	repo, err := repoFullName(pl.Repo.RepoPath)
+	if err != nil {
+		return err
+	}
+
+	return r.Report(&api.CommitStatus{
+		Repo:        repo,
+		Sha:         sha,
+		State:       state,
+		Context:     statusContext(pl),
+		Description: description,
+		TargetUrl:   targetUrl,
+	})
+}
+
+// buildPullRequest Performs the pipelines in pull request mode of the repo, whose branches are the target
+// branch of the pull request, and reports the pending status of the pull request head.
+func (mgr *Manager) buildPullRequest(repoUrls []string, pr *pullRequest) error {
+	names, err := mgr.store.List(pipelineKind)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		pl, err := getPipeline(mgr.store, name)
+		if err != nil {
+			return err
+		}
+		if pl.PullRequests == nil || pl.Repo.Branch != pr.targetBranch || !matchRepoUrl(pl.Repo.RepoPath, repoUrls) {
+			continue
+		}
+
+		log.Infof("Build the pull request %s of pipeline %s", pr.ref, name)
+		params := &api.PerformParams{
+			Branch:   pr.targetBranch,
+			Revision: pr.sha,
+			Params:   map[string]string{pullRequestRefParameter: pr.ref},
+		}
+		if err = mgr.Perform(name, params); err != nil {
+			return fmt.Errorf("Fail to build the pull request %s of pipeline %s as %s", pr.ref, name, err.Error())
+		}
+
+		if err = mgr.reportStatus(pl, pr.sha, api.COMMIT_PENDING, "The build is queued", ""); err != nil {
+			log.Warnf("Fail to report the status of pull request %s of pipeline %s as %s", pr.ref, name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ReportPullRequestStatus Reports the commit status of the pull request when its build is started or completed,
+// the builds not for pull requests are ignored.
+func (mgr *Manager) ReportPullRequestStatus(event *api.BuildEvent) {
+	if event.Type != api.BUILD_STARTED && event.Type != api.BUILD_COMPLETED {
+		return
+	}
+
+	pl, err := getPipeline(mgr.store, event.Pipeline)
+	if err != nil || pl.PullRequests == nil {
+		return
+	}
+
+	// The pull request of the build is known by its parameters
+	getter, ok := mgr.backend.(BuildParameterGetter)
+	if !ok {
+		return
+	}
+	params, err := getter.GetBuildParameters(event.Pipeline, event.Build)
+	if err != nil {
+		log.Warnf("Fail to report the status of build %d of pipeline %s as %s", event.Build, event.Pipeline, err.Error())
+		return
+	}
+	if len(params[pullRequestRefParameter]) == 0 {
+		return
+	}
+
+	state, description := api.CommitState(api.COMMIT_RUNNING), "The build is running"
+	if event.Type == api.BUILD_COMPLETED {
+		state, description = commitStateOf(event.Result), "The build result is "+event.Result
+	}
+
+	err = mgr.reportStatus(pl, params["revision"], state, description, mgr.buildUrl(event.Pipeline, event.Build))
+	if err != nil {
+		log.Warnf("Fail to report the status of build %d of pipeline %s as %s", event.Build, event.Pipeline, err.Error())
+	}
+}
+
+// commitStateOf Gets the commit state of the build result, the aborted builds are errors
+func commitStateOf(result string) api.CommitState {
+	switch api.BuildResult(result) {
+	case api.SUCCESS:
+		return api.COMMIT_SUCCESS
+	case api.UNSTABLE, api.FAILURE:
+		return api.COMMIT_FAILURE
+	default:
+		return api.COMMIT_ERROR
+	}
+}
+
+// GetBuildParameters Gets the parameters of the build
+func (backend *JenkinsBackend) GetBuildParameters(plName string, number int64) (map[string]string, error) {
+	job, err := backend.getJob(plName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := struct {
+		Actions []struct {
+			Parameters []struct {
+				Name  string      `json:"name"`
+				Value interface{} `json:"value"`
+			} `json:"parameters"`
+		} `json:"actions"`
+	}{}
+
+	querystring := map[string]string{
+		"tree": "actions[parameters[name,value]]",
+	}
+	_, err = backend.Jenkins.Requester.GetJSON(job.Base+"/"+strconv.FormatInt(number, 10), &result, querystring)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to get the parameters of build %d as %s", number, err.Error())
+	}
+
+	params := map[string]string{}
+	for _, action := range result.Actions {
+		for _, param := range action.Parameters {
+			params[param.Name] = fmt.Sprint(param.Value)
+		}
+	}
+
+	return params, nil
+}
+
+// checkReporter Checks the status reporter of the pipeline in pull request mode is configured
+func (mgr *Manager) checkReporter(pl *api.Pipeline) error {
+	if pl.PullRequests == nil {
+		return nil
+	}
+
+	if _, ok := mgr.reporters[pl.PullRequests.Reporter]; !ok {
+		return fmt.Errorf("The status reporter %s is not configured", pl.PullRequests.Reporter)
+	}
+	return nil
+}
+
+// validatePullRequests Validates the pull request mode, which only supports the Git repos
+func validatePullRequests(pipeline *api.Pipeline) bool {
+	if pipeline.Repo.Type != "" && pipeline.Repo.Type != api.GIT {
+		log.Errorln("The pull request mode only supports the Git repos")
+		return false
+	}
+
+	if len(pipeline.PullRequests.Reporter) == 0 {
+		log.Errorln("The status reporter of the pull request mode is empty")
+		return false
+	}
+
+	if _, err := repoFullName(pipeline.Repo.RepoPath); err != nil {
+		log.Errorln(err.Error())
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,78 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+// generateBuildDiscarderTmpl Generates the build discarder property, empty if no retention
+func generateBuildDiscarderTmpl(retention *api.Retention) string {
+	if retention == nil {
+		return ""
+	}
+
+	return strings.NewReplacer("${retention.days}", retentionLimit(retention.DaysToKeep),
+		"${retention.num}", retentionLimit(retention.NumToKeep),
+		"${retention.artifact.days}", retentionLimit(retention.ArtifactDaysToKeep),
+		"${retention.artifact.num}", retentionLimit(retention.ArtifactNumToKeep)).Replace(BUILD_DISCARDER_TEMPLATE)
+}
+
+// retentionLimit Converts the limit to the one of Jenkins, which uses -1 as no limit
+func retentionLimit(limit int) string {
+	if limit == 0 {
+		return "-1"
+	}
+	return strconv.Itoa(limit)
+}
+
+// validateRetention Validates the limits of the retention
+func validateRetention(retention *api.Retention) bool {
+	if retention.DaysToKeep < 0 || retention.NumToKeep < 0 ||
+		retention.ArtifactDaysToKeep < 0 || retention.ArtifactNumToKeep < 0 {
+		log.Errorf("The retention limits %+v should not be negative", *retention)
+		return false
+	}
+
+	return true
+}
+
+// GetDiskUsages Gets the disk usages of the pipelines managed by goline in Jenkins
+func (backend *JenkinsBackend) GetDiskUsages() ([]*api.DiskUsage, error) {
+	names, err := backend.store.List(pipelineKind)
+	if err != nil {
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	usages := []*api.DiskUsage{}
+	if len(names) == 0 {
+		return usages, nil
+	}
+
+	// Sum up the sizes of the pipeline dirs by the script console, as Jenkins has no API for them
+	quoted := []string{}
+	for _, name := range names {
+		quoted = append(quoted, "'"+escapeGroovyString(name)+"'")
+	}
+	script := strings.Replace(DISK_USAGE_SCRIPT, "${disk.usage.pipelines}", strings.Join(quoted, ", "), 1)
+
+	resp, err := backend.Jenkins.Requester.Post("/scriptText", strings.NewReader("script="+url.QueryEscape(script)), &usages, nil)
+	if err != nil {
+		err = fmt.Errorf("Fail to get the disk usages as %s", err.Error())
+		log.Errorln(err.Error())
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("Fail to get the disk usages as %s", resp.Status)
+		log.Errorln(err.Error())
+		return nil, err
+	}
+
+	return usages, nil
+}
@@ -0,0 +1,216 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+var (
+	svnUrlPattern      = regexp.MustCompile(`^(svn|svn\+ssh|http|https|file)://`)
+	svnRevisionPattern = regexp.MustCompile(`^([0-9]+|HEAD)$`)
+	gitCommitPattern   = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	gitTagPattern      = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.+/-]*$`)
+)
+
+type SCMGenerator interface {
+	GenerateCheckout() string
+}
+
+// newSCMGenerator Creates the SCM generator according to the repo type,
+// only Git supports to check out the pull requests.
+func newSCMGenerator(repo *api.Repo, credentialId string, platform *Platform, pullRequests bool) (SCMGenerator, error) {
+	switch repo.Type {
+	case "", api.GIT:
+		return &GitSCMGenerator{repo, credentialId, platform, pullRequests}, nil
+	case api.SVN:
+		return &SvnSCMGenerator{repo, credentialId}, nil
+	case api.MERCURIAL:
+		return &HgSCMGenerator{repo, credentialId}, nil
+	default:
+		return nil, fmt.Errorf("The repo type %s is not supported", repo.Type)
+	}
+}
+
+type GitSCMGenerator struct {
+	Repo         *api.Repo
+	CredentialId string
+	Platform     *Platform
+	PullRequests bool
+}
+
+func (generator *GitSCMGenerator) GenerateCheckout() string {
+	repo := generator.Repo
+
+	extensions := []string{}
+	if repo.Depth > 0 {
+		extensions = append(extensions, strings.Replace(GIT_CLONE_EXTENSION, "${git.depth}", strconv.Itoa(repo.Depth), 1))
+	}
+	if repo.Submodules {
+		extensions = append(extensions, GIT_SUBMODULE_EXTENSION)
+	}
+	if len(repo.SparsePaths) > 0 {
+		paths := []string{}
+		for _, path := range repo.SparsePaths {
+			paths = append(paths, fmt.Sprintf("[path: '%s']", escapeGroovyString(path)))
+		}
+		extensions = append(extensions, strings.Replace(GIT_SPARSE_CHECKOUT_EXTENSION, "${git.sparse.paths}", strings.Join(paths, ", "), 1))
+	}
+	if repo.Lfs {
+		extensions = append(extensions, GIT_LFS_EXTENSION)
+	}
+	if repo.Clean {
+		extensions = append(extensions, GIT_CLEAN_EXTENSION)
+	}
+
+	checkout := strings.NewReplacer("${jenkins.credentialId}", generator.CredentialId,
+		"${project.repoPath}", repo.RepoPath,
+		"${platform.shell}", generator.Platform.ShellStep,
+		"${git.extensions}", strings.Join(extensions, ", ")).Replace(GIT_CHECKOUT_TEMPLATE)
+	if !generator.PullRequests {
+		return checkout
+	}
+
+	// The pull request builds check out the head of the pull request, and merge it into the branch
+	extensions = append(extensions, GIT_MERGE_EXTENSION)
+	return strings.NewReplacer("${jenkins.credentialId}", generator.CredentialId,
+		"${project.repoPath}", repo.RepoPath,
+		"${git.extensions}", strings.Join(extensions, ", "),
+		"${git.checkout}", checkout).Replace(GIT_PULL_REQUEST_CHECKOUT_TEMPLATE)
+}
+
+type SvnSCMGenerator struct {
+	Repo         *api.Repo
+	CredentialId string
+}
+
+func (generator *SvnSCMGenerator) GenerateCheckout() string {
+	repo := generator.Repo
+
+	// The branch can be changed by the branch param when performs
+	remote := strings.TrimSuffix(repo.RepoPath, "/")
+	if len(repo.Branch) > 0 {
+		remote += "/$branch"
+	}
+
+	return strings.NewReplacer("${jenkins.credentialId}", generator.CredentialId,
+		"${svn.ignore.externals}", strconv.FormatBool(!repo.Externals),
+		"${svn.remote}", remote).Replace(SVN_CHECKOUT_TEMPLATE)
+}
+
+type HgSCMGenerator struct {
+	Repo         *api.Repo
+	CredentialId string
+}
+
+func (generator *HgSCMGenerator) GenerateCheckout() string {
+	return strings.NewReplacer("${jenkins.credentialId}", generator.CredentialId,
+		"${project.repoPath}", generator.Repo.RepoPath).Replace(HG_CHECKOUT_TEMPLATE)
+}
+
+// defaultRevision Gets the default revision to check out, empty means the branch head.
+func defaultRevision(repo *api.Repo) string {
+	if len(repo.Tag) > 0 {
+		return repo.Tag
+	}
+	return repo.Revision
+}
+
+// validateRevision Validates the revision to check out when performs the pipeline, which must be reachable
+// in the clone of the repo unless it is fetched by its ref, such as the head of the pull request.
+func validateRevision(repo *api.Repo, revision string, fetched bool) error {
+	switch repo.Type {
+	case "", api.GIT:
+		// The tags are checked out by their names, the same as the tag of the repo
+		if !gitCommitPattern.MatchString(revision) && !gitTagPattern.MatchString(revision) {
+			return fmt.Errorf("The git revision %s is neither a commit SHA nor a tag", revision)
+		}
+		if repo.Depth > 0 && !fetched {
+			return fmt.Errorf("The revision %s can not be checked out from the shallow clone of depth %d", revision, repo.Depth)
+		}
+	case api.SVN:
+		if !svnRevisionPattern.MatchString(revision) {
+			return fmt.Errorf("The SVN revision %s should be a number or HEAD", revision)
+		}
+	}
+
+	return nil
+}
+
+// validateRepo Validates the source code repo according to its type.
+func validateRepo(repo *api.Repo) bool {
+	// TODO (robin) Check the repo path and branch pattern
+	// The repo path is passed to the scm commands, it can not be taken as an option
+	if strings.HasPrefix(repo.RepoPath, "-") {
+		log.Errorf("The source code repo path %s should not start with -", repo.RepoPath)
+		return false
+	}
+
+	switch repo.Type {
+	case "", api.GIT:
+		if len(repo.RepoPath) == 0 || len(repo.Branch) == 0 {
+			log.Errorln("The source code repo path or branch is empty")
+			return false
+		}
+		if len(repo.Revision) > 0 && !gitCommitPattern.MatchString(repo.Revision) {
+			log.Errorf("The git revision %s is not a commit SHA", repo.Revision)
+			return false
+		}
+		if len(repo.Tag) > 0 && !gitTagPattern.MatchString(repo.Tag) {
+			log.Errorf("The git tag %s is not a valid tag name", repo.Tag)
+			return false
+		}
+		if len(repo.Revision) > 0 && len(repo.Tag) > 0 {
+			log.Errorln("Only one of the git revision and tag can be specified")
+			return false
+		}
+		if repo.Depth < 0 {
+			log.Errorf("The clone depth %d should not be negative", repo.Depth)
+			return false
+		}
+		if repo.Depth > 0 && len(defaultRevision(repo)) > 0 {
+			log.Errorf("The revision or tag can not be checked out from the shallow clone of depth %d", repo.Depth)
+			return false
+		}
+		for _, path := range repo.SparsePaths {
+			if len(strings.TrimSpace(path)) == 0 {
+				log.Errorln("The sparse checkout path is empty")
+				return false
+			}
+		}
+	case api.MERCURIAL:
+		if len(repo.RepoPath) == 0 || len(repo.Branch) == 0 {
+			log.Errorln("The source code repo path or branch is empty")
+			return false
+		}
+	case api.SVN:
+		if !svnUrlPattern.MatchString(repo.RepoPath) {
+			log.Errorf("The SVN repo url %s is not correct", repo.RepoPath)
+			return false
+		}
+		if len(repo.Revision) > 0 && !svnRevisionPattern.MatchString(repo.Revision) {
+			log.Errorf("The SVN revision %s should be a number or HEAD", repo.Revision)
+			return false
+		}
+	default:
+		log.Errorf("The repo type %s is not supported", repo.Type)
+		return false
+	}
+
+	if repo.Type != api.SVN && repo.Externals {
+		log.Errorln("The externals is only supported by SVN repo")
+		return false
+	}
+
+	isGit := repo.Type == "" || repo.Type == api.GIT
+	if !isGit && (len(repo.Tag) > 0 || repo.Depth > 0 || repo.Submodules || len(repo.SparsePaths) > 0 || repo.Lfs || repo.Clean) {
+		log.Errorf("The tag and clone options are only supported by git repo, not %s repo", repo.Type)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,253 @@
+package pipeline
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+)
+
+// The headers of the webhook events of GitHub, GitLab and Gitea
+const (
+	GITHUB_EVENT_HEADER = "X-GitHub-Event"
+	GITLAB_EVENT_HEADER = "X-Gitlab-Event"
+	GITEA_EVENT_HEADER  = "X-Gitea-Event"
+)
+
+// The headers of the webhook signatures of GitHub, GitLab and Gitea
+const (
+	GITHUB_SIGNATURE_HEADER = "X-Hub-Signature-256"
+	GITLAB_TOKEN_HEADER     = "X-Gitlab-Token"
+	GITEA_SIGNATURE_HEADER  = "X-Gitea-Signature"
+)
+
+// scmEventKind is the kind of the event in the source code repo
+type scmEventKind string
+
+const (
+	pushEvent        scmEventKind = "push"
+	pullRequestEvent scmEventKind = "pull_request"
+)
+
+// scmEvent is the webhook event of the source code repo, sent by GitHub, GitLab or Gitea.
+// PullRequest is only for the pull request events.
+type scmEvent struct {
+	kind scmEventKind
+	// The urls of the repo, such as the HTTP and the SSH clone urls
+	repoUrls    []string
+	pullRequest *pullRequest
+}
+
+// scmRepository is the repo in the webhook payloads, with the url fields of GitHub, GitLab and Gitea
+type scmRepository struct {
+	CloneUrl   string `json:"clone_url"`
+	SshUrl     string `json:"ssh_url"`
+	GitUrl     string `json:"git_url"`
+	HtmlUrl    string `json:"html_url"`
+	GitHttpUrl string `json:"git_http_url"`
+	GitSshUrl  string `json:"git_ssh_url"`
+	WebUrl     string `json:"web_url"`
+}
+
+// scmPullRequest is the pull request of GitHub and Gitea
+type scmPullRequest struct {
+	Number int64 `json:"number"`
+	Head   struct {
+		Sha string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// scmMergeRequest is the merge request of GitLab, Oldrev is only for the updates pushing commits
+type scmMergeRequest struct {
+	Iid          int64  `json:"iid"`
+	Action       string `json:"action"`
+	TargetBranch string `json:"target_branch"`
+	Oldrev       string `json:"oldrev"`
+	LastCommit   struct {
+		Id string `json:"id"`
+	} `json:"last_commit"`
+}
+
+// scmPayload is the webhook payload, GitLab names the repo as the project
+type scmPayload struct {
+	Action           string           `json:"action"`
+	Repository       *scmRepository   `json:"repository"`
+	Project          *scmRepository   `json:"project"`
+	PullRequest      *scmPullRequest  `json:"pull_request"`
+	ObjectAttributes *scmMergeRequest `json:"object_attributes"`
+}
+
+// scmEventProvider Gets the provider and the name of the webhook event by its headers.
+// Gitea also sends the GitHub headers, so it is told apart first.
+func scmEventProvider(header http.Header) (api.ReporterType, string, error) {
+	switch {
+	case len(header.Get(GITEA_EVENT_HEADER)) > 0:
+		return api.GITEA, header.Get(GITEA_EVENT_HEADER), nil
+	case len(header.Get(GITHUB_EVENT_HEADER)) > 0:
+		return api.GITHUB, header.Get(GITHUB_EVENT_HEADER), nil
+	case len(header.Get(GITLAB_EVENT_HEADER)) > 0:
+		return api.GITLAB, header.Get(GITLAB_EVENT_HEADER), nil
+	default:
+		return "", "", fmt.Errorf("The event is not from GitHub, GitLab or Gitea")
+	}
+}
+
+// verifyScmEvent Verifies the webhook event is sent by the webhook configured with the secret.
+// GitHub and Gitea sign the payload by HMAC-SHA256 with the secret, and GitLab sends the secret as the token.
+func verifyScmEvent(header http.Header, body []byte, secret string) error {
+	if len(secret) == 0 {
+		return fmt.Errorf("The secret of the scm events is not configured")
+	}
+
+	provider, _, err := scmEventProvider(header)
+	if err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	verified := false
+	switch provider {
+	case api.GITHUB:
+		verified = hmac.Equal([]byte(header.Get(GITHUB_SIGNATURE_HEADER)), []byte("sha256="+signature))
+	case api.GITEA:
+		verified = hmac.Equal([]byte(header.Get(GITEA_SIGNATURE_HEADER)), []byte(signature))
+	case api.GITLAB:
+		verified = subtle.ConstantTimeCompare([]byte(header.Get(GITLAB_TOKEN_HEADER)), []byte(secret)) == 1
+	}
+	if !verified {
+		return fmt.Errorf("The event of %s is not signed with the secret of the scm events", provider)
+	}
+
+	return nil
+}
+
+// parseScmEvent Parses the webhook event by its headers and payload, returns nil for the events not concerned
+func parseScmEvent(header http.Header, body []byte) (*scmEvent, error) {
+	provider, name, err := scmEventProvider(header)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &scmPayload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, fmt.Errorf("Fail to parse the event payload as %s", err.Error())
+	}
+
+	event := &scmEvent{}
+	switch provider {
+	case api.GITHUB, api.GITEA:
+		switch name {
+		// The branches are changed by the push, create and delete events
+		case "push", "create", "delete":
+			event.kind = pushEvent
+		case "pull_request":
+			// The pull requests are built when opened and pushed
+			switch payload.Action {
+			case "opened", "reopened", "synchronize", "synchronized":
+				if pr := payload.PullRequest; pr != nil {
+					event.kind = pullRequestEvent
+					event.pullRequest = &pullRequest{
+						ref:          fmt.Sprintf("refs/pull/%d/head", pr.Number),
+						sha:          pr.Head.Sha,
+						targetBranch: pr.Base.Ref,
+					}
+				}
+			}
+		}
+	case api.GITLAB:
+		switch name {
+		case "Push Hook":
+			event.kind = pushEvent
+		case "Merge Request Hook":
+			mr := payload.ObjectAttributes
+			if mr != nil && (mr.Action == "open" || mr.Action == "reopen" || (mr.Action == "update" && len(mr.Oldrev) > 0)) {
+				event.kind = pullRequestEvent
+				event.pullRequest = &pullRequest{
+					ref:          fmt.Sprintf("refs/merge-requests/%d/head", mr.Iid),
+					sha:          mr.LastCommit.Id,
+					targetBranch: mr.TargetBranch,
+				}
+			}
+		}
+	}
+	if len(event.kind) == 0 {
+		return nil, nil
+	}
+
+	for _, repo := range []*scmRepository{payload.Repository, payload.Project} {
+		if repo == nil {
+			continue
+		}
+		for _, repoUrl := range []string{repo.CloneUrl, repo.SshUrl, repo.GitUrl, repo.HtmlUrl, repo.GitHttpUrl, repo.GitSshUrl, repo.WebUrl} {
+			if len(repoUrl) > 0 {
+				event.repoUrls = append(event.repoUrls, repoUrl)
+			}
+		}
+	}
+
+	return event, nil
+}
+
+// matchRepoUrl Judges whether the repo path is one of the repo urls, ignoring the .git suffix and the case
+func matchRepoUrl(repoPath string, repoUrls []string) bool {
+	normalize := func(repoUrl string) string {
+		return strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(repoUrl, "/"), ".git"))
+	}
+
+	for _, repoUrl := range repoUrls {
+		if normalize(repoPath) == normalize(repoUrl) {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleScmEvent Handles the webhook event of the source code repo, which must be signed with the secret
+// of the scm events. The branches of the fan-out pipelines are discovered immediately when the branches
+// of their repos are changed, and the pull requests are built by the pipelines in pull request mode.
+// The event is handled in the background after it is verified, so the failures are only logged.
+func (mgr *Manager) HandleScmEvent(header http.Header, body []byte) error {
+	err := verifyScmEvent(header, body, mgr.scmEventSecret)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+
+	event, err := parseScmEvent(header, body)
+	if err != nil {
+		log.Errorln(err.Error())
+		return err
+	}
+	if event == nil {
+		log.Infoln("Ignore the event not concerned")
+		return nil
+	}
+
+	go func() {
+		var err error
+		switch event.kind {
+		case pushEvent:
+			err = mgr.discoverRepoBranches(event.repoUrls)
+		case pullRequestEvent:
+			err = mgr.buildPullRequest(event.repoUrls, event.pullRequest)
+		}
+		if err != nil {
+			log.Errorf("Fail to handle the %s event as %s", event.kind, err.Error())
+		}
+	}()
+
+	return nil
+}
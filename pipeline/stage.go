@@ -20,6 +20,7 @@ func (generator *MavenPiplineStageGenerator) GenerateCompileStage() string {
 	project := generator.ProjectConfig
 
 	stageTmpl := strings.NewReplacer("${maven.rootpom}", project.RootPom,
+		"${mvn.cache.options}", generateCacheOptions(project.Cache, api.MAVEN),
 		"${mvn.options}", project.Options).Replace(MAVEN_COMPILE_STAGE)
 
 	return stageTmpl
@@ -30,6 +31,7 @@ func (generator *MavenPiplineStageGenerator) GenerateUnitTestStage() string {
 	stage := project.UnitTest
 
 	stageTmpl := strings.NewReplacer("${maven.rootpom}", project.RootPom,
+		"${mvn.cache.options}", generateCacheOptions(project.Cache, api.MAVEN),
 		"${mvn.options}", project.Options,
 		"${test.report.path}", stage.TestReportPath).Replace(MAVEN_UNIT_TEST_STAGE)
 
@@ -40,6 +42,7 @@ func (generator *MavenPiplineStageGenerator) GenerateBuildStage() string {
 	project := generator.ProjectConfig
 
 	stageTmpl := strings.NewReplacer("${maven.rootpom}", project.RootPom,
+		"${mvn.cache.options}", generateCacheOptions(project.Cache, api.MAVEN),
 		"${mvn.options}", project.Options).Replace(MAVEN_BUILD_STAGE)
 
 	return stageTmpl
@@ -47,12 +50,16 @@ func (generator *MavenPiplineStageGenerator) GenerateBuildStage() string {
 
 type GradlePiplineStageGenerator struct {
 	ProjectConfig api.GradleProject
+	Platform      *Platform
 }
 
 func (generator *GradlePiplineStageGenerator) GenerateCompileStage() string {
 	project := generator.ProjectConfig
 
-	stageTmpl := strings.Replace(GRADLE_COMPILE_STAGE, "${gradle.gradleOpts}", project.Options, 1)
+	stageTmpl := strings.NewReplacer("${platform.shell}", generator.Platform.ShellStep,
+		"${gradle.command}", generator.Platform.Command(generator.Platform.GradleCommand),
+		"${gradle.cache.options}", generateCacheOptions(project.Cache, api.GRADLE),
+		"${gradle.gradleOpts}", project.Options).Replace(GRADLE_COMPILE_STAGE)
 
 	return stageTmpl
 }
@@ -61,7 +68,10 @@ func (generator *GradlePiplineStageGenerator) GenerateUnitTestStage() string {
 	project := generator.ProjectConfig
 	stage := project.UnitTest
 
-	stageTmpl := strings.NewReplacer("${gradle.gradleOpts}", project.Options,
+	stageTmpl := strings.NewReplacer("${platform.shell}", generator.Platform.ShellStep,
+		"${gradle.command}", generator.Platform.Command(generator.Platform.GradleCommand),
+		"${gradle.cache.options}", generateCacheOptions(project.Cache, api.GRADLE),
+		"${gradle.gradleOpts}", project.Options,
 		"${test.report.path}", stage.TestReportPath).Replace(GRADLE_UNIT_TEST_STAGE)
 
 	return stageTmpl
@@ -70,26 +80,25 @@ func (generator *GradlePiplineStageGenerator) GenerateUnitTestStage() string {
 func (generator *GradlePiplineStageGenerator) GenerateBuildStage() string {
 	project := generator.ProjectConfig
 
-	stageTmpl := strings.Replace(GRADLE_BUILD_STAGE, "${gradle.gradleOpts}", project.Options, 1)
+	stageTmpl := strings.NewReplacer("${platform.shell}", generator.Platform.ShellStep,
+		"${gradle.command}", generator.Platform.Command(generator.Platform.GradleCommand),
+		"${gradle.cache.options}", generateCacheOptions(project.Cache, api.GRADLE),
+		"${gradle.gradleOpts}", project.Options).Replace(GRADLE_BUILD_STAGE)
 
 	return stageTmpl
 }
 
 type ScriptPiplineStageGenerator struct {
 	ProjectConfig api.ScriptProject
-	ProjectType   api.ProjectType
+	Platform      *Platform
 }
 
 func (generator *ScriptPiplineStageGenerator) GenerateCompileStage() string {
 	project := generator.ProjectConfig
 	stage := project.Compile
 
-	stageTmpl := SCRIPT_COMPILE_STAGE
-	if generator.ProjectType == api.BATCH {
-		stageTmpl = strings.Replace(stageTmpl, "sh", "bat", 1)
-	}
-
-	stageTmpl = strings.NewReplacer("${script.compile.command}", stage.Command).Replace(stageTmpl)
+	stageTmpl := strings.NewReplacer("${platform.shell}", generator.Platform.ShellStep,
+		"${script.compile.command}", stage.Command).Replace(SCRIPT_COMPILE_STAGE)
 
 	return stageTmpl
 }
@@ -98,13 +107,9 @@ func (generator *ScriptPiplineStageGenerator) GenerateUnitTestStage() string {
 	project := generator.ProjectConfig
 	stage := project.UnitTest
 
-	stageTmpl := SCRIPT_UNIT_TEST_STAGE
-	if generator.ProjectType == api.BATCH {
-		stageTmpl = strings.Replace(stageTmpl, "sh", "bat", 1)
-	}
-
-	stageTmpl = strings.NewReplacer("${script.ut.command}", stage.Command,
-		"${test.report.path}", stage.TestReportPath).Replace(stageTmpl)
+	stageTmpl := strings.NewReplacer("${platform.shell}", generator.Platform.ShellStep,
+		"${script.ut.command}", stage.Command,
+		"${test.report.path}", stage.TestReportPath).Replace(SCRIPT_UNIT_TEST_STAGE)
 
 	return stageTmpl
 }
@@ -113,12 +118,8 @@ func (generator *ScriptPiplineStageGenerator) GenerateBuildStage() string {
 	project := generator.ProjectConfig
 	stage := project.Build
 
-	stageTmpl := SCRIPT_BUILD_STAGE
-	if generator.ProjectType == api.BATCH {
-		stageTmpl = strings.Replace(stageTmpl, "sh", "bat", 1)
-	}
-
-	stageTmpl = strings.NewReplacer("${script.build.command}", stage.Command).Replace(stageTmpl)
+	stageTmpl := strings.NewReplacer("${platform.shell}", generator.Platform.ShellStep,
+		"${script.build.command}", stage.Command).Replace(SCRIPT_BUILD_STAGE)
 
 	return stageTmpl
 }
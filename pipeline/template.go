@@ -6,21 +6,21 @@ const (
   <actions/>
   <description>Pipeline to compile, unit test, build and deploy.</description>
   <keepDependencies>false</keepDependencies>
-  <properties>
+  <properties>${pipeline.build.discarder}${pipeline.concurrency}
     <jenkins.plugins.slack.SlackNotifier_-SlackJobProperty plugin="slack@1.8">
-      <teamDomain></teamDomain>
+      <teamDomain>${slack.team.domain}</teamDomain>
       <token></token>
-      <room></room>
-      <startNotification>false</startNotification>
-      <notifySuccess>false</notifySuccess>
+      <room>${slack.room}</room>
+      <startNotification>${slack.notify.start}</startNotification>
+      <notifySuccess>${slack.notify.success}</notifySuccess>
       <notifyAborted>false</notifyAborted>
       <notifyNotBuilt>false</notifyNotBuilt>
-      <notifyUnstable>false</notifyUnstable>
-      <notifyFailure>false</notifyFailure>
-      <notifyBackToNormal>false</notifyBackToNormal>
+      <notifyUnstable>${slack.notify.unstable}</notifyUnstable>
+      <notifyFailure>${slack.notify.failure}</notifyFailure>
+      <notifyBackToNormal>${slack.notify.fixed}</notifyBackToNormal>
       <notifyRepeatedFailure>false</notifyRepeatedFailure>
-      <includeTestSummary>false</includeTestSummary>
-      <showCommitList>false</showCommitList>
+      <includeTestSummary>${slack.include.test.summary}</includeTestSummary>
+      <showCommitList>${slack.show.commit.list}</showCommitList>
       <includeCustomMessage>false</includeCustomMessage>
       <customMessage></customMessage>
     </jenkins.plugins.slack.SlackNotifier_-SlackJobProperty>
@@ -31,11 +31,16 @@ const (
           <description>The srouce code branch.</description>
           <defaultValue>${project.branch}</defaultValue>
         </hudson.model.StringParameterDefinition>
+        <hudson.model.StringParameterDefinition>
+          <name>revision</name>
+          <description>The revision to check out instead of the branch head.</description>
+          <defaultValue>${project.revision}</defaultValue>
+        </hudson.model.StringParameterDefinition>
         <hudson.model.StringParameterDefinition>
           <name>performPhases</name>
           <description>The phases to be performed.</description>
           <defaultValue>${pipeline.perform.phases}</defaultValue>
-        </hudson.model.StringParameterDefinition>
+        </hudson.model.StringParameterDefinition>${pipeline.parameters}
       </parameterDefinitions>
     </hudson.model.ParametersDefinitionProperty>
     <org.jenkinsci.plugins.workflow.job.properties.PipelineTriggersJobProperty>
@@ -49,24 +54,193 @@ const (
   <triggers/>
 </flow-definition>`
 
-	PIPELINE_TRIGGERS_TEMPLATE = `<triggers>
+	LIST_VIEW_TEMPLATE = `<?xml version='1.0' encoding='UTF-8'?>
+<hudson.model.ListView>
+  <name>${view.name}</name>
+  <description>The pipelines tagged ${view.name}, managed by goline.</description>
+  <filterExecutors>false</filterExecutors>
+  <filterQueue>false</filterQueue>
+  <properties class="hudson.model.View$PropertyList"/>
+  <jobNames>
+    <comparator class="hudson.util.CaseInsensitiveComparator"/>
+  </jobNames>
+  <jobFilters/>
+  <columns>
+    <hudson.views.StatusColumn/>
+    <hudson.views.WeatherColumn/>
+    <hudson.views.JobColumn/>
+    <hudson.views.LastSuccessColumn/>
+    <hudson.views.LastFailureColumn/>
+    <hudson.views.LastDurationColumn/>
+    <hudson.views.BuildButtonColumn/>
+  </columns>
+  <recurse>true</recurse>
+</hudson.model.ListView>`
+
+	FOLDER_TEMPLATE = `<?xml version='1.0' encoding='UTF-8'?>
+<com.cloudbees.hudson.plugins.folder.Folder plugin="cloudbees-folder@5.12">
+  <actions/>
+  <description>The pipelines of namespace ${namespace.name}, created by goline.</description>
+  <properties/>
+  <views>
+    <hudson.model.AllView>
+      <owner class="com.cloudbees.hudson.plugins.folder.Folder" reference="../../.."/>
+      <name>All</name>
+      <filterExecutors>false</filterExecutors>
+      <filterQueue>false</filterQueue>
+      <properties class="hudson.model.View$PropertyList"/>
+    </hudson.model.AllView>
+  </views>
+  <viewsTabBar class="hudson.views.DefaultViewsTabBar"/>
+  <healthMetrics/>
+  <icon class="com.cloudbees.hudson.plugins.folder.icons.StockFolderIcon"/>
+</com.cloudbees.hudson.plugins.folder.Folder>`
+
+	BUILD_DISCARDER_TEMPLATE = `
+    <jenkins.model.BuildDiscarderProperty>
+      <strategy class="hudson.tasks.LogRotator">
+        <daysToKeep>${retention.days}</daysToKeep>
+        <numToKeep>${retention.num}</numToKeep>
+        <artifactDaysToKeep>${retention.artifact.days}</artifactDaysToKeep>
+        <artifactNumToKeep>${retention.artifact.num}</artifactNumToKeep>
+      </strategy>
+    </jenkins.model.BuildDiscarderProperty>`
+
+	DISK_USAGE_SCRIPT = `
+import groovy.json.JsonOutput
+
+def usages = []
+for (name in [${disk.usage.pipelines}]) {
+    def job = Jenkins.instance.getItemByFullName(name)
+    if (job == null) {
+        continue
+    }
+
+    long size = 0
+    job.rootDir.eachFileRecurse { if (it.isFile()) size += it.length() }
+    long artifactsSize = 0
+    job.builds.each { build ->
+        if (build.artifactsDir.exists()) {
+            build.artifactsDir.eachFileRecurse { if (it.isFile()) artifactsSize += it.length() }
+        }
+    }
+    usages << [pipeline: name, builds: job.builds.size(), size: size, artifacts_size: artifactsSize]
+}
+println JsonOutput.toJson(usages)
+`
+
+	DISABLE_CONCURRENT_TEMPLATE = `
+    <org.jenkinsci.plugins.workflow.job.properties.DisableConcurrentBuildsJobProperty/>`
+
+	THROTTLE_TEMPLATE = `
+    <hudson.plugins.throttleconcurrents.ThrottleJobProperty plugin="throttle-concurrents@2.0.1">
+      <maxConcurrentPerNode>${throttle.max.per.node}</maxConcurrentPerNode>
+      <maxConcurrentTotal>${throttle.max.total}</maxConcurrentTotal>
+      <categories class="java.util.concurrent.CopyOnWriteArrayList"/>
+      <throttleEnabled>true</throttleEnabled>
+      <throttleOption>project</throttleOption>
+      <limitOneJobWithMatchingParams>false</limitOneJobWithMatchingParams>
+      <paramsToUseForLimit></paramsToUseForLimit>
+    </hudson.plugins.throttleconcurrents.ThrottleJobProperty>`
+
+	PIPELINE_TRIGGERS_TEMPLATE = `<triggers>${pipeline.triggers.list}
+      </triggers>`
+
+	PERIOD_TRIGGER_TEMPLATE = `
         <hudson.triggers.TimerTrigger>
           <spec>${period.trigger.strategy}</spec>
-        </hudson.triggers.TimerTrigger>
-      </triggers>`
+        </hudson.triggers.TimerTrigger>`
+
+	UPSTREAM_TRIGGER_TEMPLATE = `
+        <jenkins.triggers.ReverseBuildTrigger>
+          <spec></spec>
+          <upstreamProjects>${upstream.trigger.projects}</upstreamProjects>
+          <threshold>
+            <name>${upstream.trigger.threshold.name}</name>
+            <ordinal>${upstream.trigger.threshold.ordinal}</ordinal>
+            <color>${upstream.trigger.threshold.color}</color>
+            <completeBuild>true</completeBuild>
+          </threshold>
+        </jenkins.triggers.ReverseBuildTrigger>`
+
+	PARAMETER_TEMPLATE = `
+        <hudson.model.${parameter.class}>
+          <name>${parameter.name}</name>
+          <description>${parameter.description}</description>
+          <defaultValue>${parameter.default}</defaultValue>
+        </hudson.model.${parameter.class}>`
+
+	CHOICE_PARAMETER_TEMPLATE = `
+        <hudson.model.ChoiceParameterDefinition>
+          <name>${parameter.name}</name>
+          <description>${parameter.description}</description>
+          <choices class="java.util.Arrays$ArrayList">
+            <a class="string-array">${parameter.choices}
+            </a>
+          </choices>
+        </hudson.model.ChoiceParameterDefinition>`
+
+	CHOICE_TEMPLATE = `
+              <string>${parameter.choice}</string>`
+
+	MASK_PASSWORDS_TEMPLATE = `wrap([$class: 'MaskPasswordsBuildWrapper', varPasswordPairs: [${parameter.passwords}]]) {
+${pipeline.script.node}
+}`
+
+	PASSWORD_PAIR_TEMPLATE = `[var: '${parameter.name}', password: env.${parameter.name}]`
+
+	USERNAME_PASSWORD_CREDENTIAL_TEMPLATE = `<com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl>
+  <scope>GLOBAL</scope>
+  <id>${credential.id}</id>
+  <description>${credential.description}</description>
+  <username>${credential.username}</username>
+  <password>${credential.password}</password>
+</com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl>`
+
+	SECRET_TEXT_CREDENTIAL_TEMPLATE = `<org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl>
+  <scope>GLOBAL</scope>
+  <id>${credential.id}</id>
+  <description>${credential.description}</description>
+  <secret>${credential.secret}</secret>
+</org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl>`
+
+	SSH_KEY_CREDENTIAL_TEMPLATE = `<com.cloudbees.jenkins.plugins.sshcredentials.impl.BasicSSHUserPrivateKey>
+  <scope>GLOBAL</scope>
+  <id>${credential.id}</id>
+  <description>${credential.description}</description>
+  <username>${credential.username}</username>
+  <passphrase>${credential.passphrase}</passphrase>
+  <privateKeySource class="com.cloudbees.jenkins.plugins.sshcredentials.impl.BasicSSHUserPrivateKey$DirectEntryPrivateKeySource">
+    <privateKey>${credential.private.key}</privateKey>
+  </privateKeySource>
+</com.cloudbees.jenkins.plugins.sshcredentials.impl.BasicSSHUserPrivateKey>`
 
 	PIPELINE_SCRIPT_TEMPLATE = `
 performPhases = "${performPhases}"
 
-node("${pipeline.label.node}") {
+${pipeline.script.notify.start}
+
+${pipeline.script.milestone}
+
+${pipeline.script.node}
+
+// Send the notifications
+${pipeline.script.notify.result}
+
+// Trigger the downstream pipelines
+${pipeline.script.downstream}
+	`
+
+	PIPELINE_NODE_TEMPLATE = `node("${pipeline.label.node}") {
 	timestamps {
 		catchError {
-			timeout(time: 1, unit: 'HOURS') {	
+			timeout(time: ${pipeline.timeout.time}, unit: '${pipeline.timeout.unit}') {	
 				// Checkout the source code
-				checkout([$class: 'GitSCM', branches: [[name: '${project.branch}']], userRemoteConfigs: [[credentialsId: '${jenkins.credentialId}', url: '${project.repoPath}']]])
-				 sh "git checkout $branch"
+				${pipeline.script.checkout}
 				
-				withEnv(["WORKSPACE=${pwd()}", "PATH+JAVA=${jdk.version}/bin", "JAVA_HOME=${jdk.version}"]) {
+				withEnv(["WORKSPACE=${pwd()}", "PATH+JAVA=${jdk.bin}", "JAVA_HOME=${jdk.home}"${pipeline.script.env}]) {
+					${pipeline.script.cache.purge}
+
 					// Compile Stage
 					${pipeline.script.stage.compile}
 					
@@ -83,98 +257,361 @@ node("${pipeline.label.node}") {
 		}
 	}
 
-    // Archive the workspace
-	${pipeline.script.archive.workspace}
+    // Archive the artifacts
+	${pipeline.script.archive}
+}`
+
+	AGENTS_TEMPLATE = `timestamps {
+	catchError {
+		timeout(time: ${pipeline.timeout.time}, unit: '${pipeline.timeout.unit}') {
+${agent.nodes}
+		}
+	}
+
+	${agent.archive}
+}`
+
+	AGENT_NODE_TEMPLATE = `
+node("${pipeline.label.node}") {
+	${agent.workspace}
+
+	try {
+		withEnv(["WORKSPACE=${pwd()}", "PATH+JAVA=${jdk.bin}", "JAVA_HOME=${jdk.home}"${pipeline.script.env}]) {
+			${pipeline.script.cache.purge}
+${agent.stages}
+		}
+	} finally {
+		${agent.artifacts}
+	}
+
+	${agent.handoff}
+}
+`
+
+	AGENT_ARTIFACTS_STASH_TEMPLATE = `// Keep the artifacts to archive, even if the stages fail
+		stash name: '${stash.name}', includes: '${artifacts.includes}', excludes: '${artifacts.excludes}', allowEmpty: true`
+
+	AGENT_ARCHIVE_TEMPLATE = `// Archive the artifacts kept by the last agent outside of catchError, so that they are archived even if the stages fail
+	node("${pipeline.label.node}") {
+		deleteDir()
+		unstash '${stash.name}'
+		${pipeline.script.archive}
+	}`
+
+	AGENT_STAGE_TEMPLATE = `
+		${pipeline.script.stage}
+`
+
+	UNSTASH_TEMPLATE = `// Take over the workspace from the previous agent
+	deleteDir()
+	unstash '${stash.name}'`
+
+	STASH_TEMPLATE = `// Hand off the workspace to the next agent
+	stash name: '${stash.name}', includes: '${stash.includes}', excludes: '${stash.excludes}', allowEmpty: true`
+
+	CACHE_PURGE_TEMPLATE = `if (params.purgeCache) {
+						echo "Purge the dependency cache ${cache.dir}"
+						dir("${cache.dir}") {
+							deleteDir()
+						}
+					}`
+
+	MATRIX_TEMPLATE = `def cells = [:]
+${matrix.cells}
+parallel cells`
+
+	MATRIX_CELL_TEMPLATE = `
+cells['${matrix.cell.name}'] = {
+${pipeline.script.node}
+}
+`
+
+	STAGE_NAME_FUNCTION = `
+def stageName(name) {
+    return env.MATRIX_CELL ? "${name} [${env.MATRIX_CELL}]" : name
 }
 	`
 
+	TEST_REPORT_FUNCTION = `
+def publishTestReport(testResults) {
+    if (!env.MATRIX_CELL) {
+        junit testResults
+        return
+    }
+
+    // Publish the copies of the reports, whose suites and classes are prefixed with the cell, so that the cells do not collide
+    def cell = env.MATRIX_CELL.replaceAll(/[^A-Za-z0-9_]+/, '_')
+    def reports = findFiles(glob: testResults)
+    dir(pwd(tmp: true) + "/test-reports/" + cell) {
+        deleteDir()
+        for (int i = 0; i < reports.size(); i++) {
+            def content = readFile(file: "${env.WORKSPACE}/${reports[i].path}")
+            content = content.replaceAll(/(<testsuite\b[^>]*?\sname=")/, '$1' + cell + '.').replaceAll(/(\sclassname=")/, '$1' + cell + '.')
+            writeFile file: "${i}-${reports[i].name}", text: content
+        }
+        junit '*.xml'
+    }
+}
+	`
+
+	GIT_CHECKOUT_TEMPLATE = `checkout([$class: 'GitSCM', branches: [[name: revision ?: '${project.branch}']], extensions: [${git.extensions}], userRemoteConfigs: [[credentialsId: '${jenkins.credentialId}', url: '${project.repoPath}']]])
+				 ${platform.shell} "git checkout ${revision ?: branch}"`
+
+	GIT_PULL_REQUEST_CHECKOUT_TEMPLATE = `if (params.pullRequestRef) {
+					checkout([$class: 'GitSCM', branches: [[name: revision]], extensions: [${git.extensions}], userRemoteConfigs: [[credentialsId: '${jenkins.credentialId}', url: '${project.repoPath}', refspec: "+refs/heads/${branch}:refs/remotes/origin/${branch} +${params.pullRequestRef}:refs/remotes/origin/pull-request"]]])
+				} else {
+					${git.checkout}
+				}`
+
+	GIT_MERGE_EXTENSION = `[$class: 'PreBuildMerge', options: [fastForwardMode: 'FF', mergeRemote: 'origin', mergeTarget: branch]]`
+
+	GIT_CLONE_EXTENSION = `[$class: 'CloneOption', depth: ${git.depth}, noTags: false, shallow: true, reference: '']`
+
+	GIT_SUBMODULE_EXTENSION = `[$class: 'SubmoduleOption', recursiveSubmodules: true, parentCredentials: true, disableSubmodules: false, trackingSubmodules: false, reference: '']`
+
+	GIT_SPARSE_CHECKOUT_EXTENSION = `[$class: 'SparseCheckoutPaths', sparseCheckoutPaths: [${git.sparse.paths}]]`
+
+	GIT_LFS_EXTENSION = `[$class: 'GitLFSPull']`
+
+	GIT_CLEAN_EXTENSION = `[$class: 'CleanBeforeCheckout']`
+
+	SVN_CHECKOUT_TEMPLATE = `checkout([$class: 'SubversionSCM', locations: [[credentialsId: '${jenkins.credentialId}', depthOption: 'infinity', ignoreExternalsOption: ${svn.ignore.externals}, local: '.', remote: "${svn.remote}" + (revision ? "@$revision" : "")]], workspaceUpdater: [$class: 'UpdateUpdater']])`
+
+	HG_CHECKOUT_TEMPLATE = `checkout([$class: 'MercurialSCM', source: '${project.repoPath}', credentialsId: '${jenkins.credentialId}', revisionType: revision ? 'CHANGESET' : 'BRANCH', revision: revision ?: branch, clean: false])`
+
 	STAGE_TEMPLATE = `if (performPhases.contains("${pipeline.stage}")) {
 						${pipeline.script.stage.function}
 					}`
 
+	STAGE_LOCK_TEMPLATE = `lock(resource: '${stage.lock}') {
+							${pipeline.script.stage.function}
+						}`
+
+	STAGE_APPROVAL_TEMPLATE = `def approval = ${stage.approval.input}
+						echo "Approved by ${approval.approver}: ${approval.comment}"
+						${pipeline.script.stage.function}`
+
+	APPROVAL_INPUT_TEMPLATE = `input(id: '${approval.id}', message: '${approval.message}', ok: 'Approve',${approval.submitter}
+							parameters: [string(name: 'approver', defaultValue: '', description: 'The approver'), string(name: 'comment', defaultValue: '', description: 'The comment')])`
+
+	APPROVAL_SUBMITTER_TEMPLATE = ` submitter: '${approval.submitter.names}',`
+
+	APPROVAL_TIMEOUT_TEMPLATE = `timeout(time: ${approval.timeout.time}, unit: '${approval.timeout.unit}') {
+							${stage.approval.input}
+						}`
+
+	MILESTONE_TEMPLATE = `milestone(${milestone.ordinal})`
+
+	THROTTLE_CATEGORIES_TEMPLATE = `throttle([${throttle.categories}]) {
+${pipeline.script.node}
+}`
+
+	STAGE_TIMEOUT_TEMPLATE = `timeout(time: ${stage.timeout.time}, unit: '${stage.timeout.unit}') {
+							${pipeline.script.stage.function}
+						}`
+
+	STAGE_RETRY_TEMPLATE = `retryWithBackoff(${stage.retry.times}, ${stage.retry.backoff}) {
+							${pipeline.script.stage.function}
+						}`
+
+	STAGE_CREDENTIALS_TEMPLATE = `withCredentials([${stage.credentials}]) {
+							${pipeline.script.stage.function}
+						}`
+
+	USERNAME_PASSWORD_BINDING = `usernamePassword(credentialsId: '${credential.id}', usernameVariable: '${credential.username.variable}', passwordVariable: '${credential.password.variable}')`
+
+	SECRET_TEXT_BINDING = `string(credentialsId: '${credential.id}', variable: '${credential.variable}')`
+
+	SSH_KEY_BINDING = `sshUserPrivateKey(credentialsId: '${credential.id}', keyFileVariable: '${credential.variable}', usernameVariable: '${credential.username.variable}', passphraseVariable: '${credential.password.variable}')`
+
+	SECRET_FILE_BINDING = `file(credentialsId: '${credential.id}', variable: '${credential.variable}')`
+
+	NOTIFY_FUNCTION = `
+def notifyResult() {
+    def result = currentBuild.result ?: 'SUCCESS'
+    def previousResult = currentBuild.previousBuild?.result ?: 'SUCCESS'
+
+    // Only the first matched event is notified
+    def events = []
+    if (result == 'FAILURE') {
+        events << 'failure'
+    }
+    if (result == 'UNSTABLE') {
+        events << 'unstable'
+    }
+    if (result == 'SUCCESS' && previousResult != 'SUCCESS') {
+        events << 'fixed'
+    }
+    events << 'always'
+
+    for (event in events) {
+        if ([${notify.events}].contains(event)) {
+            sendNotification(event, result)
+            return
+        }
+    }
+}
+
+def sendNotification(event, result) {
+    def message = "${env.JOB_NAME} #${env.BUILD_NUMBER} ${event}: ${result} (${env.BUILD_URL})"
+    def testSummary = ""
+    def culprits = []
+    ${notify.summary}
+    ${notify.culprits}
+
+    try {
+        ${notify.channels}
+    } catch (err) {
+        echo "Fail to send the notifications as ${err}"
+    }
+}
+	`
+
+	NOTIFY_TEST_SUMMARY = `def testResult = currentBuild.rawBuild.getAction(hudson.tasks.test.AbstractTestResultAction.class)
+    if (testResult != null) {
+        testSummary = "Tests: ${testResult.totalCount} total, ${testResult.failCount} failed, ${testResult.skipCount} skipped"
+        message += "\n" + testSummary
+    }`
+
+	NOTIFY_CULPRITS = `culprits = currentBuild.rawBuild.culprits.collect { it.fullName }
+    if (culprits) {
+        message += "\nCulprits: " + culprits.join(", ")
+    }`
+
+	NOTIFY_SLACK = `slackSend teamDomain: '${slack.team.domain}', channel: '${slack.room}', tokenCredentialId: '${slack.token.credentialId}', color: result == 'SUCCESS' ? 'good' : (result == 'UNSTABLE' ? 'warning' : 'danger'), message: message`
+
+	NOTIFY_EMAIL = `emailext to: '${email.recipients}', recipientProviders: [${email.recipient.providers}], subject: "${env.JOB_NAME} #${env.BUILD_NUMBER} ${event}: ${result}", body: message`
+
+	NOTIFY_WEBHOOK = `httpRequest url: '${webhook.url}', httpMode: 'POST', contentType: 'APPLICATION_JSON', validResponseCodes: '100:599',
+            requestBody: groovy.json.JsonOutput.toJson([pipeline: env.JOB_NAME, build: env.BUILD_NUMBER, url: env.BUILD_URL, event: event, result: result, test_summary: testSummary, culprits: culprits])`
+
+	RETRY_FUNCTION = `
+def retryWithBackoff(int times, int backoff, body) {
+    for (int attempt = 1; ; attempt++) {
+        try {
+            return body()
+        } catch (err) {
+            if (attempt > times) {
+                throw err
+            }
+            echo "Retry ${attempt}/${times} in ${backoff} seconds as ${err}"
+            sleep time: backoff, unit: 'SECONDS'
+            backoff *= 2
+        }
+    }
+}
+	`
+
 	MAVEN_COMMAND_FUNCTION = `
 def mvn(args) {
-    sh "/opt/maven/latest/bin/mvn ${args}"
+    ${platform.shell} "${maven.command} ${args}"
 }
 	`
 
 	MAVEN_COMPILE_STAGE = `
 def compile() {
-    stage "Compile"
-	
-    mvn("-B -f ${maven.rootpom} clean install -e -U -DskipTests=true -Dfindbugs.skip=true ${mvn.options}")
+    stage(stageName("Compile")) {
+        mvn("-B -f ${maven.rootpom} clean install -e${mvn.cache.options} -DskipTests=true -Dfindbugs.skip=true ${mvn.options}")
+    }
 }
 	`
 
 	MAVEN_UNIT_TEST_STAGE = `
 def unitTest() {
-    stage "Unit Test"
-	
-    mvn("-B -f ${maven.rootpom} clean org.jacoco:jacoco-maven-plugin:0.7.2.201409121644:prepare-agent test -Dfindbugs.skip=true ${mvn.options}")
-	
-	junit '**/${test.report.path}/TEST-*.xml'
+    stage(stageName("Unit Test")) {
+        mvn("-B -f ${maven.rootpom} clean org.jacoco:jacoco-maven-plugin:0.7.2.201409121644:prepare-agent test${mvn.cache.options} -Dfindbugs.skip=true ${mvn.options}")
+
+        publishTestReport('**/${test.report.path}/TEST-*.xml')
+    }
 }
 	`
 
 	MAVEN_BUILD_STAGE = `
 def build() {
-    stage "Build"
-	
-    mvn("-B -f ${maven.rootpom} clean package -e -U -DskipTests=true -Dfindbugs.skip=true ${mvn.options}")
+    stage(stageName("Build")) {
+        mvn("-B -f ${maven.rootpom} clean package -e${mvn.cache.options} -DskipTests=true -Dfindbugs.skip=true ${mvn.options}")
+    }
 }
 	`
 
 	GRADLE_COMPILE_STAGE = `
 def compile() {
-    stage "Compile"
-	
-	sh "gradle clean compile -x test -x check ${gradle.gradleOpts}"
+    stage(stageName("Compile")) {
+        ${platform.shell} "${gradle.command} clean compile -x test -x check${gradle.cache.options} ${gradle.gradleOpts}"
+    }
 }
 	`
 
 	GRADLE_UNIT_TEST_STAGE = `
 def unitTest() {
-    stage "Unit Test"
-	
-	sh "gradle clean test ${gradle.gradleOpts}"
-	
-	junit '${test.report.path}'
+    stage(stageName("Unit Test")) {
+        ${platform.shell} "${gradle.command} clean test${gradle.cache.options} ${gradle.gradleOpts}"
+
+        publishTestReport('${test.report.path}')
+    }
 }
 	`
 
 	GRADLE_BUILD_STAGE = `
 def build() {
-    stage "Build"
-	
-	sh "gradle clean build ${gradle.gradleOpts} -x test"
+    stage(stageName("Build")) {
+        ${platform.shell} "${gradle.command} clean build${gradle.cache.options} ${gradle.gradleOpts} -x test"
+    }
 }
 	`
 
 	SCRIPT_COMPILE_STAGE = `
 def compile() {
-    stage "Compile"
-	
-    sh '''${script.compile.command}'''
+    stage(stageName("Compile")) {
+        ${platform.shell} '''${script.compile.command}'''
+    }
 }
 	`
 
 	SCRIPT_UNIT_TEST_STAGE = `
 def unitTest() {
-    stage "Unit Test"
-	
-    sh '''${script.ut.command}'''
-	
-	junit '${test.report.path}'
+    stage(stageName("Unit Test")) {
+        ${platform.shell} '''${script.ut.command}'''
+
+        publishTestReport('${test.report.path}')
+    }
 }
 	`
 
 	SCRIPT_BUILD_STAGE = `
 def build() {
-    stage "Build"
-	
-    sh '''${script.build.command}'''
+    stage(stageName("Build")) {
+        ${platform.shell} '''${script.build.command}'''
+    }
 }
 	`
 
-	ARCHIVE_WORKSPACE = `archiveArtifacts artifacts: '**/*', excludes: '**/*.war, **/*.tar.gz, **/*.tgz, **/*.zip'`
+	DOWNSTREAM_TEMPLATE = `if (currentBuild.result == null || currentBuild.result == "SUCCESS") {
+	${downstream.builds}
+}`
+
+	DOWNSTREAM_BUILD_TEMPLATE = `build job: '${downstream.pipeline}', parameters: [${downstream.params}], wait: ${downstream.wait}`
+
+	DOWNSTREAM_PARAM_TEMPLATE = `string(name: '${downstream.param.name}', value: '${downstream.param.value}')`
+
+	ARCHIVE_ARTIFACTS_TEMPLATE = `archiveArtifacts artifacts: '${artifacts.includes}', excludes: '${artifacts.excludes}', allowEmptyArchive: ${artifacts.allow.empty}, fingerprint: ${artifacts.fingerprint}`
+
+	ARCHIVE_ON_SUCCESS_TEMPLATE = `if (currentBuild.result == null || currentBuild.result == "SUCCESS") {
+		${pipeline.script.archive}
+	}`
+
+	// The commands of the stages run by the local backend
+	LOCAL_MAVEN_COMPILE_COMMAND = `mvn -B -f ${maven.rootpom} clean install -e -DskipTests=true -Dfindbugs.skip=true ${mvn.options}`
+
+	LOCAL_MAVEN_UNIT_TEST_COMMAND = `mvn -B -f ${maven.rootpom} clean org.jacoco:jacoco-maven-plugin:0.7.2.201409121644:prepare-agent test -Dfindbugs.skip=true ${mvn.options}`
+
+	LOCAL_MAVEN_BUILD_COMMAND = `mvn -B -f ${maven.rootpom} clean package -e -DskipTests=true -Dfindbugs.skip=true ${mvn.options}`
+
+	LOCAL_MAVEN_TEST_REPORT = `**/${test.report.path}/TEST-*.xml`
+
+	LOCAL_GRADLE_COMPILE_COMMAND = `gradle clean compile -x test -x check ${gradle.gradleOpts}`
+
+	LOCAL_GRADLE_UNIT_TEST_COMMAND = `gradle clean test ${gradle.gradleOpts}`
+
+	LOCAL_GRADLE_BUILD_COMMAND = `gradle clean build ${gradle.gradleOpts} -x test`
 )
@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bndr/gojenkins"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/store"
+)
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// syncViews Keeps the Jenkins list views of the tags in sync with the tags of the pipeline,
+// the pipeline is added into the views of its tags and removed from the views of the old tags.
+// The deleted pipelines are removed from the views by Jenkins itself.
+func (backend *JenkinsBackend) syncViews(plName string, oldTags []string, tags []string) error {
+	current := map[string]bool{}
+	for _, tag := range tags {
+		current[tag] = true
+
+		view, err := backend.ensureView(tag)
+		if err != nil {
+			return err
+		}
+		if _, err := view.AddJob(plName); err != nil {
+			return fmt.Errorf("Fail to add the pipeline %s into the view %s as %s", plName, tag, err.Error())
+		}
+	}
+
+	for _, tag := range oldTags {
+		if current[tag] {
+			continue
+		}
+
+		view, err := backend.Jenkins.GetView(tag)
+		if err != nil {
+			return fmt.Errorf("Fail to get the view %s as %s", tag, err.Error())
+		}
+		// The view has been deleted in Jenkins
+		if len(view.GetName()) == 0 {
+			continue
+		}
+		if _, err := view.DeleteJob(plName); err != nil {
+			return fmt.Errorf("Fail to remove the pipeline %s from the view %s as %s", plName, tag, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ensureView Gets the list view of the tag, creates it if not exists
+func (backend *JenkinsBackend) ensureView(tag string) (*gojenkins.View, error) {
+	view, err := backend.Jenkins.GetView(tag)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to get the view %s as %s", tag, err.Error())
+	}
+	if len(view.GetName()) > 0 {
+		return view, nil
+	}
+
+	view, err = backend.Jenkins.CreateView(tag, gojenkins.LIST_VIEW)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to create the view %s as %s", tag, err.Error())
+	}
+
+	// Recurse into the folders, so that the pipelines in namespaces are listed in the view
+	viewCfg := strings.Replace(LIST_VIEW_TEMPLATE, "${view.name}", escapeXml(tag), -1)
+	resp, err := backend.Jenkins.Requester.PostXML(view.Base+"/config.xml", viewCfg, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to configure the view %s as %s", tag, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fail to configure the view %s as %s", tag, resp.Status)
+	}
+
+	log.Infof("Create the view %s", tag)
+	return view, nil
+}
+
+// storedTags Gets the tags of the pipeline saved by goline, returns empty if the pipeline is not saved
+func (backend *JenkinsBackend) storedTags(plName string) ([]string, error) {
+	pl := &api.Pipeline{}
+	err := backend.store.Get(pipelineKind, plName, pl)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Fail to get the config of pipeline %s as %s", plName, err.Error())
+	}
+
+	return pl.Tags, nil
+}
+
+// validateTags Validates the tags of the pipeline, which are the names of the Jenkins views
+func validateTags(tags []string) bool {
+	names := map[string]bool{}
+	for _, tag := range tags {
+		if !tagPattern.MatchString(tag) {
+			log.Errorf("The tag %s should only contain letters, digits, underscores, dots and hyphens", tag)
+			return false
+		}
+		if names[tag] {
+			log.Errorf("The tag %s is duplicated", tag)
+			return false
+		}
+		names[tag] = true
+	}
+
+	return true
+}
@@ -0,0 +1,225 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/bndr/gojenkins"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/utils/id"
+)
+
+// buildState is the state of the pipeline builds known by the watcher
+type buildState struct {
+	queueId   int64
+	lastBuild int64
+	// The finished stages of the running builds
+	runningBuilds map[int64]map[string]bool
+}
+
+// stageDescription is the stage described by the Pipeline Stage View API
+type stageDescription struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// WatchBuilds Watches the builds of the managed pipelines, and emits the build events to the handler.
+// The builds before watching are not emitted.
+func (backend *JenkinsBackend) WatchBuilds(interval time.Duration, handler func(*api.BuildEvent)) {
+	states := map[string]*buildState{}
+	for {
+		names, err := backend.listWatchedPipelines()
+		if err != nil {
+			log.Errorf("Fail to list the pipelines to watch as %s", err.Error())
+		}
+
+		watched := map[string]*buildState{}
+		for _, name := range names {
+			state, err := backend.watchPipeline(name, states[name], handler)
+			if err != nil {
+				log.Warnf("Fail to watch the pipeline %s as %s", name, err.Error())
+			}
+			watched[name] = state
+		}
+		// The states of deleted pipelines are dropped
+		states = watched
+
+		time.Sleep(interval)
+	}
+}
+
+// listWatchedPipelines Lists the pipelines saved by goline, and the Jenkins jobs managed by goline
+// which are created before goline saves the pipeline configs.
+func (backend *JenkinsBackend) listWatchedPipelines() ([]string, error) {
+	names, err := backend.store.List(pipelineKind)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := backend.listManagedJobs()
+	if err != nil {
+		return names, err
+	}
+	for _, job := range jobs {
+		if !containString(names, job) {
+			names = append(names, job)
+		}
+	}
+
+	return names, nil
+}
+
+// listManagedJobs Lists the top-level Jenkins jobs managed by goline, which have the performPhases parameter
+func (backend *JenkinsBackend) listManagedJobs() ([]string, error) {
+	result := struct {
+		Jobs []struct {
+			Name     string `json:"name"`
+			Property []struct {
+				ParameterDefinitions []parameterDefinition `json:"parameterDefinitions"`
+			} `json:"property"`
+		} `json:"jobs"`
+	}{}
+
+	querystring := map[string]string{
+		"tree": "jobs[name,property[parameterDefinitions[name]]]",
+	}
+	_, err := backend.Jenkins.Requester.GetJSON("/", &result, querystring)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to list the Jenkins jobs as %s", err.Error())
+	}
+
+	names := []string{}
+	for _, job := range result.Jobs {
+		for _, property := range job.Property {
+			if containParameter(property.ParameterDefinitions, "performPhases") {
+				names = append(names, job.Name)
+				break
+			}
+		}
+	}
+
+	return names, nil
+}
+
+func (backend *JenkinsBackend) watchPipeline(plName string, state *buildState, handler func(*api.BuildEvent)) (*buildState, error) {
+	job, err := backend.Jenkins.GetJob(jobId(plName))
+	if err != nil {
+		return state, err
+	}
+
+	raw := job.GetDetails()
+	queueId := int64(0)
+	if item, ok := raw.QueueItem.(map[string]interface{}); ok {
+		if id, ok := item["id"].(float64); ok {
+			queueId = int64(id)
+		}
+	}
+
+	// Initialize the state with the current builds
+	if state == nil {
+		state = &buildState{
+			queueId:       queueId,
+			lastBuild:     raw.LastBuild.Number,
+			runningBuilds: map[int64]map[string]bool{},
+		}
+
+		if raw.LastBuild.Number > 0 {
+			build, err := backend.getBuildInfo(job, raw.LastBuild.Number)
+			if err != nil {
+				return state, err
+			}
+			if build.Building {
+				stages, err := backend.describeStages(job, raw.LastBuild.Number)
+				if err != nil {
+					return state, err
+				}
+				finished := map[string]bool{}
+				for _, stage := range stages {
+					if isStageFinished(stage) {
+						finished[stage.Id] = true
+					}
+				}
+				state.runningBuilds[raw.LastBuild.Number] = finished
+			}
+		}
+		return state, nil
+	}
+
+	if raw.InQueue && queueId != state.queueId {
+		handler(newBuildEvent(api.BUILD_QUEUED, plName, 0))
+	}
+	state.queueId = queueId
+
+	for number := state.lastBuild + 1; number <= raw.LastBuild.Number; number++ {
+		handler(newBuildEvent(api.BUILD_STARTED, plName, number))
+		state.runningBuilds[number] = map[string]bool{}
+	}
+	if raw.LastBuild.Number > state.lastBuild {
+		state.lastBuild = raw.LastBuild.Number
+	}
+
+	for number, finished := range state.runningBuilds {
+		build, err := backend.getBuildInfo(job, number)
+		if err != nil {
+			return state, err
+		}
+
+		stages, err := backend.describeStages(job, number)
+		if err != nil {
+			return state, err
+		}
+		for _, stage := range stages {
+			if isStageFinished(stage) && !finished[stage.Id] {
+				event := newBuildEvent(api.STAGE_FINISHED, plName, number)
+				event.Stage = stage.Name
+				event.Result = stage.Status
+				handler(event)
+				finished[stage.Id] = true
+			}
+		}
+
+		if !build.Building {
+			event := newBuildEvent(api.BUILD_COMPLETED, plName, number)
+			event.Result = build.Result
+			handler(event)
+			delete(state.runningBuilds, number)
+		}
+	}
+
+	return state, nil
+}
+
+// describeStages Describes the stages of the build through the Pipeline Stage View API
+func (backend *JenkinsBackend) describeStages(job *gojenkins.Job, number int64) ([]stageDescription, error) {
+	result := struct {
+		Stages []stageDescription `json:"stages"`
+	}{}
+
+	_, err := backend.Jenkins.Requester.Get(job.Base+"/"+strconv.FormatInt(number, 10)+"/wfapi/describe", &result, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to describe the stages of build %d as %s", number, err.Error())
+	}
+
+	return result.Stages, nil
+}
+
+func isStageFinished(stage stageDescription) bool {
+	switch stage.Status {
+	case "SUCCESS", "FAILED", "UNSTABLE", "ABORTED":
+		return true
+	}
+	return false
+}
+
+func newBuildEvent(eventType api.BuildEventType, plName string, number int64) *api.BuildEvent {
+	return &api.BuildEvent{
+		Id:        id.New(),
+		Type:      eventType,
+		Pipeline:  plName,
+		Build:     number,
+		Timestamp: time.Now(),
+	}
+}
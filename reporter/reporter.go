@@ -0,0 +1,139 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/supereagle/goline/api"
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// StatusReporter reports the commit statuses to the source code repo service
+type StatusReporter interface {
+	Report(status *api.CommitStatus) error
+}
+
+// NewStatusReporter Creates the status reporter according to the reporter type
+func NewStatusReporter(cfg *api.ReporterConfig) (StatusReporter, error) {
+	if len(cfg.Url) == 0 || len(cfg.Token) == 0 {
+		return nil, fmt.Errorf("The url and the token of the status reporter should not be empty")
+	}
+
+	apiUrl := strings.TrimSuffix(cfg.Url, "/")
+	switch cfg.Type {
+	case api.GITHUB:
+		return &GitHubReporter{apiUrl, cfg.Token}, nil
+	case api.GITLAB:
+		return &GitLabReporter{apiUrl, cfg.Token}, nil
+	case api.GITEA:
+		return &GiteaReporter{apiUrl, cfg.Token}, nil
+	default:
+		return nil, fmt.Errorf("The status reporter type %s is not supported", cfg.Type)
+	}
+}
+
+// GitHubReporter reports the commit statuses by the GitHub API, Url is the API url such as https://api.github.com
+type GitHubReporter struct {
+	Url   string
+	Token string
+}
+
+func (reporter *GitHubReporter) Report(status *api.CommitStatus) error {
+	// GitHub has no running state
+	state := status.State
+	if state == api.COMMIT_RUNNING {
+		state = api.COMMIT_PENDING
+	}
+
+	body := map[string]string{
+		"state":       string(state),
+		"context":     status.Context,
+		"description": status.Description,
+		"target_url":  status.TargetUrl,
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/statuses/%s", reporter.Url, status.Repo, status.Sha)
+	return post(endpoint, map[string]string{"Authorization": "token " + reporter.Token}, body)
+}
+
+// GitLabReporter reports the commit statuses by the GitLab API v4
+type GitLabReporter struct {
+	Url   string
+	Token string
+}
+
+func (reporter *GitLabReporter) Report(status *api.CommitStatus) error {
+	state := string(status.State)
+	switch status.State {
+	case api.COMMIT_FAILURE:
+		state = "failed"
+	case api.COMMIT_ERROR:
+		state = "canceled"
+	}
+
+	body := map[string]string{
+		"state":       state,
+		"name":        status.Context,
+		"description": status.Description,
+		"target_url":  status.TargetUrl,
+	}
+	// GitLab identifies the projects by the url-encoded full names
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/statuses/%s", reporter.Url, url.PathEscape(status.Repo), status.Sha)
+	return post(endpoint, map[string]string{"PRIVATE-TOKEN": reporter.Token}, body)
+}
+
+// GiteaReporter reports the commit statuses by the Gitea API v1
+type GiteaReporter struct {
+	Url   string
+	Token string
+}
+
+func (reporter *GiteaReporter) Report(status *api.CommitStatus) error {
+	// Gitea has no running state
+	state := status.State
+	if state == api.COMMIT_RUNNING {
+		state = api.COMMIT_PENDING
+	}
+
+	body := map[string]string{
+		"state":       string(state),
+		"context":     status.Context,
+		"description": status.Description,
+		"target_url":  status.TargetUrl,
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/repos/%s/statuses/%s", reporter.Url, status.Repo, status.Sha)
+	return post(endpoint, map[string]string{"Authorization": "token " + reporter.Token}, body)
+}
+
+func post(endpoint string, headers map[string]string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Fail to report the commit status as %s", resp.Status)
+	}
+
+	return nil
+}
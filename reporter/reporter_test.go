@@ -0,0 +1,80 @@
+package reporter_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/reporter"
+)
+
+func TestReport(t *testing.T) {
+	type request struct {
+		path  string
+		auth  string
+		state string
+	}
+
+	received := make(chan *request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body := map[string]string{}
+		json.NewDecoder(req.Body).Decode(&body)
+		received <- &request{
+			path:  req.URL.EscapedPath(),
+			auth:  req.Header.Get("Authorization") + req.Header.Get("PRIVATE-TOKEN"),
+			state: body["state"],
+		}
+		resp.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	cases := map[string]struct {
+		reporterType api.ReporterType
+		state        api.CommitState
+		path         string
+		auth         string
+		expected     string
+	}{
+		"github-running": {
+			reporterType: api.GITHUB,
+			state:        api.COMMIT_RUNNING,
+			path:         "/repos/example/service/statuses/abc123",
+			auth:         "token secret",
+			expected:     "pending",
+		},
+		"gitlab-failure": {
+			reporterType: api.GITLAB,
+			state:        api.COMMIT_FAILURE,
+			path:         "/api/v4/projects/example%2Fservice/statuses/abc123",
+			auth:         "secret",
+			expected:     "failed",
+		},
+		"gitea-success": {
+			reporterType: api.GITEA,
+			state:        api.COMMIT_SUCCESS,
+			path:         "/api/v1/repos/example/service/statuses/abc123",
+			auth:         "token secret",
+			expected:     "success",
+		},
+	}
+
+	for name, c := range cases {
+		r, err := reporter.NewStatusReporter(&api.ReporterConfig{Type: c.reporterType, Url: server.URL, Token: "secret"})
+		if err != nil {
+			t.Fatalf("Fail to create the reporter of case %s as %s", name, err.Error())
+		}
+
+		err = r.Report(&api.CommitStatus{Repo: "example/service", Sha: "abc123", State: c.state, Context: "goline/service"})
+		if err != nil {
+			t.Errorf("Fail to report the status of case %s as %s", name, err.Error())
+			continue
+		}
+
+		req := <-received
+		if req.path != c.path || req.auth != c.auth || req.state != c.expected {
+			t.Errorf("The case %s reports %+v, but expected path %s and state %s", name, req, c.path, c.expected)
+		}
+	}
+}
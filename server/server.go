@@ -7,32 +7,59 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 	"github.com/supereagle/goline/api"
 	"github.com/supereagle/goline/config"
 	"github.com/supereagle/goline/pipeline"
+	"github.com/supereagle/goline/store"
 	httputil "github.com/supereagle/goline/utils/http"
 	jsonutil "github.com/supereagle/goline/utils/json"
+	"github.com/supereagle/goline/webhook"
 )
 
 const DefaultSwaggerPath = "./swagger.json"
 
 type Server struct {
-	router *mux.Router
-	pm     *pipeline.Manager
+	router     *mux.Router
+	pm         *pipeline.Manager
+	dispatcher *webhook.Dispatcher
 }
 
 func Run(cfg *config.Config) error {
-	pm, err := pipeline.NewPipelineManager(cfg)
+	st, err := store.NewStore(cfg.DataDir)
 	if err != nil {
 		return fmt.Errorf("Fail to create the server as %s", err.Error())
 	}
 
+	pm, err := pipeline.NewPipelineManager(cfg, st)
+	if err != nil {
+		return fmt.Errorf("Fail to create the server as %s", err.Error())
+	}
+
+	server := NewServer(pm, webhook.NewDispatcher(st))
+
+	// watch the builds, dispatch the build events to the subscribers and report the pull request builds
+	go pm.WatchBuilds(time.Duration(cfg.WatchInterval)*time.Second, func(event *api.BuildEvent) {
+		server.dispatcher.Dispatch(event)
+		pm.ReportPullRequestStatus(event)
+	})
+
+	// discover the branches of the fan-out pipelines
+	go pm.DiscoverBranches(time.Duration(cfg.BranchDiscoveryInterval) * time.Second)
+
+	log.Infof("Start the server to listen on: %d", cfg.Port)
+	return http.ListenAndServe(":"+strconv.Itoa(cfg.Port), server)
+}
+
+// NewServer Creates the server with the pipeline manager, whose backend can be replaced in tests
+func NewServer(pm *pipeline.Manager, dispatcher *webhook.Dispatcher) *Server {
 	server := &Server{
-		router: mux.NewRouter(),
-		pm:     pm,
+		router:     mux.NewRouter(),
+		pm:         pm,
+		dispatcher: dispatcher,
 	}
 
 	// register the pipeline handlers
@@ -41,16 +68,49 @@ func Run(cfg *config.Config) error {
 	// register the swagger handler
 	server.registerSwaggerHandler()
 
-	log.Infof("Start the server to listen on: %d", cfg.Port)
-	return http.ListenAndServe(":"+strconv.Itoa(cfg.Port), server.router)
+	return server
+}
+
+func (server *Server) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	server.router.ServeHTTP(resp, req)
 }
 
 func (server *Server) registerRoutes() {
 	router := server.router
-	router.Path("/pipelines").Methods("POST").HandlerFunc(server.createPipeline)
-	router.Path("/pipelines/{pipelinename}").Methods("PUT").HandlerFunc(server.updatePipeline)
-	router.Path("/pipelines/{pipelinename}").Methods("DELETE").HandlerFunc(server.deletePipeline)
-	router.Path("/pipelines/performance/{pipelinename}").Methods("PUT").HandlerFunc(server.performPipeline)
+	router.Path("/pipelines/diskusage").Methods("GET").HandlerFunc(server.getDiskUsages)
+	// The pipelines in namespaces are managed by the same routes under the namespace
+	for _, prefix := range []string{"", "/namespaces/{namespace}"} {
+		router.Path(prefix + "/pipelines").Methods("POST").HandlerFunc(server.createPipeline)
+		router.Path(prefix + "/pipelines").Methods("GET").HandlerFunc(server.listPipelines)
+		router.Path(prefix + "/pipelines/{pipelinename}").Methods("PUT").HandlerFunc(server.updatePipeline)
+		router.Path(prefix + "/pipelines/{pipelinename}").Methods("DELETE").HandlerFunc(server.deletePipeline)
+		router.Path(prefix + "/pipelines/performance/{pipelinename}").Methods("PUT").HandlerFunc(server.performPipeline)
+		// The export route is matched first, so the pipeline named dependency can be exported
+		router.Path(prefix + "/pipelines/{pipelinename}/export").Methods("GET").HandlerFunc(server.exportPipeline)
+		router.Path(prefix + "/pipelines/dependency/{pipelinename}").Methods("GET").HandlerFunc(server.getPipelineDependency)
+		router.Path(prefix + "/pipelines/builds/{pipelinename}/{buildnumber}").Methods("GET").HandlerFunc(server.getBuildStatus)
+		router.Path(prefix + "/pipelines/logs/{pipelinename}/{buildnumber}").Methods("GET").HandlerFunc(server.getBuildLog)
+		router.Path(prefix + "/pipelines/tests/{pipelinename}/{buildnumber}").Methods("GET").HandlerFunc(server.getTestReport)
+		router.Path(prefix + "/approvals/approval/{pipelinename}/{buildnumber}/{stage}").Methods("POST").HandlerFunc(server.approveStage)
+		router.Path(prefix + "/approvals/rejection/{pipelinename}/{buildnumber}/{stage}").Methods("POST").HandlerFunc(server.rejectStage)
+	}
+	router.Path("/templates").Methods("POST").HandlerFunc(server.createTemplate)
+	router.Path("/templates").Methods("GET").HandlerFunc(server.listTemplates)
+	router.Path("/templates/{templatename}").Methods("GET").HandlerFunc(server.getTemplate)
+	router.Path("/templates/{templatename}").Methods("PUT").HandlerFunc(server.updateTemplate)
+	router.Path("/templates/{templatename}").Methods("DELETE").HandlerFunc(server.deleteTemplate)
+	router.Path("/templates/preview/{templatename}").Methods("GET").HandlerFunc(server.previewTemplate)
+	router.Path("/templates/preview/{templatename}").Methods("POST").HandlerFunc(server.previewTemplateChange)
+	router.Path("/templates/rollout/{templatename}").Methods("PUT").HandlerFunc(server.rolloutTemplate)
+	router.Path("/approvals").Methods("GET").HandlerFunc(server.listPendingApprovals)
+	router.Path("/scm/events").Methods("POST").HandlerFunc(server.receiveScmEvent)
+	router.Path("/credentials").Methods("POST").HandlerFunc(server.createCredential)
+	router.Path("/credentials/{credentialid}").Methods("PUT").HandlerFunc(server.updateCredential)
+	router.Path("/credentials/{credentialid}").Methods("DELETE").HandlerFunc(server.deleteCredential)
+	router.Path("/subscriptions").Methods("POST").HandlerFunc(server.createSubscription)
+	router.Path("/subscriptions").Methods("GET").HandlerFunc(server.listSubscriptions)
+	router.Path("/subscriptions/{subscriptionid}").Methods("DELETE").HandlerFunc(server.deleteSubscription)
+	router.Path("/subscriptions/deliveries/{subscriptionid}").Methods("GET").HandlerFunc(server.listDeliveries)
 }
 
 // createPipeline swagger:route POST /pipelines pipelines createPipeline
@@ -61,7 +121,7 @@ func (server *Server) registerRoutes() {
 //    default: genericErrorResponse
 //        201: pipelineResponse
 func (server *Server) createPipeline(resp http.ResponseWriter, req *http.Request) {
-	pipeline, err := parseBody(req)
+	pipeline, err := server.parseBody(req, "")
 	if err != nil {
 		err = fmt.Errorf("Fail to parse the pipeline config as %s", err.Error())
 		log.Errorln(err.Error())
@@ -91,14 +151,13 @@ func (server *Server) createPipeline(resp http.ResponseWriter, req *http.Request
 func (server *Server) updatePipeline(resp http.ResponseWriter, req *http.Request) {
 	plName := mux.Vars(req)["pipelinename"]
 
-	pipeline, err := parseBody(req)
+	pipeline, err := server.parseBody(req, plName)
 	if err != nil {
 		err = fmt.Errorf("Fail to parse the pipeline config as %s", err.Error())
 		log.Errorln(err.Error())
 		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
 		return
 	}
-	pipeline.Name = plName
 	log.Infof("Update Pipeline %s", pipeline.Name)
 
 	err = server.pm.Update(pipeline)
@@ -112,6 +171,29 @@ func (server *Server) updatePipeline(resp http.ResponseWriter, req *http.Request
 	httputil.WriteResponse(resp, http.StatusOK, pipeline, nil)
 }
 
+// listPipelines swagger:route GET /pipelines pipelines listPipelines
+//
+// Lists the pipelines out of namespaces, the pipelines in a namespace are listed by
+// GET /namespaces/{namespace}/pipelines.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: pipelinesResponse
+func (server *Server) listPipelines(resp http.ResponseWriter, req *http.Request) {
+	namespace := mux.Vars(req)["namespace"]
+	log.Infof("List the Pipelines in namespace %s", namespace)
+
+	pls, err := server.pm.ListPipelines(namespace)
+	if err != nil {
+		err = fmt.Errorf("Fail to list the pipelines in namespace %s as %s", namespace, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, pls, nil)
+}
+
 // deletePipeline swagger:route DELETE /pipelines/{pipelinename} pipelines deletePipeline
 //
 // Deletes a pipeline.
@@ -120,7 +202,7 @@ func (server *Server) updatePipeline(resp http.ResponseWriter, req *http.Request
 //    default: genericErrorResponse
 //        200: noObjectResponse
 func (server *Server) deletePipeline(resp http.ResponseWriter, req *http.Request) {
-	plName := mux.Vars(req)["pipelinename"]
+	plName := pipelineName(req)
 	log.Infof("Delete Pipeline %s", plName)
 
 	err := server.pm.Delete(plName)
@@ -143,7 +225,7 @@ func (server *Server) deletePipeline(resp http.ResponseWriter, req *http.Request
 //        200: noObjectResponse
 func (server *Server) performPipeline(resp http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
-	plName := mux.Vars(req)["pipelinename"]
+	plName := pipelineName(req)
 	params := &api.PerformParams{}
 	err := jsonutil.Unmarshal2JsonObj(req.Body, params)
 	if err != nil {
@@ -165,6 +247,635 @@ func (server *Server) performPipeline(resp http.ResponseWriter, req *http.Reques
 	httputil.WriteResponse(resp, http.StatusOK, nil, nil)
 }
 
+// getPipelineDependency swagger:route GET /pipelines/dependency/{pipelinename} pipelines getPipelineDependency
+//
+// Gets the dependency graph of a pipeline.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: dependencyGraphResponse
+func (server *Server) getPipelineDependency(resp http.ResponseWriter, req *http.Request) {
+	plName := pipelineName(req)
+	log.Infof("Get the dependency graph of Pipeline %s", plName)
+
+	graph, err := server.pm.GetDependencyGraph(plName)
+	if err != nil {
+		err = fmt.Errorf("Fail to get the dependency graph of pipeline %s as %s", plName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, graph, nil)
+}
+
+// getDiskUsages swagger:route GET /pipelines/diskusage pipelines getDiskUsages
+//
+// Gets the disk usages of the pipelines.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: diskUsagesResponse
+func (server *Server) getDiskUsages(resp http.ResponseWriter, req *http.Request) {
+	log.Infoln("Get the disk usages of Pipelines")
+
+	usages, err := server.pm.GetDiskUsages()
+	if err != nil {
+		err = fmt.Errorf("Fail to get the disk usages of pipelines as %s", err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, usages, nil)
+}
+
+// getBuildStatus swagger:route GET /pipelines/builds/{pipelinename}/{buildnumber} pipelines getBuildStatus
+//
+// Gets the status of a build, the stages of the matrix builds are reported per cell.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: buildStatusResponse
+func (server *Server) getBuildStatus(resp http.ResponseWriter, req *http.Request) {
+	plName := pipelineName(req)
+	number, err := strconv.ParseInt(mux.Vars(req)["buildnumber"], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("Bad request. The build number %s is not a number", mux.Vars(req)["buildnumber"])
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Get the status of build %d of Pipeline %s", number, plName)
+
+	status, err := server.pm.GetBuildStatus(plName, number)
+	if err != nil {
+		err = fmt.Errorf("Fail to get the status of build %d of pipeline %s as %s", number, plName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, status, nil)
+}
+
+// getBuildLog swagger:route GET /pipelines/logs/{pipelinename}/{buildnumber} pipelines getBuildLog
+//
+// Gets the console log of a build.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: buildLogResponse
+func (server *Server) getBuildLog(resp http.ResponseWriter, req *http.Request) {
+	plName := pipelineName(req)
+	number, err := strconv.ParseInt(mux.Vars(req)["buildnumber"], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("Bad request. The build number %s is not a number", mux.Vars(req)["buildnumber"])
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Get the log of build %d of Pipeline %s", number, plName)
+
+	buildLog, err := server.pm.GetBuildLog(plName, number)
+	if err != nil {
+		err = fmt.Errorf("Fail to get the log of build %d of pipeline %s as %s", number, plName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, buildLog, nil)
+}
+
+// getTestReport swagger:route GET /pipelines/tests/{pipelinename}/{buildnumber} pipelines getTestReport
+//
+// Gets the test report of a build, the tests of the matrix builds are reported per cell.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: testReportResponse
+func (server *Server) getTestReport(resp http.ResponseWriter, req *http.Request) {
+	plName := pipelineName(req)
+	number, err := strconv.ParseInt(mux.Vars(req)["buildnumber"], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("Bad request. The build number %s is not a number", mux.Vars(req)["buildnumber"])
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Get the test report of build %d of Pipeline %s", number, plName)
+
+	report, err := server.pm.GetTestReport(plName, number)
+	if err != nil {
+		err = fmt.Errorf("Fail to get the test report of build %d of pipeline %s as %s", number, plName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, report, nil)
+}
+
+// exportPipeline swagger:route GET /pipelines/{pipelinename}/export pipelines exportPipeline
+//
+// Exports a pipeline as the config file of GitLab CI or GitHub Actions, with the warnings of the features not mapped.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: pipelineExportResponse
+func (server *Server) exportPipeline(resp http.ResponseWriter, req *http.Request) {
+	plName := pipelineName(req)
+	format := api.ExportFormat(strings.TrimSpace(req.URL.Query().Get("format")))
+	if len(format) == 0 {
+		err := fmt.Errorf("Bad request. The export format of pipeline %s is not specified", plName)
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Export the Pipeline %s as %s", plName, format)
+
+	export, err := server.pm.ExportPipeline(plName, format)
+	if err != nil {
+		err = fmt.Errorf("Fail to export the pipeline %s as %s", plName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, export, nil)
+}
+
+// createTemplate swagger:route POST /templates templates createTemplate
+//
+// Creates a pipeline template.
+//
+// Responses:
+//    default: genericErrorResponse
+//        201: templateResponse
+func (server *Server) createTemplate(resp http.ResponseWriter, req *http.Request) {
+	tpl, err := parseTemplate(req)
+	if err != nil {
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Create Template %s", tpl.Name)
+
+	err = server.pm.CreateTemplate(tpl)
+	if err != nil {
+		err = fmt.Errorf("Fail to create the template %s as %s", tpl.Name, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusCreated, tpl, nil)
+}
+
+// listTemplates swagger:route GET /templates templates listTemplates
+//
+// Lists the pipeline templates.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: templatesResponse
+func (server *Server) listTemplates(resp http.ResponseWriter, req *http.Request) {
+	tpls, err := server.pm.ListTemplates()
+	if err != nil {
+		err = fmt.Errorf("Fail to list the templates as %s", err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, tpls, nil)
+}
+
+// getTemplate swagger:route GET /templates/{templatename} templates getTemplate
+//
+// Gets a pipeline template.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: templateResponse
+func (server *Server) getTemplate(resp http.ResponseWriter, req *http.Request) {
+	tplName := mux.Vars(req)["templatename"]
+
+	tpl, err := server.pm.GetTemplate(tplName)
+	if err != nil {
+		err = fmt.Errorf("Fail to get the template %s as %s", tplName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, tpl, nil)
+}
+
+// updateTemplate swagger:route PUT /templates/{templatename} templates updateTemplate
+//
+// Updates a pipeline template, the pipelines using it are not changed until it is rolled out.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: templateResponse
+func (server *Server) updateTemplate(resp http.ResponseWriter, req *http.Request) {
+	tplName := mux.Vars(req)["templatename"]
+
+	tpl, err := parseTemplate(req)
+	if err != nil {
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	tpl.Name = tplName
+	log.Infof("Update Template %s", tpl.Name)
+
+	err = server.pm.UpdateTemplate(tpl)
+	if err != nil {
+		err = fmt.Errorf("Fail to update the template %s as %s", tpl.Name, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, tpl, nil)
+}
+
+// deleteTemplate swagger:route DELETE /templates/{templatename} templates deleteTemplate
+//
+// Deletes a pipeline template which is not used by any pipeline.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: noObjectResponse
+func (server *Server) deleteTemplate(resp http.ResponseWriter, req *http.Request) {
+	tplName := mux.Vars(req)["templatename"]
+	log.Infof("Delete Template %s", tplName)
+
+	err := server.pm.DeleteTemplate(tplName)
+	if err != nil {
+		err = fmt.Errorf("Fail to delete the template %s as %s", tplName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, nil, nil)
+}
+
+// previewTemplate swagger:route GET /templates/preview/{templatename} templates previewTemplate
+//
+// Previews the re-rendered configs of the pipelines using a template.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: templateRenderingsResponse
+func (server *Server) previewTemplate(resp http.ResponseWriter, req *http.Request) {
+	tplName := mux.Vars(req)["templatename"]
+
+	renderings, err := server.pm.PreviewTemplate(tplName)
+	if err != nil {
+		err = fmt.Errorf("Fail to preview the template %s as %s", tplName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, renderings, nil)
+}
+
+// previewTemplateChange swagger:route POST /templates/preview/{templatename} templates previewTemplateChange
+//
+// Previews the re-rendered configs of the pipelines using a template with the proposed template,
+// the proposed template is not saved.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: templateRenderingsResponse
+func (server *Server) previewTemplateChange(resp http.ResponseWriter, req *http.Request) {
+	tplName := mux.Vars(req)["templatename"]
+
+	tpl, err := parseTemplate(req)
+	if err != nil {
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	tpl.Name = tplName
+
+	renderings, err := server.pm.PreviewTemplateChange(tpl)
+	if err != nil {
+		err = fmt.Errorf("Fail to preview the template %s as %s", tplName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, renderings, nil)
+}
+
+// rolloutTemplate swagger:route PUT /templates/rollout/{templatename} templates rolloutTemplate
+//
+// Rolls out a template, the pipelines using it are re-rendered and updated if changed.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: templateRenderingsResponse
+func (server *Server) rolloutTemplate(resp http.ResponseWriter, req *http.Request) {
+	tplName := mux.Vars(req)["templatename"]
+	log.Infof("Roll out Template %s", tplName)
+
+	renderings, err := server.pm.RolloutTemplate(tplName)
+	if err != nil {
+		err = fmt.Errorf("Fail to roll out the template %s as %s", tplName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, renderings, nil)
+}
+
+// listPendingApprovals swagger:route GET /approvals approvals listPendingApprovals
+//
+// Lists the approval gates waiting for the approvers in the running builds.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: pendingApprovalsResponse
+func (server *Server) listPendingApprovals(resp http.ResponseWriter, req *http.Request) {
+	approvals, err := server.pm.ListPendingApprovals()
+	if err != nil {
+		err = fmt.Errorf("Fail to list the pending approvals as %s", err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, approvals, nil)
+}
+
+// approveStage swagger:route POST /approvals/approval/{pipelinename}/{buildnumber}/{stage} approvals approveStage
+//
+// Approves the approval gate of a stage, the build continues to run the stage.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: noObjectResponse
+func (server *Server) approveStage(resp http.ResponseWriter, req *http.Request) {
+	plName, number, stage, decision, token, err := parseApproval(req)
+	if err != nil {
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Approve the %s stage of build %d of Pipeline %s", stage, number, plName)
+
+	err = server.pm.Approve(plName, number, stage, decision, token)
+	if err != nil {
+		err = fmt.Errorf("Fail to approve the %s stage of build %d of pipeline %s as %s", stage, number, plName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, nil, nil)
+}
+
+// rejectStage swagger:route POST /approvals/rejection/{pipelinename}/{buildnumber}/{stage} approvals rejectStage
+//
+// Rejects the approval gate of a stage, the build is aborted.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: noObjectResponse
+func (server *Server) rejectStage(resp http.ResponseWriter, req *http.Request) {
+	plName, number, stage, decision, token, err := parseApproval(req)
+	if err != nil {
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Reject the %s stage of build %d of Pipeline %s", stage, number, plName)
+
+	err = server.pm.Reject(plName, number, stage, decision, token)
+	if err != nil {
+		err = fmt.Errorf("Fail to reject the %s stage of build %d of pipeline %s as %s", stage, number, plName, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, nil, nil)
+}
+
+// receiveScmEvent swagger:route POST /scm/events scm receiveScmEvent
+//
+// Receives the webhook events of GitHub, GitLab and Gitea. The branches of the fan-out pipelines
+// are discovered immediately when the branches of their repos are changed, and the pull requests
+// are built by the pipelines in pull request mode.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: noObjectResponse
+func (server *Server) receiveScmEvent(resp http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		err = fmt.Errorf("Bad request. Can't read the request body as %s", err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	err = server.pm.HandleScmEvent(req.Header, body)
+	if err != nil {
+		err = fmt.Errorf("Fail to handle the scm event as %s", err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, nil, nil)
+}
+
+// createCredential swagger:route POST /credentials credentials createCredential
+//
+// Creates a Jenkins credential.
+//
+// Responses:
+//    default: genericErrorResponse
+//        201: credentialResponse
+func (server *Server) createCredential(resp http.ResponseWriter, req *http.Request) {
+	credential, err := parseCredential(req)
+	if err != nil {
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Create Credential %s", credential.Id)
+
+	err = server.pm.CreateCredential(credential)
+	if err != nil {
+		err = fmt.Errorf("Fail to create the credential %s as %s", credential.Id, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusCreated, hideSecrets(credential), nil)
+}
+
+// updateCredential swagger:route PUT /credentials/{credentialid} credentials updateCredential
+//
+// Updates a Jenkins credential to rotate its secrets.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: credentialResponse
+func (server *Server) updateCredential(resp http.ResponseWriter, req *http.Request) {
+	credentialId := mux.Vars(req)["credentialid"]
+
+	credential, err := parseCredential(req)
+	if err != nil {
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	credential.Id = credentialId
+	log.Infof("Update Credential %s", credential.Id)
+
+	err = server.pm.UpdateCredential(credential)
+	if err != nil {
+		err = fmt.Errorf("Fail to update the credential %s as %s", credential.Id, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, hideSecrets(credential), nil)
+}
+
+// deleteCredential swagger:route DELETE /credentials/{credentialid} credentials deleteCredential
+//
+// Deletes a Jenkins credential.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: noObjectResponse
+func (server *Server) deleteCredential(resp http.ResponseWriter, req *http.Request) {
+	credentialId := mux.Vars(req)["credentialid"]
+	log.Infof("Delete Credential %s", credentialId)
+
+	err := server.pm.DeleteCredential(credentialId)
+	if err != nil {
+		err = fmt.Errorf("Fail to delete the credential %s as %s", credentialId, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, nil, nil)
+}
+
+// createSubscription swagger:route POST /subscriptions subscriptions createSubscription
+//
+// Subscribes the build events by the webhook.
+//
+// Responses:
+//    default: genericErrorResponse
+//        201: subscriptionResponse
+func (server *Server) createSubscription(resp http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	sub := &api.Subscription{}
+	err := jsonutil.Unmarshal2JsonObj(req.Body, sub)
+	if err != nil {
+		err = fmt.Errorf("Bad request. Can't parse the request body to a json object as %s", err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+	log.Infof("Create Subscription for %s", sub.Url)
+
+	err = server.dispatcher.CreateSubscription(sub)
+	if err != nil {
+		err = fmt.Errorf("Fail to create the subscription for %s as %s", sub.Url, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusCreated, hideSubscriptionSecret(sub), nil)
+}
+
+// listSubscriptions swagger:route GET /subscriptions subscriptions listSubscriptions
+//
+// Lists the subscriptions of the build events.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: subscriptionsResponse
+func (server *Server) listSubscriptions(resp http.ResponseWriter, req *http.Request) {
+	subs, err := server.dispatcher.ListSubscriptions()
+	if err != nil {
+		err = fmt.Errorf("Fail to list the subscriptions as %s", err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	for i, sub := range subs {
+		subs[i] = hideSubscriptionSecret(sub)
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, subs, nil)
+}
+
+// deleteSubscription swagger:route DELETE /subscriptions/{subscriptionid} subscriptions deleteSubscription
+//
+// Deletes a subscription.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: noObjectResponse
+func (server *Server) deleteSubscription(resp http.ResponseWriter, req *http.Request) {
+	subId := mux.Vars(req)["subscriptionid"]
+	log.Infof("Delete Subscription %s", subId)
+
+	err := server.dispatcher.DeleteSubscription(subId)
+	if err != nil {
+		err = fmt.Errorf("Fail to delete the subscription %s as %s", subId, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, nil, nil)
+}
+
+// listDeliveries swagger:route GET /subscriptions/deliveries/{subscriptionid} subscriptions listDeliveries
+//
+// Lists the recent deliveries of a subscription.
+//
+// Responses:
+//    default: genericErrorResponse
+//        200: deliveriesResponse
+func (server *Server) listDeliveries(resp http.ResponseWriter, req *http.Request) {
+	subId := mux.Vars(req)["subscriptionid"]
+
+	deliveries, err := server.dispatcher.ListDeliveries(subId)
+	if err != nil {
+		err = fmt.Errorf("Fail to list the deliveries of subscription %s as %s", subId, err.Error())
+		log.Errorln(err.Error())
+		httputil.WriteResponse(resp, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	httputil.WriteResponse(resp, http.StatusOK, deliveries, nil)
+}
+
 func (server *Server) registerSwaggerHandler() {
 	server.router.HandleFunc("/swagger.json", func(resp http.ResponseWriter, req *http.Request) {
 		path := strings.TrimSpace(req.URL.Query().Get("path"))
@@ -199,46 +910,98 @@ func (server *Server) registerSwaggerHandler() {
 	})
 }
 
-func parseBody(req *http.Request) (*api.Pipeline, error) {
+// parseBody Parses the pipeline definition in the request body, which is rendered with its template if any
+func (server *Server) parseBody(req *http.Request, plName string) (*api.Pipeline, error) {
 	defer req.Body.Close()
-	pipeline := &api.Pipeline{}
-	err := jsonutil.Unmarshal2JsonObj(req.Body, pipeline)
+	definition := map[string]interface{}{}
+	err := jsonutil.Unmarshal2JsonObj(req.Body, &definition)
 	if err != nil {
 		err = fmt.Errorf("Bad request. Can't parse the request body to a json object as %s", err.Error())
 		return nil, err
 	}
 
-	projectStr, err := jsonutil.Marshal2JsonStr(pipeline.Project)
+	// The name and the namespace in the path take precedence over the ones in the body
+	namespace := mux.Vars(req)["namespace"]
+	if len(plName) > 0 {
+		definition["name"] = plName
+		definition["namespace"] = namespace
+	} else if len(namespace) > 0 {
+		definition["namespace"] = namespace
+	}
+
+	return server.pm.RenderPipeline(definition)
+}
+
+// pipelineName Gets the full name of the pipeline in the path, which is prefixed by the namespace if any
+func pipelineName(req *http.Request) string {
+	vars := mux.Vars(req)
+	return pipeline.FullName(vars["namespace"], vars["pipelinename"])
+}
+
+func parseTemplate(req *http.Request) (*api.PipelineTemplate, error) {
+	defer req.Body.Close()
+	tpl := &api.PipelineTemplate{}
+	err := jsonutil.Unmarshal2JsonObj(req.Body, tpl)
 	if err != nil {
+		err = fmt.Errorf("Bad request. Can't parse the request body to a json object as %s", err.Error())
 		return nil, err
 	}
 
-	projectType := pipeline.ProjectType
-	switch projectType {
-	case api.SHELL, api.BATCH:
-		project := api.ScriptProject{}
-		err = jsonutil.UnmarshalJsonStr2Obj(projectStr, &project)
-		if err != nil {
-			return nil, err
-		}
-		pipeline.Project = project
-	case api.MAVEN:
-		project := api.MavenProject{}
-		err = jsonutil.UnmarshalJsonStr2Obj(projectStr, &project)
-		if err != nil {
-			return nil, err
-		}
-		pipeline.Project = project
-	case api.GRADLE:
-		project := api.GradleProject{}
-		err = jsonutil.UnmarshalJsonStr2Obj(projectStr, &project)
-		if err != nil {
-			return nil, err
-		}
-		pipeline.Project = project
-	default:
-		return nil, fmt.Errorf("The project type %s is not supported", projectType)
+	return tpl, nil
+}
+
+func parseCredential(req *http.Request) (*api.Credential, error) {
+	defer req.Body.Close()
+	credential := &api.Credential{}
+	err := jsonutil.Unmarshal2JsonObj(req.Body, credential)
+	if err != nil {
+		err = fmt.Errorf("Bad request. Can't parse the request body to a json object as %s", err.Error())
+		return nil, err
 	}
 
-	return pipeline, nil
+	return credential, nil
+}
+
+func parseApproval(req *http.Request) (string, int64, api.Stage, *api.ApprovalDecision, string, error) {
+	defer req.Body.Close()
+	vars := mux.Vars(req)
+	number, err := strconv.ParseInt(vars["buildnumber"], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("Bad request. The build number %s is not a number", vars["buildnumber"])
+		return "", 0, "", nil, "", err
+	}
+
+	// The approver signs in with the Jenkins user and its API token
+	user, token, ok := req.BasicAuth()
+	if !ok || len(user) == 0 || len(token) == 0 {
+		err = fmt.Errorf("Bad request. The approver should sign in with the Jenkins user and API token by the basic authentication")
+		return "", 0, "", nil, "", err
+	}
+
+	decision := &api.ApprovalDecision{}
+	err = jsonutil.Unmarshal2JsonObj(req.Body, decision)
+	if err != nil {
+		err = fmt.Errorf("Bad request. Can't parse the request body to a json object as %s", err.Error())
+		return "", 0, "", nil, "", err
+	}
+	decision.Approver = user
+
+	return pipelineName(req), number, api.Stage(vars["stage"]), decision, token, nil
+}
+
+// hideSecrets Returns the copy of the credential without secrets, as secrets must never be responded
+func hideSecrets(credential *api.Credential) *api.Credential {
+	return &api.Credential{
+		Id:          credential.Id,
+		Type:        credential.Type,
+		Description: credential.Description,
+		Username:    credential.Username,
+	}
+}
+
+// hideSubscriptionSecret Returns the copy of the subscription without the secret
+func hideSubscriptionSecret(sub *api.Subscription) *api.Subscription {
+	copied := *sub
+	copied.Secret = ""
+	return &copied
 }
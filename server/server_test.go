@@ -0,0 +1,262 @@
+package server_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/config"
+	"github.com/supereagle/goline/pipeline"
+	"github.com/supereagle/goline/server"
+	"github.com/supereagle/goline/store"
+	"github.com/supereagle/goline/webhook"
+)
+
+// fakeBackend keeps the pipelines and the performed builds in memory
+type fakeBackend struct {
+	pipelines map[string]*api.Pipeline
+	builds    map[string]int64
+}
+
+func (backend *fakeBackend) Create(pl *api.Pipeline) error {
+	backend.pipelines[pipeline.FullName(pl.Namespace, pl.Name)] = pl
+	return nil
+}
+
+func (backend *fakeBackend) Update(pl *api.Pipeline) error {
+	name := pipeline.FullName(pl.Namespace, pl.Name)
+	if _, ok := backend.pipelines[name]; !ok {
+		return &pipeline.NotExistError{Pipeline: name}
+	}
+	backend.pipelines[name] = pl
+	return nil
+}
+
+func (backend *fakeBackend) Delete(plName string) error {
+	if _, ok := backend.pipelines[plName]; !ok {
+		return &pipeline.NotExistError{Pipeline: plName}
+	}
+	delete(backend.pipelines, plName)
+	return nil
+}
+
+func (backend *fakeBackend) Perform(plName string, params *api.PerformParams) error {
+	if _, ok := backend.pipelines[plName]; !ok {
+		return &pipeline.NotExistError{Pipeline: plName}
+	}
+	backend.builds[plName]++
+	return nil
+}
+
+func (backend *fakeBackend) GetBuildStatus(plName string, number int64) (*api.BuildStatus, error) {
+	if number > backend.builds[plName] {
+		return nil, fmt.Errorf("The build %d of pipeline %s does not exist", number, plName)
+	}
+	return &api.BuildStatus{Pipeline: plName, Build: number, Result: string(api.SUCCESS)}, nil
+}
+
+func (backend *fakeBackend) GetBuildLog(plName string, number int64) (string, error) {
+	if number > backend.builds[plName] {
+		return "", fmt.Errorf("The build %d of pipeline %s does not exist", number, plName)
+	}
+	return fmt.Sprintf("build %d of %s", number, plName), nil
+}
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goline-server")
+	if err != nil {
+		t.Fatalf("Fail to create the temp dir as %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	st, err := store.NewStore(dir)
+	if err != nil {
+		t.Fatalf("Fail to create the store as %s", err.Error())
+	}
+
+	backend := &fakeBackend{pipelines: map[string]*api.Pipeline{}, builds: map[string]int64{}}
+	pm, err := pipeline.NewManager(backend, &config.Config{}, st)
+	if err != nil {
+		t.Fatalf("Fail to create the manager as %s", err.Error())
+	}
+	ts := httptest.NewServer(server.NewServer(pm, webhook.NewDispatcher(st)))
+	defer ts.Close()
+
+	cases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		expected int
+		contains string
+	}{
+		{"create", "POST", "/namespaces/team/pipelines", `{"name": "svc", "type": "shell", "project": {"build": {"command": "make"}}, "repo": {"repo_path": "https://github.com/example/svc.git"}}`, http.StatusCreated, `"namespace":"team"`},
+		{"list", "GET", "/namespaces/team/pipelines", "", http.StatusOK, `"name":"svc"`},
+		{"perform", "PUT", "/namespaces/team/pipelines/performance/svc", `{}`, http.StatusOK, ""},
+		{"perform-invalid-revision", "PUT", "/namespaces/team/pipelines/performance/svc", `{"revision": "--upload-pack=touch"}`, http.StatusInternalServerError, "neither a commit SHA nor a tag"},
+		{"status", "GET", "/namespaces/team/pipelines/builds/svc/1", "", http.StatusOK, `"result":"SUCCESS"`},
+		{"log", "GET", "/namespaces/team/pipelines/logs/svc/1", "", http.StatusOK, `"log":"build 1 of team/svc"`},
+		{"missing-log", "GET", "/namespaces/team/pipelines/logs/svc/2", "", http.StatusInternalServerError, "does not exist"},
+		{"perform-tag", "PUT", "/namespaces/team/pipelines/performance/svc", `{"revision": "release/v1.0"}`, http.StatusOK, ""},
+		{"create-exported", "POST", "/namespaces/team/pipelines", `{"name": "lib", "jdk": "jdk1.8", "type": "shell", "project": {"compile": {"command": "make compile"}, "build": {"command": "make"}}, "repo": {"repo_path": "https://github.com/example/lib.git", "branch": "master"}}`, http.StatusCreated, `"name":"lib"`},
+		{"export", "GET", "/namespaces/team/pipelines/lib/export?format=github", "", http.StatusOK, `"path":".github/workflows/lib.yml"`},
+		{"export-unknown-format", "GET", "/namespaces/team/pipelines/lib/export?format=travis", "", http.StatusInternalServerError, "not supported"},
+		{"create-dependency", "POST", "/namespaces/team/pipelines", `{"name": "dependency", "jdk": "jdk1.8", "type": "shell", "project": {"compile": {"command": "make compile"}, "build": {"command": "make"}}, "repo": {"repo_path": "https://github.com/example/dependency.git", "branch": "master"}}`, http.StatusCreated, `"name":"dependency"`},
+		{"export-dependency", "GET", "/namespaces/team/pipelines/dependency/export?format=gitlab", "", http.StatusOK, `"path":".gitlab-ci.yml"`},
+		{"jenkins-only", "GET", "/pipelines/diskusage", "", http.StatusInternalServerError, "only supported by the Jenkins backend"},
+		{"approve-unauthenticated", "POST", "/namespaces/team/approvals/approval/svc/1/build", `{"approver": "alice"}`, http.StatusInternalServerError, "basic authentication"},
+		{"create-template", "POST", "/templates", `{"name": "tpl", "pipeline": {"jdk": "jdk1.8", "type": "shell", "project": {"build": {"command": "make"}}}}`, http.StatusCreated, `"name":"tpl"`},
+		{"create-from-template", "POST", "/namespaces/team/pipelines", `{"name": "app", "template": "tpl", "repo": {"repo_path": "https://github.com/example/app.git", "branch": "master"}}`, http.StatusCreated, `"template":"tpl"`},
+		{"preview-template-change", "POST", "/templates/preview/tpl", `{"pipeline": {"jdk": "jdk1.8", "type": "shell", "project": {"compile": {"command": "make compile"}, "build": {"command": "make"}}}}`, http.StatusOK, `"changed":true`},
+		{"preview-template", "GET", "/templates/preview/tpl", "", http.StatusOK, `"changed":false`},
+		{"delete", "DELETE", "/namespaces/team/pipelines/svc", "", http.StatusOK, ""},
+		{"delete-missing", "DELETE", "/namespaces/team/pipelines/svc", "", http.StatusInternalServerError, "does not exist"},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, ts.URL+c.path, strings.NewReader(c.body))
+		if err != nil {
+			t.Fatalf("Fail to create the request of case %s as %s", c.name, err.Error())
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Fail to send the request of case %s as %s", c.name, err.Error())
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != c.expected || !strings.Contains(string(body), c.contains) {
+			t.Errorf("The case %s responds %d %s, but expected %d containing %s", c.name, resp.StatusCode, body, c.expected, c.contains)
+		}
+	}
+
+	pl := &api.Pipeline{}
+	if err := st.Get("pipelines", "team/svc", pl); err != store.ErrNotFound {
+		data, _ := json.Marshal(pl)
+		t.Errorf("The config of the deleted pipeline is still saved: %s", data)
+	}
+}
+
+func TestScmEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goline-server")
+	if err != nil {
+		t.Fatalf("Fail to create the temp dir as %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	st, err := store.NewStore(dir)
+	if err != nil {
+		t.Fatalf("Fail to create the store as %s", err.Error())
+	}
+
+	backend := &fakeBackend{pipelines: map[string]*api.Pipeline{}, builds: map[string]int64{}}
+	cfg := &config.Config{
+		ScmEventSecret: "secret",
+		StatusReporters: map[string]*api.ReporterConfig{
+			"github": &api.ReporterConfig{Type: api.GITHUB, Url: "https://api.github.com", Token: "token"},
+		},
+	}
+	pm, err := pipeline.NewManager(backend, cfg, st)
+	if err != nil {
+		t.Fatalf("Fail to create the manager as %s", err.Error())
+	}
+	ts := httptest.NewServer(server.NewServer(pm, webhook.NewDispatcher(st)))
+	defer ts.Close()
+
+	push := `{"ref": "refs/heads/master", "repository": {"clone_url": "https://github.com/example/svc.git"}}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(push))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	cases := []struct {
+		name     string
+		header   map[string]string
+		expected int
+	}{
+		{"github-signed", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + signature}, http.StatusOK},
+		{"github-unsigned", map[string]string{"X-GitHub-Event": "push"}, http.StatusInternalServerError},
+		{"github-wrong-signature", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + strings.Repeat("0", 64)}, http.StatusInternalServerError},
+		{"gitea-signed", map[string]string{"X-Gitea-Event": "push", "X-GitHub-Event": "push", "X-Gitea-Signature": signature}, http.StatusOK},
+		{"gitea-unsigned", map[string]string{"X-Gitea-Event": "push", "X-GitHub-Event": "push"}, http.StatusInternalServerError},
+		{"gitlab-token", map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "secret"}, http.StatusOK},
+		{"gitlab-wrong-token", map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "guess"}, http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest("POST", ts.URL+"/scm/events", strings.NewReader(push))
+		if err != nil {
+			t.Fatalf("Fail to create the request of case %s as %s", c.name, err.Error())
+		}
+		for key, value := range c.header {
+			req.Header.Set(key, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Fail to send the request of case %s as %s", c.name, err.Error())
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != c.expected {
+			t.Errorf("The case %s responds %d %s, but expected %d", c.name, resp.StatusCode, body, c.expected)
+		}
+	}
+
+	// The pull requests are not built by the events not signed with the secret
+	err = pm.Create(&api.Pipeline{
+		Name:         "svc",
+		Jdk:          "jdk1.8",
+		Repo:         &api.Repo{RepoPath: "https://github.com/example/svc.git", Branch: "master"},
+		ProjectType:  api.SHELL,
+		Project:      api.ScriptProject{Build: &api.ScriptBuild{Command: "make"}},
+		PullRequests: &api.PullRequests{Reporter: "github"},
+	})
+	if err != nil {
+		t.Fatalf("Fail to create the pipeline in pull request mode as %s", err.Error())
+	}
+
+	pullRequest := `{"action": "opened", "pull_request": {"number": 1, "head": {"sha": "3f6c2a1"}, "base": {"ref": "master"}}, "repository": {"clone_url": "https://github.com/example/svc.git"}}`
+	mergeRequest := `{"object_attributes": {"iid": 1, "action": "open", "target_branch": "master", "last_commit": {"id": "3f6c2a1"}}, "project": {"git_http_url": "https://github.com/example/svc.git"}}`
+	unsigned := []struct {
+		name   string
+		body   string
+		header map[string]string
+	}{
+		{"github-pull-request", pullRequest, map[string]string{"X-GitHub-Event": "pull_request"}},
+		{"github-pull-request-push-signature", pullRequest, map[string]string{"X-GitHub-Event": "pull_request", "X-Hub-Signature-256": "sha256=" + signature}},
+		{"gitea-pull-request", pullRequest, map[string]string{"X-Gitea-Event": "pull_request", "X-GitHub-Event": "pull_request"}},
+		{"gitlab-merge-request", mergeRequest, map[string]string{"X-Gitlab-Event": "Merge Request Hook"}},
+	}
+
+	for _, c := range unsigned {
+		req, err := http.NewRequest("POST", ts.URL+"/scm/events", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatalf("Fail to create the request of case %s as %s", c.name, err.Error())
+		}
+		for key, value := range c.header {
+			req.Header.Set(key, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Fail to send the request of case %s as %s", c.name, err.Error())
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusInternalServerError || !strings.Contains(string(body), "not signed") {
+			t.Errorf("The unsigned case %s responds %d %s, but expected to be rejected", c.name, resp.StatusCode, body)
+		}
+	}
+	if backend.builds["svc"] != 0 {
+		t.Errorf("The pull requests of the unsigned events are built %d times", backend.builds["svc"])
+	}
+}
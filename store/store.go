@@ -0,0 +1,135 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const fileSuffix = ".json"
+
+var ErrNotFound = errors.New("The object is not found")
+
+// Store stores the JSON objects in files, grouped by kinds.
+// Each kind is a directory, and each object is a file named with its key.
+type Store struct {
+	dir   string
+	mutex sync.RWMutex
+}
+
+func NewStore(dir string) (*Store, error) {
+	if len(dir) == 0 {
+		return nil, fmt.Errorf("The data dir should not be empty")
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("Fail to create the data dir %s as %s", dir, err.Error())
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// Put Puts the object with the key, the existing one will be replaced
+func (s *Store) Put(kind, key string, obj interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	contents, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Fail to marshal the %s %s as %s", kind, key, err.Error())
+	}
+
+	if err = os.MkdirAll(filepath.Join(s.dir, kind), 0700); err != nil {
+		return fmt.Errorf("Fail to create the dir for %s as %s", kind, err.Error())
+	}
+
+	// Write to the temp file first to avoid the broken file
+	path := s.path(kind, key)
+	if err = ioutil.WriteFile(path+".tmp", contents, 0600); err != nil {
+		return fmt.Errorf("Fail to write the %s %s as %s", kind, key, err.Error())
+	}
+	if err = os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("Fail to write the %s %s as %s", kind, key, err.Error())
+	}
+
+	return nil
+}
+
+// Get Gets the object with the key, returns ErrNotFound if not exists
+func (s *Store) Get(kind, key string, obj interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	contents, err := ioutil.ReadFile(s.path(kind, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("Fail to read the %s %s as %s", kind, key, err.Error())
+	}
+
+	if err = json.Unmarshal(contents, obj); err != nil {
+		return fmt.Errorf("Fail to unmarshal the %s %s as %s", kind, key, err.Error())
+	}
+
+	return nil
+}
+
+// Delete Deletes the object with the key, returns ErrNotFound if not exists
+func (s *Store) Delete(kind, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := os.Remove(s.path(kind, key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("Fail to delete the %s %s as %s", kind, key, err.Error())
+	}
+
+	return nil
+}
+
+// List Lists the sorted keys of the objects of the kind
+func (s *Store) List(kind string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := []string{}
+	files, err := ioutil.ReadDir(filepath.Join(s.dir, kind))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return keys, nil
+		}
+		return nil, fmt.Errorf("Fail to list the %s as %s", kind, err.Error())
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+
+		key, err := url.QueryUnescape(strings.TrimSuffix(name, fileSuffix))
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// path Gets the file path of the object, the key is escaped as it may contain slashes
+func (s *Store) path(kind, key string) string {
+	return filepath.Join(s.dir, kind, url.QueryEscape(key)+fileSuffix)
+}
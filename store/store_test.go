@@ -0,0 +1,66 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/supereagle/goline/store"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goline-store")
+	if err != nil {
+		t.Fatalf("Fail to create the temp dir as %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := store.NewStore(dir)
+	if err != nil {
+		t.Fatalf("Fail to create the store as %s", err.Error())
+	}
+
+	type object struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	objs := map[string]*object{
+		"team/pipeline": &object{Name: "team/pipeline", Count: 1},
+		"pipeline":      &object{Name: "pipeline", Count: 2},
+	}
+	for key, obj := range objs {
+		if err = s.Put("objects", key, obj); err != nil {
+			t.Fatalf("Fail to put the object %s as %s", key, err.Error())
+		}
+	}
+
+	keys, err := s.List("objects")
+	if err != nil {
+		t.Fatalf("Fail to list the objects as %s", err.Error())
+	}
+	if !reflect.DeepEqual(keys, []string{"pipeline", "team/pipeline"}) {
+		t.Fatalf("The listed keys %v are not as desired", keys)
+	}
+
+	obj := &object{}
+	if err = s.Get("objects", "team/pipeline", obj); err != nil {
+		t.Fatalf("Fail to get the object as %s", err.Error())
+	}
+	if !reflect.DeepEqual(obj, objs["team/pipeline"]) {
+		t.Fatal("The object got from store is not as desired")
+	}
+
+	if err = s.Delete("objects", "team/pipeline"); err != nil {
+		t.Fatalf("Fail to delete the object as %s", err.Error())
+	}
+	if err = s.Get("objects", "team/pipeline", obj); err != store.ErrNotFound {
+		t.Fatalf("The deleted object should not be found, but got %v", err)
+	}
+
+	keys, err = s.List("nothing")
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("The objects of unknown kind should be empty, but got %v %v", keys, err)
+	}
+}
@@ -0,0 +1,13 @@
+package id
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// New Generates a random id of 32 hex characters
+func New() string {
+	bs := make([]byte, 16)
+	rand.Read(bs)
+	return hex.EncodeToString(bs)
+}
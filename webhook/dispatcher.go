@@ -0,0 +1,271 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/store"
+	"github.com/supereagle/goline/utils/id"
+)
+
+const (
+	subscriptionKind = "subscriptions"
+	deliveryKind     = "deliveries"
+
+	SIGNATURE_HEADER = "X-Goline-Signature"
+	EVENT_HEADER     = "X-Goline-Event"
+	DELIVERY_HEADER  = "X-Goline-Delivery"
+
+	maxAttempts   = 3
+	maxDeliveries = 100
+	retryBackoff  = time.Second
+)
+
+var buildEventTypes = []api.BuildEventType{api.BUILD_QUEUED, api.BUILD_STARTED, api.STAGE_FINISHED, api.BUILD_COMPLETED}
+
+// Dispatcher dispatches the build events to the subscribers
+type Dispatcher struct {
+	store  *store.Store
+	client *http.Client
+	// mutex protects the delivery logs
+	mutex sync.Mutex
+}
+
+func NewDispatcher(st *store.Store) *Dispatcher {
+	return &Dispatcher{
+		store:  st,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// CreateSubscription Creates the subscription with a generated id
+func (d *Dispatcher) CreateSubscription(sub *api.Subscription) error {
+	if !ValidateSubscription(sub) {
+		return fmt.Errorf("The subscription is not correct")
+	}
+
+	sub.Id = id.New()
+	return d.store.Put(subscriptionKind, sub.Id, sub)
+}
+
+// ListSubscriptions Lists all the subscriptions
+func (d *Dispatcher) ListSubscriptions() ([]*api.Subscription, error) {
+	ids, err := d.store.List(subscriptionKind)
+	if err != nil {
+		return nil, err
+	}
+
+	subs := []*api.Subscription{}
+	for _, subId := range ids {
+		sub := &api.Subscription{}
+		if err = d.store.Get(subscriptionKind, subId, sub); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, nil
+}
+
+// DeleteSubscription Deletes the subscription and its delivery logs
+func (d *Dispatcher) DeleteSubscription(subId string) error {
+	err := d.store.Delete(subscriptionKind, subId)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return fmt.Errorf("The subscription %s does not exist", subId)
+		}
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if err = d.store.Delete(deliveryKind, subId); err != nil && err != store.ErrNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// ListDeliveries Lists the recent deliveries of the subscription, the latest one is the last
+func (d *Dispatcher) ListDeliveries(subId string) ([]*api.Delivery, error) {
+	sub := &api.Subscription{}
+	if err := d.store.Get(subscriptionKind, subId, sub); err != nil {
+		if err == store.ErrNotFound {
+			return nil, fmt.Errorf("The subscription %s does not exist", subId)
+		}
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.getDeliveries(subId)
+}
+
+// Dispatch Dispatches the event to the matched subscribers asynchronously
+func (d *Dispatcher) Dispatch(event *api.BuildEvent) {
+	subs, err := d.ListSubscriptions()
+	if err != nil {
+		log.Errorf("Fail to list the subscriptions as %s", err.Error())
+		return
+	}
+
+	for _, sub := range subs {
+		if matchSubscription(sub, event) {
+			go d.deliver(sub, event)
+		}
+	}
+}
+
+// deliver Delivers the event to the subscriber, retries with backoff if fails
+func (d *Dispatcher) deliver(sub *api.Subscription, event *api.BuildEvent) {
+	delivery := &api.Delivery{
+		Id:             id.New(),
+		SubscriptionId: sub.Id,
+		Event:          event,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = fmt.Sprintf("Fail to marshal the event as %s", err.Error())
+		d.recordDelivery(delivery)
+		return
+	}
+
+	backoff := retryBackoff
+	for delivery.Attempts < maxAttempts {
+		if delivery.Attempts > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		delivery.Attempts++
+		delivery.Timestamp = time.Now()
+
+		delivery.StatusCode, err = d.post(sub, delivery.Id, event, body)
+		if err == nil {
+			delivery.Delivered = true
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+		log.Warnf("Fail to deliver the event %s to %s as %s", event.Id, sub.Url, err.Error())
+	}
+
+	d.recordDelivery(delivery)
+}
+
+func (d *Dispatcher) post(sub *api.Subscription, deliveryId string, event *api.BuildEvent, body []byte) (int, error) {
+	req, err := http.NewRequest("POST", sub.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EVENT_HEADER, string(event.Type))
+	req.Header.Set(DELIVERY_HEADER, deliveryId)
+	if len(sub.Secret) > 0 {
+		req.Header.Set(SIGNATURE_HEADER, Sign(sub.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("The subscriber responds %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// recordDelivery Records the delivery, only the latest deliveries are kept
+func (d *Dispatcher) recordDelivery(delivery *api.Delivery) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	deliveries, err := d.getDeliveries(delivery.SubscriptionId)
+	if err != nil {
+		log.Errorf("Fail to get the deliveries of %s as %s", delivery.SubscriptionId, err.Error())
+		return
+	}
+
+	deliveries = append(deliveries, delivery)
+	if len(deliveries) > maxDeliveries {
+		deliveries = deliveries[len(deliveries)-maxDeliveries:]
+	}
+
+	if err = d.store.Put(deliveryKind, delivery.SubscriptionId, deliveries); err != nil {
+		log.Errorf("Fail to record the delivery %s as %s", delivery.Id, err.Error())
+	}
+}
+
+func (d *Dispatcher) getDeliveries(subId string) ([]*api.Delivery, error) {
+	deliveries := []*api.Delivery{}
+	err := d.store.Get(deliveryKind, subId, &deliveries)
+	if err != nil && err != store.ErrNotFound {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Sign Signs the body with the secret by HMAC-SHA256, subscribers can verify the deliveries with it
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func ValidateSubscription(sub *api.Subscription) bool {
+	u, err := url.Parse(sub.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		log.Errorf("The subscription url %s is not a valid http url", sub.Url)
+		return false
+	}
+
+	for _, event := range sub.Events {
+		if !containEventType(buildEventTypes, event) {
+			log.Errorf("The build event %s is not supported, only supports %v", event, buildEventTypes)
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchSubscription(sub *api.Subscription, event *api.BuildEvent) bool {
+	if len(sub.Events) > 0 && !containEventType(sub.Events, event.Type) {
+		return false
+	}
+
+	if len(sub.Pipelines) == 0 {
+		return true
+	}
+	for _, pipeline := range sub.Pipelines {
+		if pipeline == event.Pipeline {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containEventType(types []api.BuildEventType, eventType api.BuildEventType) bool {
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
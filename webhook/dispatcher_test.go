@@ -0,0 +1,102 @@
+package webhook_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/supereagle/goline/api"
+	"github.com/supereagle/goline/store"
+	"github.com/supereagle/goline/webhook"
+)
+
+func TestDispatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goline-webhook")
+	if err != nil {
+		t.Fatalf("Fail to create the temp dir as %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	st, err := store.NewStore(dir)
+	if err != nil {
+		t.Fatalf("Fail to create the store as %s", err.Error())
+	}
+
+	received := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		received <- req.Header.Get(webhook.SIGNATURE_HEADER) == webhook.Sign("secret", body) &&
+			req.Header.Get(webhook.EVENT_HEADER) == string(api.BUILD_COMPLETED)
+	}))
+	defer server.Close()
+
+	d := webhook.NewDispatcher(st)
+	sub := &api.Subscription{
+		Url:       server.URL,
+		Secret:    "secret",
+		Events:    []api.BuildEventType{api.BUILD_COMPLETED},
+		Pipelines: []string{"pipeline"},
+	}
+	if err = d.CreateSubscription(sub); err != nil {
+		t.Fatalf("Fail to create the subscription as %s", err.Error())
+	}
+
+	// The unmatched events are not delivered
+	d.Dispatch(&api.BuildEvent{Id: "1", Type: api.BUILD_STARTED, Pipeline: "pipeline"})
+	d.Dispatch(&api.BuildEvent{Id: "2", Type: api.BUILD_COMPLETED, Pipeline: "other"})
+	d.Dispatch(&api.BuildEvent{Id: "3", Type: api.BUILD_COMPLETED, Pipeline: "pipeline", Build: 1, Result: "SUCCESS"})
+
+	select {
+	case signed := <-received:
+		if !signed {
+			t.Errorf("The delivery is not signed correctly")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("The event is not delivered")
+	}
+
+	// Wait for the delivery to be recorded
+	var deliveries []*api.Delivery
+	for i := 0; i < 50; i++ {
+		deliveries, err = d.ListDeliveries(sub.Id)
+		if err != nil {
+			t.Fatalf("Fail to list the deliveries as %s", err.Error())
+		}
+		if len(deliveries) > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(deliveries) != 1 || !deliveries[0].Delivered || deliveries[0].Event.Id != "3" {
+		t.Errorf("The deliveries are not expected: %v", deliveries)
+	}
+}
+
+func TestValidateSubscription(t *testing.T) {
+	testCases := map[string]struct {
+		sub      *api.Subscription
+		expected bool
+	}{
+		"valid": {
+			sub:      &api.Subscription{Url: "https://example.com/hooks", Events: []api.BuildEventType{api.BUILD_STARTED}},
+			expected: true,
+		},
+		"invalid-url": {
+			sub:      &api.Subscription{Url: "ftp://example.com/hooks"},
+			expected: false,
+		},
+		"invalid-event": {
+			sub:      &api.Subscription{Url: "http://example.com/hooks", Events: []api.BuildEventType{"build_deleted"}},
+			expected: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		if result := webhook.ValidateSubscription(tc.sub); result != tc.expected {
+			t.Errorf("Case %s: expected %v, but got %v", name, tc.expected, result)
+		}
+	}
+}